@@ -4,11 +4,12 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 
-	_ "github.com/jinzhu/gorm/dialects/sqlite" // required for sqlite
+	_ "github.com/jinzhu/gorm/dialects/postgres" // required for postgres
+	_ "github.com/jinzhu/gorm/dialects/sqlite"   // required for sqlite
 
 	"github.com/kbariotis/go-discover/internal/api"
 	"github.com/kbariotis/go-discover/internal/config"
-	"github.com/kbariotis/go-discover/internal/store"
+	"github.com/kbariotis/go-discover/internal/setup"
 	"github.com/kbariotis/go-discover/internal/version"
 )
 
@@ -44,16 +45,11 @@ func main() {
 	defer db.Close()
 
 	// create suggestions store
-	suggestionStore, err := store.NewSuggestionSQL(db)
+	suggestionStore, err := setup.NewSuggestionStore(cfg, db)
 	if err != nil {
 		logger.WithError(err).Fatal("could not create suggestion store")
 	}
 
-	// setup suggestion db
-	if err := suggestionStore.Setup(); err != nil {
-		logger.WithError(err).Fatal("could not setup suggestion db")
-	}
-
 	// constrcut api
 	api := api.NewAPI(
 		suggestionStore,
@@ -2,16 +2,17 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/Financial-Times/neoism"
 	"github.com/go-redis/redis"
-	"github.com/google/go-github/v25/github"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/oauth2"
 
-	_ "github.com/jinzhu/gorm/dialects/sqlite" // required for sqlite
+	_ "github.com/jinzhu/gorm/dialects/postgres" // required for postgres
+	_ "github.com/jinzhu/gorm/dialects/sqlite"   // required for sqlite
 
 	"github.com/kbariotis/go-discover/internal/cache"
 	"github.com/kbariotis/go-discover/internal/config"
@@ -19,7 +20,7 @@ import (
 	"github.com/kbariotis/go-discover/internal/model"
 	"github.com/kbariotis/go-discover/internal/provider"
 	"github.com/kbariotis/go-discover/internal/queue"
-	"github.com/kbariotis/go-discover/internal/store"
+	"github.com/kbariotis/go-discover/internal/setup"
 	"github.com/kbariotis/go-discover/internal/version"
 )
 
@@ -31,7 +32,17 @@ func main() {
 		"gitSHA":  version.GitSHA,
 	})
 
-	ctx := context.Background()
+	// cancel the context on SIGINT or SIGTERM so that in-flight tasks can finish
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.WithField("signal", sig.String()).Info("shutting down")
+		cancel()
+	}()
 
 	logger.Debug("loading configuration")
 	cfg, err := config.LoadConfig()
@@ -46,70 +57,97 @@ func main() {
 
 	logrus.SetLevel(logLevel)
 
-	// create github client
-	ghTokenSource := oauth2.StaticTokenSource(
-		&oauth2.Token{
-			AccessToken: cfg.GithubToken,
-		},
-	)
-
-	ghClient := github.NewClient(
-		oauth2.NewClient(ctx, ghTokenSource),
-	)
+	// connect to redis
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisHost,
+		Password: "", // no password set
+		DB:       0,  // use default DB
+	})
+	_, err = redisClient.Ping().Result()
+	if err != nil {
+		logger.WithError(err).Fatal("could not connect to Redis")
+	}
 
 	// create queues
-	userOnboardingQueue, err := queue.NewDQueue(
+	userOnboardingQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
 		"userOnboarding.queue",
-		cfg.QueueStoreDir,
 		&model.UserOnboardingTask{},
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create dqueue for userOnboarding")
+		logger.WithError(err).Fatal("could not create queue for userOnboarding")
 	}
 
-	userFolloweeQueue, err := queue.NewDQueue(
+	userFolloweeQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
 		"userFollowee.queue",
-		cfg.QueueStoreDir,
 		&model.UserFolloweeTask{},
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create dqueue for userFollowee")
+		logger.WithError(err).Fatal("could not create queue for userFollowee")
 	}
 
-	userQueue, err := queue.NewDQueue(
+	userQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
 		"user.queue",
-		cfg.QueueStoreDir,
 		&model.UserTask{},
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create dqueue for user")
+		logger.WithError(err).Fatal("could not create queue for user")
 	}
 
-	repositoryQueue, err := queue.NewDQueue(
+	repositoryQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
 		"repository.queue",
-		cfg.QueueStoreDir,
 		&model.RepositoryTask{},
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create dqueue for repository")
+		logger.WithError(err).Fatal("could not create queue for repository")
 	}
 
-	// create neo db
-	time.Sleep(time.Second * 30)
-	graphDB, err := neoism.Connect(cfg.NeoHost)
+	// create dead-letter queues
+	userOnboardingDeadLetterQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
+		"userOnboarding.deadletter.queue",
+		&model.UserOnboardingTask{},
+	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create neo client")
+		logger.WithError(err).Fatal("could not create dead-letter queue for userOnboarding")
 	}
 
-	// create graph store
-	graphStore, err := store.NewNeo(graphDB)
+	userFolloweeDeadLetterQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
+		"userFollowee.deadletter.queue",
+		&model.UserFolloweeTask{},
+	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create graph store")
+		logger.WithError(err).Fatal("could not create dead-letter queue for userFollowee")
 	}
 
-	// setup graph store indices
-	if err := graphStore.SetupIndices(); err != nil {
-		logger.WithError(err).Fatal("could not setup graph indices")
+	userDeadLetterQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
+		"user.deadletter.queue",
+		&model.UserTask{},
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create dead-letter queue for user")
+	}
+
+	repositoryDeadLetterQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
+		"repository.deadletter.queue",
+		&model.RepositoryTask{},
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create dead-letter queue for repository")
 	}
 
 	// connect to suggestions store db
@@ -120,40 +158,42 @@ func main() {
 	if err != nil {
 		logger.WithError(err).Fatal("could not connect to db")
 	}
-	defer db.Close()
 
 	// create suggestions store
-	suggestionStore, err := store.NewSuggestionSQL(db)
+	suggestionStore, err := setup.NewSuggestionStore(cfg, db)
 	if err != nil {
 		logger.WithError(err).Fatal("could not create suggestion store")
 	}
 
-	// setup suggestion db
-	if err := suggestionStore.Setup(); err != nil {
-		logger.WithError(err).Fatal("could not setup suggestion db")
+	// create graph store
+	graphStore, err := setup.NewGraphStore(cfg, db, false)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create graph store")
 	}
 
 	// create redis cache
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisHost,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
-	_, err = redisClient.Ping().Result()
-	if err != nil {
-		logger.WithError(err).Fatal("could not connect to Redis")
-	}
 	redis, err := cache.NewRedis(
 		redisClient,
 		cfg.LockUserDuration,
 		cfg.LockRepositoryDuration,
+		cfg.LockUserProfileDuration,
 	)
 	if err != nil {
 		logger.WithError(err).Fatal("could not create Redis cache")
 	}
 
+	// gather github tokens, including the ones of registered users
+	ghTokens := append([]string{}, cfg.GithubTokens...)
+	users, err := suggestionStore.GetAllUsers()
+	if err != nil {
+		logger.WithError(err).Fatal("could not retrieve users")
+	}
+	for _, user := range users {
+		ghTokens = append(ghTokens, user.Token)
+	}
+
 	// create github provider
-	prv, err := provider.NewGithub(ghClient)
+	prv, err := provider.NewGithub(cfg.GithubToken, ghTokens)
 	if err != nil {
 		logger.WithError(err).Fatal("could not construct github provider")
 	}
@@ -161,6 +201,17 @@ func main() {
 	// create crawler
 	crw, err := crawler.New(
 		time.Minute*5,
+		cfg.ShutdownTimeout,
+		crawler.Workers{
+			UserOnboarding: cfg.CrawlerUserOnboardingWorkers,
+			UserFollowee:   cfg.CrawlerUserFolloweeWorkers,
+			User:           cfg.CrawlerUserWorkers,
+			Repository:     cfg.CrawlerRepositoryWorkers,
+		},
+		crawler.Retry{
+			MaxAttempts: cfg.CrawlerRetryMaxAttempts,
+			Backoff:     cfg.CrawlerRetryBackoff,
+		},
 		graphStore,
 		suggestionStore,
 		redis,
@@ -169,6 +220,10 @@ func main() {
 		userFolloweeQueue,
 		userQueue,
 		repositoryQueue,
+		userOnboardingDeadLetterQueue,
+		userFolloweeDeadLetterQueue,
+		userDeadLetterQueue,
+		repositoryDeadLetterQueue,
 	)
 	if err != nil {
 		logger.WithError(err).Fatal("could not construct crawler")
@@ -176,8 +231,39 @@ func main() {
 
 	logger.Info("starting crawler")
 
-	// start crawler
-	if err := crw.Start(ctx); err != nil {
-		logger.WithError(err).Fatal("github crawler processing failed")
+	// start crawler, returns once in-flight tasks have finished
+	startErr := crw.Start(ctx)
+
+	for _, q := range []queue.Queue{
+		userOnboardingQueue,
+		userFolloweeQueue,
+		userQueue,
+		repositoryQueue,
+		userOnboardingDeadLetterQueue,
+		userFolloweeDeadLetterQueue,
+		userDeadLetterQueue,
+		repositoryDeadLetterQueue,
+	} {
+		if err := q.Close(); err != nil {
+			logger.WithError(err).Warn("could not close queue")
+		}
 	}
+
+	if err := graphStore.Close(); err != nil {
+		logger.WithError(err).Warn("could not close graph store")
+	}
+
+	if err := redisClient.Close(); err != nil {
+		logger.WithError(err).Warn("could not close Redis client")
+	}
+
+	if err := db.Close(); err != nil {
+		logger.WithError(err).Warn("could not close db")
+	}
+
+	if startErr != nil {
+		logger.WithError(startErr).Fatal("github crawler processing failed")
+	}
+
+	logger.Info("crawler stopped")
 }
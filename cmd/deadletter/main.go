@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/config"
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/setup"
+	"github.com/kbariotis/go-discover/internal/version"
+)
+
+const usage = `usage: deadletter <list|replay|purge> <queue>
+
+Manages the crawler's dead-letter queues, when using dque the crawler should
+not be running.
+
+Commands:
+  list    print all dead-lettered tasks
+  replay  move all dead-lettered tasks back to their queue
+  purge   delete all dead-lettered tasks
+
+Queues:
+  userOnboarding, userFollowee, user, repository
+`
+
+// tasks maps the crawler's queue names to their task types
+var queueTasks = map[string]func() model.RetryableTask{
+	"userOnboarding": func() model.RetryableTask { return &model.UserOnboardingTask{} },
+	"userFollowee":   func() model.RetryableTask { return &model.UserFolloweeTask{} },
+	"user":           func() model.RetryableTask { return &model.UserTask{} },
+	"repository":     func() model.RetryableTask { return &model.RepositoryTask{} },
+}
+
+// main lists, replays or purges dead-lettered crawler tasks
+func main() {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":  "cmd/deadletter",
+		"version": version.Version,
+		"gitSHA":  version.GitSHA,
+	})
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, name := flag.Arg(0), flag.Arg(1)
+
+	newTask, ok := queueTasks[name]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch command {
+	case "list", "replay", "purge":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger.Debug("loading configuration")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("could not load configuration")
+	}
+
+	logLevel, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.WithError(err).Fatal("could not parse log level")
+	}
+
+	logrus.SetLevel(logLevel)
+
+	// connect to redis, if used for queues
+	var redisClient *redis.Client
+	if cfg.QueueType == "redis" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: "", // no password set
+			DB:       0,  // use default DB
+		})
+		_, err = redisClient.Ping().Result()
+		if err != nil {
+			logger.WithError(err).Fatal("could not connect to Redis")
+		}
+	}
+
+	deadLetterQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
+		name+".deadletter.queue",
+		newTask(),
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create dead-letter queue")
+	}
+
+	// queues are closed before exiting, whether the command failed or not
+	queues := []queue.Queue{deadLetterQueue}
+
+	switch command {
+	case "list":
+		err = list(deadLetterQueue)
+	case "replay":
+		var q queue.Queue
+		q, err = setup.NewQueue(
+			cfg,
+			redisClient,
+			name+".queue",
+			newTask(),
+		)
+		if err != nil {
+			err = errors.Wrap(err, "could not create queue")
+			break
+		}
+		queues = append(queues, q)
+		err = replay(deadLetterQueue, q)
+	case "purge":
+		err = purge(deadLetterQueue)
+	}
+
+	for _, q := range queues {
+		if err := q.Close(); err != nil {
+			logger.WithError(err).Warn("could not close queue")
+		}
+	}
+
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			logger.WithError(err).Warn("could not close Redis client")
+		}
+	}
+
+	if err != nil {
+		logger.WithError(err).Fatal("could not " + command + " dead-letter queue")
+	}
+}
+
+// drain pops all tasks from the given queue and hands them to handle, each
+// task is acknowledged once handled, or returned to the queue if handle fails
+func drain(q queue.Queue, handle func(task interface{}) error) (int, error) {
+	count := 0
+	for {
+		lease, err := q.Pop()
+		if err != nil {
+			return count, errors.Wrap(err, "could not pop task")
+		}
+		if lease == nil {
+			return count, nil
+		}
+		if err := handle(lease.Task); err != nil {
+			if err := q.Nack(lease); err != nil {
+				logrus.WithError(err).Warn("could not return task to queue")
+			}
+			return count, err
+		}
+		if err := q.Ack(lease); err != nil {
+			return count, errors.Wrap(err, "could not ack task")
+		}
+		count++
+	}
+}
+
+// list prints all tasks of the dead-letter queue, leaving them in place
+// Tasks are leased until all of them have been printed and then returned to
+// the queue in the same order
+func list(deadLetterQueue queue.Queue) error {
+	leases := []*queue.Lease{}
+	defer func() {
+		for _, lease := range leases {
+			if err := deadLetterQueue.Nack(lease); err != nil {
+				logrus.WithError(err).Warn("could not return task to queue")
+			}
+		}
+	}()
+
+	for {
+		lease, err := deadLetterQueue.Pop()
+		if err != nil {
+			return errors.Wrap(err, "could not pop task")
+		}
+		if lease == nil {
+			return nil
+		}
+		leases = append(leases, lease)
+		out, _ := json.Marshal(lease.Task)
+		fmt.Println(string(out))
+	}
+}
+
+// replay moves all tasks of the dead-letter queue back to the given queue,
+// resetting their attempts
+func replay(deadLetterQueue, q queue.Queue) error {
+	count, err := drain(deadLetterQueue, func(task interface{}) error {
+		if retryableTask, ok := task.(model.RetryableTask); ok {
+			*retryableTask.GetRetry() = model.TaskRetry{}
+		}
+		if err := q.Push(task); err != nil {
+			return errors.Wrap(err, "could not push task to queue")
+		}
+		return nil
+	})
+	fmt.Printf("replayed %d tasks\n", count)
+	return err
+}
+
+// purge deletes all tasks of the dead-letter queue
+func purge(deadLetterQueue queue.Queue) error {
+	count, err := drain(deadLetterQueue, func(task interface{}) error {
+		return nil
+	})
+	fmt.Printf("purged %d tasks\n", count)
+	return err
+}
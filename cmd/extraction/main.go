@@ -2,21 +2,25 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/Financial-Times/neoism"
+	"github.com/go-redis/redis"
 	"github.com/jinzhu/gorm"
 	"github.com/mailgun/mailgun-go/v3"
 	"github.com/sirupsen/logrus"
 
-	_ "github.com/jinzhu/gorm/dialects/sqlite" // required for sqlite
+	_ "github.com/jinzhu/gorm/dialects/postgres" // required for postgres
+	_ "github.com/jinzhu/gorm/dialects/sqlite"   // required for sqlite
 
 	"github.com/kbariotis/go-discover/internal/config"
 	"github.com/kbariotis/go-discover/internal/extraction"
 	"github.com/kbariotis/go-discover/internal/mailer"
 	"github.com/kbariotis/go-discover/internal/model"
-	"github.com/kbariotis/go-discover/internal/queue"
-	"github.com/kbariotis/go-discover/internal/store"
+	"github.com/kbariotis/go-discover/internal/ranker"
+	"github.com/kbariotis/go-discover/internal/setup"
 	"github.com/kbariotis/go-discover/internal/version"
 )
 
@@ -28,7 +32,17 @@ func main() {
 		"gitSHA":  version.GitSHA,
 	})
 
-	ctx := context.Background()
+	// cancel the context on SIGINT or SIGTERM so that in-flight tasks can finish
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.WithField("signal", sig.String()).Info("shutting down")
+		cancel()
+	}()
 
 	logger.Debug("loading configuration")
 	cfg, err := config.LoadConfig()
@@ -43,31 +57,28 @@ func main() {
 
 	logrus.SetLevel(logLevel)
 
-	suggestionExtractionQueue, err := queue.NewDQueue(
+	// connect to redis, if used for queues
+	var redisClient *redis.Client
+	if cfg.QueueType == "redis" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: "", // no password set
+			DB:       0,  // use default DB
+		})
+		_, err = redisClient.Ping().Result()
+		if err != nil {
+			logger.WithError(err).Fatal("could not connect to Redis")
+		}
+	}
+
+	suggestionExtractionQueue, err := setup.NewQueue(
+		cfg,
+		redisClient,
 		"suggestionExtraction.queue",
-		cfg.QueueStoreDir,
 		&model.SuggestionExtractionTask{},
 	)
 	if err != nil {
-		logger.WithError(err).Fatal("could not create dqueue for suggestionExtraction")
-	}
-
-	// create neo db
-	time.Sleep(time.Second * 30)
-	graphDB, err := neoism.Connect(cfg.NeoHost)
-	if err != nil {
-		logger.WithError(err).Fatal("could not create neo client")
-	}
-
-	// create graph store
-	graphStore, err := store.NewNeo(graphDB)
-	if err != nil {
-		logger.WithError(err).Fatal("could not create graph store")
-	}
-
-	// setup graph store indices
-	if err := graphStore.SetupIndices(); err != nil {
-		logger.WithError(err).Fatal("could not setup graph indices")
+		logger.WithError(err).Fatal("could not create queue for suggestionExtraction")
 	}
 
 	// connect to suggestions store db
@@ -78,17 +89,17 @@ func main() {
 	if err != nil {
 		logger.WithError(err).Fatal("could not connect to db")
 	}
-	defer db.Close()
 
 	// create suggestions store
-	suggestionStore, err := store.NewSuggestionSQL(db)
+	suggestionStore, err := setup.NewSuggestionStore(cfg, db)
 	if err != nil {
 		logger.WithError(err).Fatal("could not create suggestion store")
 	}
 
-	// setup suggestion db
-	if err := suggestionStore.Setup(); err != nil {
-		logger.WithError(err).Fatal("could not setup suggestion db")
+	// create graph store
+	graphStore, err := setup.NewGraphStore(cfg, db, false)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create graph store")
 	}
 
 	// setup mailgun
@@ -98,9 +109,20 @@ func main() {
 		logger.WithError(err).Fatal("could not create mailer")
 	}
 
+	// create ranker
+	rnk, err := ranker.New(cfg.SuggestionRanker, cfg.SuggestionHalfLife)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create ranker")
+	}
+
 	// create extraction
 	extr, err := extraction.New(
 		time.Hour*24*7,
+		cfg.ShutdownTimeout,
+		cfg.SuggestionWindow,
+		cfg.SuggestionHistory,
+		rnk,
+		cfg.SuggestionHalfLife,
 		graphStore,
 		suggestionStore,
 		suggestionExtractionQueue,
@@ -112,8 +134,30 @@ func main() {
 
 	logger.Info("starting extraction")
 
-	// start extraction
-	if err := extr.Start(ctx); err != nil {
-		logger.WithError(err).Fatal("github extraction processing failed")
+	// start extraction, returns once the task being handled has finished
+	startErr := extr.Start(ctx)
+
+	if err := suggestionExtractionQueue.Close(); err != nil {
+		logger.WithError(err).Warn("could not close queue")
+	}
+
+	if err := graphStore.Close(); err != nil {
+		logger.WithError(err).Warn("could not close graph store")
 	}
+
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			logger.WithError(err).Warn("could not close Redis client")
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		logger.WithError(err).Warn("could not close db")
+	}
+
+	if startErr != nil {
+		logger.WithError(startErr).Fatal("github extraction processing failed")
+	}
+
+	logger.Info("extraction stopped")
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/config"
+	"github.com/kbariotis/go-discover/internal/setup"
+	"github.com/kbariotis/go-discover/internal/store"
+	"github.com/kbariotis/go-discover/internal/version"
+)
+
+const usage = `usage: migrate <up|status>
+
+Manages the migrations of the neo graph store, the crawler and extraction
+refuse to start while migrations are pending.
+
+Commands:
+  up      apply all pending migrations
+  status  print all migrations and whether they have been applied
+`
+
+// main applies or lists graph migrations
+func main() {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":  "cmd/migrate",
+		"version": version.Version,
+		"gitSHA":  version.GitSHA,
+	})
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command := flag.Arg(0)
+
+	logger.Debug("loading configuration")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("could not load configuration")
+	}
+
+	logLevel, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.WithError(err).Fatal("could not parse log level")
+	}
+
+	logrus.SetLevel(logLevel)
+
+	if cfg.GraphStoreType != "neo" {
+		logger.Fatal("only the neo graph store has migrations, " +
+			cfg.GraphStoreType + " is set up on start")
+	}
+
+	migrator, err := setup.NewNeoGraphStore(cfg)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create graph store")
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Migrate()
+	case "status":
+		err = status(migrator)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := migrator.Close(); err != nil {
+		logger.WithError(err).Warn("could not close graph store")
+	}
+
+	if err != nil {
+		logger.WithError(err).Fatal("could not " + command + " migrations")
+	}
+}
+
+// status prints all migrations and whether they have been applied
+func status(migrator store.GraphMigrator) error {
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%d\t%s\t%s\n", migration.Version, state, migration.Description)
+	}
+
+	return nil
+}
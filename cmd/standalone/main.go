@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mailgun/mailgun-go/v3"
+	"github.com/sirupsen/logrus"
+
+	_ "github.com/jinzhu/gorm/dialects/postgres" // required for postgres
+	_ "github.com/jinzhu/gorm/dialects/sqlite"   // required for sqlite
+
+	"github.com/kbariotis/go-discover/internal/api"
+	"github.com/kbariotis/go-discover/internal/cache"
+	"github.com/kbariotis/go-discover/internal/config"
+	"github.com/kbariotis/go-discover/internal/crawler"
+	"github.com/kbariotis/go-discover/internal/extraction"
+	"github.com/kbariotis/go-discover/internal/mailer"
+	"github.com/kbariotis/go-discover/internal/provider"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/ranker"
+	"github.com/kbariotis/go-discover/internal/setup"
+	"github.com/kbariotis/go-discover/internal/version"
+)
+
+// main initliases and starts the api, crawler and extraction in a single
+// process, using in-memory queues and cache
+func main() {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":  "cmd/standalone",
+		"version": version.Version,
+		"gitSHA":  version.GitSHA,
+	})
+
+	// cancel the context on SIGINT or SIGTERM so that in-flight tasks can finish
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.WithField("signal", sig.String()).Info("shutting down")
+		cancel()
+	}()
+
+	logger.Debug("loading configuration")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.WithError(err).Fatal("could not load configuration")
+	}
+
+	logLevel, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.WithError(err).Fatal("could not parse log level")
+	}
+
+	logrus.SetLevel(logLevel)
+
+	// create queues
+	newQueue := func(name string) *queue.Memory {
+		q, err := queue.NewMemory(cfg.QueueVisibilityTimeout)
+		if err != nil {
+			logger.WithError(err).Fatal("could not create memory queue for " + name)
+		}
+		return q
+	}
+
+	userOnboardingQueue := newQueue("userOnboarding")
+	userFolloweeQueue := newQueue("userFollowee")
+	userQueue := newQueue("user")
+	repositoryQueue := newQueue("repository")
+	userOnboardingDeadLetterQueue := newQueue("userOnboarding.deadletter")
+	userFolloweeDeadLetterQueue := newQueue("userFollowee.deadletter")
+	userDeadLetterQueue := newQueue("user.deadletter")
+	repositoryDeadLetterQueue := newQueue("repository.deadletter")
+	suggestionExtractionQueue := newQueue("suggestionExtraction")
+
+	// connect to suggestions store db
+	db, err := gorm.Open(
+		cfg.SuggestionsStoreType,
+		cfg.SuggestionsStoreDSN,
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not connect to db")
+	}
+
+	// create suggestions store
+	suggestionStore, err := setup.NewSuggestionStore(cfg, db)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create suggestion store")
+	}
+
+	// create graph store
+	graphStore, err := setup.NewGraphStore(cfg, db, true)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create graph store")
+	}
+
+	// create memory cache
+	memoryCache, err := cache.NewMemory(
+		cfg.LockUserDuration,
+		cfg.LockRepositoryDuration,
+		cfg.LockUserProfileDuration,
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create memory cache")
+	}
+
+	// gather github tokens, including the ones of registered users
+	ghTokens := append([]string{}, cfg.GithubTokens...)
+	users, err := suggestionStore.GetAllUsers()
+	if err != nil {
+		logger.WithError(err).Fatal("could not retrieve users")
+	}
+	for _, user := range users {
+		ghTokens = append(ghTokens, user.Token)
+	}
+
+	// create github provider
+	prv, err := provider.NewGithub(cfg.GithubToken, ghTokens)
+	if err != nil {
+		logger.WithError(err).Fatal("could not construct github provider")
+	}
+
+	// create crawler
+	crw, err := crawler.New(
+		time.Minute*5,
+		cfg.ShutdownTimeout,
+		crawler.Workers{
+			UserOnboarding: cfg.CrawlerUserOnboardingWorkers,
+			UserFollowee:   cfg.CrawlerUserFolloweeWorkers,
+			User:           cfg.CrawlerUserWorkers,
+			Repository:     cfg.CrawlerRepositoryWorkers,
+		},
+		crawler.Retry{
+			MaxAttempts: cfg.CrawlerRetryMaxAttempts,
+			Backoff:     cfg.CrawlerRetryBackoff,
+		},
+		graphStore,
+		suggestionStore,
+		memoryCache,
+		prv,
+		userOnboardingQueue,
+		userFolloweeQueue,
+		userQueue,
+		repositoryQueue,
+		userOnboardingDeadLetterQueue,
+		userFolloweeDeadLetterQueue,
+		userDeadLetterQueue,
+		repositoryDeadLetterQueue,
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not construct crawler")
+	}
+
+	// setup mailgun
+	mg := mailgun.NewMailgun(cfg.MailgunDomain, cfg.MailgunAPIKey)
+	mailer, err := mailer.NewMailgun(mg, cfg.MailSenderAddress)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create mailer")
+	}
+
+	// create ranker
+	rnk, err := ranker.New(cfg.SuggestionRanker, cfg.SuggestionHalfLife)
+	if err != nil {
+		logger.WithError(err).Fatal("could not create ranker")
+	}
+
+	// create extraction
+	extr, err := extraction.New(
+		time.Hour*24*7,
+		cfg.ShutdownTimeout,
+		cfg.SuggestionWindow,
+		cfg.SuggestionHistory,
+		rnk,
+		cfg.SuggestionHalfLife,
+		graphStore,
+		suggestionStore,
+		suggestionExtractionQueue,
+		mailer,
+	)
+	if err != nil {
+		logger.WithError(err).Fatal("could not construct extraction")
+	}
+
+	// constrcut api
+	api := api.NewAPI(
+		suggestionStore,
+		cfg.GithubClientID,
+		cfg.GithubClientSecret,
+		cfg.GithubCallbackURL,
+	)
+
+	logger.Info("starting api")
+
+	// start api on the background, it stops along with the process
+	go func() {
+		if err := api.Serve(cfg.APIBindAddress); err != nil {
+			logger.WithError(err).Fatal("api failed")
+		}
+	}()
+
+	// start crawler and extraction, they return once in-flight tasks have
+	// finished and either of them failing stops the other one
+	wg := &sync.WaitGroup{}
+	var crawlerErr, extractionErr error
+
+	logger.Info("starting crawler")
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		crawlerErr = crw.Start(ctx)
+		cancel()
+	}()
+
+	logger.Info("starting extraction")
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		extractionErr = extr.Start(ctx)
+		cancel()
+	}()
+
+	wg.Wait()
+
+	for _, q := range []queue.Queue{
+		userOnboardingQueue,
+		userFolloweeQueue,
+		userQueue,
+		repositoryQueue,
+		userOnboardingDeadLetterQueue,
+		userFolloweeDeadLetterQueue,
+		userDeadLetterQueue,
+		repositoryDeadLetterQueue,
+		suggestionExtractionQueue,
+	} {
+		if err := q.Close(); err != nil {
+			logger.WithError(err).Warn("could not close queue")
+		}
+	}
+
+	if err := graphStore.Close(); err != nil {
+		logger.WithError(err).Warn("could not close graph store")
+	}
+
+	if err := db.Close(); err != nil {
+		logger.WithError(err).Warn("could not close db")
+	}
+
+	if crawlerErr != nil {
+		logger.WithError(crawlerErr).Fatal("github crawler processing failed")
+	}
+
+	if extractionErr != nil {
+		logger.WithError(extractionErr).Fatal("github extraction processing failed")
+	}
+
+	logger.Info("standalone stopped")
+}
@@ -1,31 +1,59 @@
 module github.com/kbariotis/go-discover
 
-go 1.12
+go 1.18
 
 require (
 	github.com/Financial-Times/neoism v1.3.1
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/caarlos0/env v3.5.0+incompatible
-	github.com/fatih/color v1.7.0 // indirect
 	github.com/gin-gonic/gin v1.4.0
 	github.com/go-redis/redis v6.15.2+incompatible
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/golangci/golangci-lint v1.16.0
 	github.com/google/go-github/v25 v25.0.2
-	github.com/google/uuid v1.1.1 // indirect
 	github.com/jinzhu/gorm v1.9.8
-	github.com/jmcvetta/randutil v0.0.0-20150817122601-2bb1b664bcff // indirect
 	github.com/joncrlsn/dque v0.0.0-20190104233530-ceded79bccfe
 	github.com/mailgun/mailgun-go/v3 v3.6.0
-	github.com/mattn/go-colorable v0.1.1 // indirect
 	github.com/mattn/goveralls v0.0.2
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.0.2
-	github.com/mitchellh/go-homedir v1.1.0 // indirect
-	github.com/mitchellh/mapstructure v1.1.2 // indirect
-	github.com/pborman/uuid v1.2.0 // indirect
+	github.com/neo4j/neo4j-go-driver/v5 v5.28.4
 	github.com/pkg/errors v0.8.1
 	github.com/sirupsen/logrus v1.2.0
 	github.com/stretchr/testify v1.3.0
 	golang.org/x/oauth2 v0.0.0-20190226205417-e64efc72b421
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gin-contrib/sse v0.0.0-20190301062529-5545eab6dad3 // indirect
+	github.com/go-chi/chi v4.0.0+incompatible // indirect
+	github.com/golang/protobuf v1.3.1 // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/jinzhu/inflection v0.0.0-20180308033659-04140366298a // indirect
+	github.com/jmcvetta/randutil v0.0.0-20150817122601-2bb1b664bcff // indirect
+	github.com/json-iterator/go v1.1.6 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/lib/pq v1.1.0 // indirect
+	github.com/mailru/easyjson v0.0.0-20180823135443-60711f1a8329 // indirect
+	github.com/mattn/go-isatty v0.0.7 // indirect
+	github.com/mattn/go-sqlite3 v1.10.0 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/pborman/uuid v1.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	github.com/ugorji/go v1.1.4 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
+	golang.org/x/crypto v0.0.0-20190325154230-a5d413f7728c // indirect
+	golang.org/x/net v0.0.0-20190503192946-f4e77d36d62c // indirect
 	golang.org/x/sys v0.0.0-20190322080309-f49334f85ddc // indirect
+	golang.org/x/tools v0.0.0-20190320215829-36c10c0a621f // indirect
+	google.golang.org/appengine v1.4.0 // indirect
+	gopkg.in/go-playground/validator.v8 v8.18.2 // indirect
 	gopkg.in/jmcvetta/napping.v3 v3.2.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
@@ -185,6 +185,7 @@ func (api *API) registerUser(githubToken string) (*model.User, error) {
 	user := &model.User{
 		Name:  ghUser.GetLogin(),
 		Email: ghUser.GetEmail(),
+		Token: githubToken,
 	}
 
 	if user.Email == "" {
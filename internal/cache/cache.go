@@ -5,8 +5,11 @@ import "github.com/pkg/errors"
 // ErrAlreadyLocked is returned on Lock* when the key is already locked
 var ErrAlreadyLocked = errors.New("key already locked")
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Cache
+
 // Cache defines the interface for the cache implementations
 type Cache interface {
 	LockUser(user string) error
 	LockRepository(name string) error
+	LockUserProfile(user string) error
 }
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory cache implementation, meant for running everything in a single
+// process
+type Memory struct {
+	mutex sync.Mutex
+	locks map[string]time.Time
+
+	userLockDuration        time.Duration
+	repositoryLockDuration  time.Duration
+	userProfileLockDuration time.Duration
+}
+
+// NewMemory constructs a new in-memory cache
+func NewMemory(
+	lockUserDuration time.Duration,
+	lockRepositoryDuration time.Duration,
+	lockUserProfileDuration time.Duration,
+) (Cache, error) {
+	mem := &Memory{
+		locks:                   map[string]time.Time{},
+		userLockDuration:        lockUserDuration,
+		repositoryLockDuration:  lockRepositoryDuration,
+		userProfileLockDuration: lockUserProfileDuration,
+	}
+
+	return mem, nil
+}
+
+// LockUser locks a user for an x amount of time
+func (mem *Memory) LockUser(user string) error {
+	return mem.lock("user/"+user, mem.userLockDuration)
+}
+
+// LockRepository locks a repository for an x amount of time
+func (mem *Memory) LockRepository(name string) error {
+	return mem.lock("repository/"+name, mem.repositoryLockDuration)
+}
+
+// LockUserProfile locks a user's profile for an x amount of time
+func (mem *Memory) LockUserProfile(user string) error {
+	return mem.lock("user-profile/"+user, mem.userProfileLockDuration)
+}
+
+func (mem *Memory) lock(key string, duration time.Duration) error {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	now := time.Now()
+	if expiry, ok := mem.locks[key]; ok && now.Before(expiry) {
+		return ErrAlreadyLocked
+	}
+
+	mem.locks[key] = now.Add(duration)
+
+	return nil
+}
@@ -10,8 +10,9 @@ import (
 type Redis struct {
 	client *redis.Client
 
-	userLockDuration       time.Duration
-	repositoryLockDuration time.Duration
+	userLockDuration        time.Duration
+	repositoryLockDuration  time.Duration
+	userProfileLockDuration time.Duration
 }
 
 // NewRedis constructs a new Redis cache given a Redis client
@@ -19,9 +20,13 @@ func NewRedis(
 	client *redis.Client,
 	lockUserDuration time.Duration,
 	lockRepositoryDuration time.Duration,
+	lockUserProfileDuration time.Duration,
 ) (Cache, error) {
 	red := &Redis{
-		client: client,
+		client:                  client,
+		userLockDuration:        lockUserDuration,
+		repositoryLockDuration:  lockRepositoryDuration,
+		userProfileLockDuration: lockUserProfileDuration,
 	}
 
 	return red, nil
@@ -46,3 +51,17 @@ func (red *Redis) LockRepository(name string) error {
 	}
 	return nil
 }
+
+// LockUserProfile locks a user's profile for an x amount of time
+func (red *Redis) LockUserProfile(user string) error {
+	key := "user-profile/" + user
+	duration := red.userProfileLockDuration
+	ok, err := red.client.SetNX(key, "value", duration).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAlreadyLocked
+	}
+	return nil
+}
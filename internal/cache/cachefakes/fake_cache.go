@@ -0,0 +1,259 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package cachefakes
+
+import (
+	"sync"
+
+	"github.com/kbariotis/go-discover/internal/cache"
+)
+
+type FakeCache struct {
+	LockRepositoryStub        func(string) error
+	lockRepositoryMutex       sync.RWMutex
+	lockRepositoryArgsForCall []struct {
+		arg1 string
+	}
+	lockRepositoryReturns struct {
+		result1 error
+	}
+	lockRepositoryReturnsOnCall map[int]struct {
+		result1 error
+	}
+	LockUserStub        func(string) error
+	lockUserMutex       sync.RWMutex
+	lockUserArgsForCall []struct {
+		arg1 string
+	}
+	lockUserReturns struct {
+		result1 error
+	}
+	lockUserReturnsOnCall map[int]struct {
+		result1 error
+	}
+	LockUserProfileStub        func(string) error
+	lockUserProfileMutex       sync.RWMutex
+	lockUserProfileArgsForCall []struct {
+		arg1 string
+	}
+	lockUserProfileReturns struct {
+		result1 error
+	}
+	lockUserProfileReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeCache) LockRepository(arg1 string) error {
+	fake.lockRepositoryMutex.Lock()
+	ret, specificReturn := fake.lockRepositoryReturnsOnCall[len(fake.lockRepositoryArgsForCall)]
+	fake.lockRepositoryArgsForCall = append(fake.lockRepositoryArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.LockRepositoryStub
+	fakeReturns := fake.lockRepositoryReturns
+	fake.recordInvocation("LockRepository", []interface{}{arg1})
+	fake.lockRepositoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) LockRepositoryCallCount() int {
+	fake.lockRepositoryMutex.RLock()
+	defer fake.lockRepositoryMutex.RUnlock()
+	return len(fake.lockRepositoryArgsForCall)
+}
+
+func (fake *FakeCache) LockRepositoryCalls(stub func(string) error) {
+	fake.lockRepositoryMutex.Lock()
+	defer fake.lockRepositoryMutex.Unlock()
+	fake.LockRepositoryStub = stub
+}
+
+func (fake *FakeCache) LockRepositoryArgsForCall(i int) string {
+	fake.lockRepositoryMutex.RLock()
+	defer fake.lockRepositoryMutex.RUnlock()
+	argsForCall := fake.lockRepositoryArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) LockRepositoryReturns(result1 error) {
+	fake.lockRepositoryMutex.Lock()
+	defer fake.lockRepositoryMutex.Unlock()
+	fake.LockRepositoryStub = nil
+	fake.lockRepositoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) LockRepositoryReturnsOnCall(i int, result1 error) {
+	fake.lockRepositoryMutex.Lock()
+	defer fake.lockRepositoryMutex.Unlock()
+	fake.LockRepositoryStub = nil
+	if fake.lockRepositoryReturnsOnCall == nil {
+		fake.lockRepositoryReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.lockRepositoryReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) LockUser(arg1 string) error {
+	fake.lockUserMutex.Lock()
+	ret, specificReturn := fake.lockUserReturnsOnCall[len(fake.lockUserArgsForCall)]
+	fake.lockUserArgsForCall = append(fake.lockUserArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.LockUserStub
+	fakeReturns := fake.lockUserReturns
+	fake.recordInvocation("LockUser", []interface{}{arg1})
+	fake.lockUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) LockUserCallCount() int {
+	fake.lockUserMutex.RLock()
+	defer fake.lockUserMutex.RUnlock()
+	return len(fake.lockUserArgsForCall)
+}
+
+func (fake *FakeCache) LockUserCalls(stub func(string) error) {
+	fake.lockUserMutex.Lock()
+	defer fake.lockUserMutex.Unlock()
+	fake.LockUserStub = stub
+}
+
+func (fake *FakeCache) LockUserArgsForCall(i int) string {
+	fake.lockUserMutex.RLock()
+	defer fake.lockUserMutex.RUnlock()
+	argsForCall := fake.lockUserArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) LockUserReturns(result1 error) {
+	fake.lockUserMutex.Lock()
+	defer fake.lockUserMutex.Unlock()
+	fake.LockUserStub = nil
+	fake.lockUserReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) LockUserReturnsOnCall(i int, result1 error) {
+	fake.lockUserMutex.Lock()
+	defer fake.lockUserMutex.Unlock()
+	fake.LockUserStub = nil
+	if fake.lockUserReturnsOnCall == nil {
+		fake.lockUserReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.lockUserReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) LockUserProfile(arg1 string) error {
+	fake.lockUserProfileMutex.Lock()
+	ret, specificReturn := fake.lockUserProfileReturnsOnCall[len(fake.lockUserProfileArgsForCall)]
+	fake.lockUserProfileArgsForCall = append(fake.lockUserProfileArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.LockUserProfileStub
+	fakeReturns := fake.lockUserProfileReturns
+	fake.recordInvocation("LockUserProfile", []interface{}{arg1})
+	fake.lockUserProfileMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCache) LockUserProfileCallCount() int {
+	fake.lockUserProfileMutex.RLock()
+	defer fake.lockUserProfileMutex.RUnlock()
+	return len(fake.lockUserProfileArgsForCall)
+}
+
+func (fake *FakeCache) LockUserProfileCalls(stub func(string) error) {
+	fake.lockUserProfileMutex.Lock()
+	defer fake.lockUserProfileMutex.Unlock()
+	fake.LockUserProfileStub = stub
+}
+
+func (fake *FakeCache) LockUserProfileArgsForCall(i int) string {
+	fake.lockUserProfileMutex.RLock()
+	defer fake.lockUserProfileMutex.RUnlock()
+	argsForCall := fake.lockUserProfileArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCache) LockUserProfileReturns(result1 error) {
+	fake.lockUserProfileMutex.Lock()
+	defer fake.lockUserProfileMutex.Unlock()
+	fake.LockUserProfileStub = nil
+	fake.lockUserProfileReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) LockUserProfileReturnsOnCall(i int, result1 error) {
+	fake.lockUserProfileMutex.Lock()
+	defer fake.lockUserProfileMutex.Unlock()
+	fake.LockUserProfileStub = nil
+	if fake.lockUserProfileReturnsOnCall == nil {
+		fake.lockUserProfileReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.lockUserProfileReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCache) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.lockRepositoryMutex.RLock()
+	defer fake.lockRepositoryMutex.RUnlock()
+	fake.lockUserMutex.RLock()
+	defer fake.lockUserMutex.RUnlock()
+	fake.lockUserProfileMutex.RLock()
+	defer fake.lockUserProfileMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeCache) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ cache.Cache = new(FakeCache)
@@ -8,26 +8,55 @@ import (
 
 // Config contains various configuration settings
 type Config struct {
-	LogLevel             string `env:"LOG_LEVEL" envDefault:"info"`
-	SuggestionsStoreType string `env:"SUGGESTION_STORE_TYPE" envDefault:"sqlite3"`
-	SuggestionsStoreDSN  string `env:"SUGGESTION_STORE_DSN" envDefault:"./local/suggestions.db"`
-	QueueStoreDir        string `env:"QUEUE_STORE_DIR" envDefault:"./local/queues" envExpand:"true"`
-	NeoHost              string `env:"NEO4J_HOST" envDefault:"http://localhost:7474/db/data"`
-	RedisHost            string `env:"REDIS_HOST" envDefault:"localhost:6379"`
-	APIBindAddress       string `env:"API_BIND_ADDRESS" envDefault:"0.0.0.0:8080"`
-
-	GithubToken        string `env:"GITHUB_TOKEN"`
-	GithubClientSecret string `env:"GITHUB_CLIENT_SECRET"`
-	GithubClientID     string `env:"GITHUB_CLIENT_ID"`
-	GithubCallbackURL  string `env:"GITHUB_CALLBACK_URL" envDefault:"http://localhost:8080/github/callback"`
+	LogLevel               string        `env:"LOG_LEVEL" envDefault:"info"`
+	SuggestionsStoreType   string        `env:"SUGGESTION_STORE_TYPE" envDefault:"sqlite3"`
+	SuggestionsStoreDSN    string        `env:"SUGGESTION_STORE_DSN" envDefault:"./local/suggestions.db"`
+	QueueType              string        `env:"QUEUE_TYPE" envDefault:"dque"`
+	QueueStoreDir          string        `env:"QUEUE_STORE_DIR" envDefault:"./local/queues" envExpand:"true"`
+	QueueVisibilityTimeout time.Duration `env:"QUEUE_VISIBILITY_TIMEOUT" envDefault:"30m"`
+	GraphStoreType         string        `env:"GRAPH_STORE_TYPE" envDefault:"neo"`
+	NeoDriver              string        `env:"NEO4J_DRIVER" envDefault:"rest"`
+	NeoHost                string        `env:"NEO4J_HOST" envDefault:"http://localhost:7474/db/data"`
+	NeoBoltURI             string        `env:"NEO4J_BOLT_URI" envDefault:"bolt://localhost:7687"`
+	NeoUsername            string        `env:"NEO4J_USERNAME" envDefault:"neo4j"`
+	NeoPassword            string        `env:"NEO4J_PASSWORD"`
+	NeoDatabase            string        `env:"NEO4J_DATABASE"`
+	NeoBatchSize           int           `env:"NEO4J_BATCH_SIZE" envDefault:"1000"`
+	NeoStartTimeout        time.Duration `env:"NEO4J_START_TIMEOUT" envDefault:"30s"`
+	RedisHost              string        `env:"REDIS_HOST" envDefault:"localhost:6379"`
+	APIBindAddress         string        `env:"API_BIND_ADDRESS" envDefault:"0.0.0.0:8080"`
+
+	GithubToken        string   `env:"GITHUB_TOKEN"`
+	GithubTokens       []string `env:"GITHUB_TOKENS" envSeparator:","`
+	GithubClientSecret string   `env:"GITHUB_CLIENT_SECRET"`
+	GithubClientID     string   `env:"GITHUB_CLIENT_ID"`
+	GithubCallbackURL  string   `env:"GITHUB_CALLBACK_URL" envDefault:"http://localhost:8080/github/callback"`
+
+	TokenEncryptionKey string `env:"TOKEN_ENCRYPTION_KEY"`
 
 	MailgunDomain string `env:"MAILGUN_DOMAIN"`
 	MailgunAPIKey string `env:"MAILGUN_APIKEY"`
 
 	MailSenderAddress string `env:"MAIL_SENDER_ADDRESS"`
 
-	LockUserDuration       time.Duration `env:"LOCK_USER_DURATION" envDefault:"12h"`
-	LockRepositoryDuration time.Duration `env:"LOCK_REPOSITORY_DURATION" envDefault:"24h"`
+	SuggestionRanker   string        `env:"SUGGESTION_RANKER" envDefault:"decayed"`
+	SuggestionWindow   time.Duration `env:"SUGGESTION_WINDOW" envDefault:"2160h"`
+	SuggestionHalfLife time.Duration `env:"SUGGESTION_HALF_LIFE" envDefault:"168h"`
+	SuggestionHistory  time.Duration `env:"SUGGESTION_HISTORY" envDefault:"672h"`
+
+	CrawlerUserOnboardingWorkers int `env:"CRAWLER_USER_ONBOARDING_WORKERS" envDefault:"1"`
+	CrawlerUserFolloweeWorkers   int `env:"CRAWLER_USER_FOLLOWEE_WORKERS" envDefault:"2"`
+	CrawlerUserWorkers           int `env:"CRAWLER_USER_WORKERS" envDefault:"2"`
+	CrawlerRepositoryWorkers     int `env:"CRAWLER_REPOSITORY_WORKERS" envDefault:"4"`
+
+	CrawlerRetryMaxAttempts int           `env:"CRAWLER_RETRY_MAX_ATTEMPTS" envDefault:"5"`
+	CrawlerRetryBackoff     time.Duration `env:"CRAWLER_RETRY_BACKOFF" envDefault:"1m"`
+
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+
+	LockUserDuration        time.Duration `env:"LOCK_USER_DURATION" envDefault:"12h"`
+	LockRepositoryDuration  time.Duration `env:"LOCK_REPOSITORY_DURATION" envDefault:"24h"`
+	LockUserProfileDuration time.Duration `env:"LOCK_USER_PROFILE_DURATION" envDefault:"168h"`
 }
 
 // loadConfig parses environment variables returning configuration
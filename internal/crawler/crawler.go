@@ -2,6 +2,7 @@ package crawler
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,9 +15,23 @@ import (
 	"github.com/kbariotis/go-discover/internal/store"
 )
 
+// ErrShutdownTimeout is returned when in-flight tasks did not finish in time
+var ErrShutdownTimeout = errors.New("timed out waiting for in-flight tasks")
+
+// Workers defines the number of concurrent workers for each queue
+type Workers struct {
+	UserOnboarding int
+	UserFollowee   int
+	User           int
+	Repository     int
+}
+
 // Crawler is our main orchestrating service
 type Crawler struct {
 	followerPollInterval time.Duration
+	shutdownTimeout      time.Duration
+	workers              Workers
+	retry                Retry
 
 	graphStore      store.GraphStore
 	suggestionStore store.SuggestionStore // Rename because it includes all SQL store
@@ -27,11 +42,19 @@ type Crawler struct {
 	userFolloweeQueue   queue.Queue
 	userQueue           queue.Queue
 	repositoryQueue     queue.Queue
+
+	userOnboardingDeadLetterQueue queue.Queue
+	userFolloweeDeadLetterQueue   queue.Queue
+	userDeadLetterQueue           queue.Queue
+	repositoryDeadLetterQueue     queue.Queue
 }
 
 // New constructs a Github crawler
 func New(
 	followerPollInterval time.Duration,
+	shutdownTimeout time.Duration,
+	workers Workers,
+	retry Retry,
 	graphStore store.GraphStore,
 	suggestionStore store.SuggestionStore,
 	cache cache.Cache,
@@ -40,7 +63,17 @@ func New(
 	userFolloweeQueue queue.Queue,
 	userQueue queue.Queue,
 	repositoryQueue queue.Queue,
+	userOnboardingDeadLetterQueue queue.Queue,
+	userFolloweeDeadLetterQueue queue.Queue,
+	userDeadLetterQueue queue.Queue,
+	repositoryDeadLetterQueue queue.Queue,
 ) (*Crawler, error) {
+	if workers.UserOnboarding < 1 ||
+		workers.UserFollowee < 1 ||
+		workers.User < 1 ||
+		workers.Repository < 1 {
+		return nil, errors.New("every queue needs at least one worker")
+	}
 
 	crw := &Crawler{
 		graphStore:           graphStore,
@@ -48,10 +81,18 @@ func New(
 		cache:                cache,
 		provider:             provider,
 		followerPollInterval: followerPollInterval,
+		shutdownTimeout:      shutdownTimeout,
+		workers:              workers,
+		retry:                retry,
 		userOnboardingQueue:  userOnboardingQueue,
 		userFolloweeQueue:    userFolloweeQueue,
 		userQueue:            userQueue,
 		repositoryQueue:      repositoryQueue,
+
+		userOnboardingDeadLetterQueue: userOnboardingDeadLetterQueue,
+		userFolloweeDeadLetterQueue:   userFolloweeDeadLetterQueue,
+		userDeadLetterQueue:           userDeadLetterQueue,
+		repositoryDeadLetterQueue:     repositoryDeadLetterQueue,
 	}
 
 	return crw, nil
@@ -71,6 +112,19 @@ func (c *Crawler) processRegisteredUsers() error {
 		return errors.Wrap(err, "could not retrieve users")
 	}
 
+	// lend registered users' tokens to the provider's pool
+	for _, user := range users {
+		c.provider.AddToken(user.Token)
+	}
+
+	// don't enqueue more work while the provider is out of budget
+	if rate := c.provider.RateLimit(); rate.Exhausted() {
+		logger.
+			WithField("rate.reset", rate.Reset).
+			Info("provider rate limit exhausted, skipping")
+		return nil
+	}
+
 	for _, user := range users {
 		logger.
 			WithField("user", user).
@@ -88,9 +142,27 @@ func (c *Crawler) processRegisteredUsers() error {
 	return nil
 }
 
-func (c *Crawler) handleUserOnboardingTask(task *model.UserOnboardingTask) error {
-	ctx := context.Background()
+// scheduleUserTask pushes a user task the first time we see a user
+func (c *Crawler) scheduleUserTask(name string) error {
+	if err := c.cache.LockUserProfile(name); err != nil {
+		if err == cache.ErrAlreadyLocked {
+			return nil
+		}
+		return errors.Wrap(err, "could not cache user profile")
+	}
+
+	userTask := &model.UserTask{
+		Name: name,
+	}
+
+	if err := c.userQueue.Push(userTask); err != nil {
+		return errors.Wrap(err, "could not add user task to queue")
+	}
 
+	return nil
+}
+
+func (c *Crawler) handleUserOnboardingTask(ctx context.Context, task *model.UserOnboardingTask) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"logger": "crawler/Github.handleUserOnboardingTask",
 		"task":   task,
@@ -129,6 +201,10 @@ func (c *Crawler) handleUserOnboardingTask(task *model.UserOnboardingTask) error
 		if err := c.userFolloweeQueue.Push(followeeTask); err != nil {
 			return errors.Wrap(err, "could not add followee task to queue")
 		}
+
+		if err := c.scheduleUserTask(followee); err != nil {
+			return errors.Wrap(err, "could not schedule user task")
+		}
 	}
 
 	// upsert the user to the c.graphStore
@@ -144,9 +220,7 @@ func (c *Crawler) handleUserOnboardingTask(task *model.UserOnboardingTask) error
 	return nil
 }
 
-func (c *Crawler) handleUserFolloweeTask(task *model.UserFolloweeTask) error {
-	ctx := context.Background()
-
+func (c *Crawler) handleUserFolloweeTask(ctx context.Context, task *model.UserFolloweeTask) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"logger": "crawler/Github.handleUserFolloweeTask",
 		"task":   task,
@@ -154,13 +228,15 @@ func (c *Crawler) handleUserFolloweeTask(task *model.UserFolloweeTask) error {
 
 	logger.Info("handling model.UserFolloweeTask")
 
-	// check if user is in the cache
-	if err := c.cache.LockUser(task.Name); err != nil {
-		if err == cache.ErrAlreadyLocked {
-			logger.Info("User's cached, skipping")
-			return nil
-		}
-
+	// check if user is in the cache, retries hold the lock of their first
+	// attempt so they are not skipped
+	err := c.cache.LockUser(task.Name)
+	switch {
+	case err == cache.ErrAlreadyLocked && task.Attempts > 0:
+	case err == cache.ErrAlreadyLocked:
+		logger.Info("User's cached, skipping")
+		return nil
+	case err != nil:
 		return errors.Wrap(err, "could not cache user")
 	}
 
@@ -182,12 +258,26 @@ func (c *Crawler) handleUserFolloweeTask(task *model.UserFolloweeTask) error {
 		})
 	}
 
-	// TODO fetch user's repositories
+	// fetch user's own repositories
+	repos, err := c.provider.GetUserRepositories(ctx, task.Name)
+	if err != nil {
+		return errors.Wrap(err, "could not get user's repositories")
+	}
+
+	// push all owned repos to repository queue
+	for _, repo := range repos {
+		if err := c.repositoryQueue.Push(&model.RepositoryTask{
+			Name: repo.Repository,
+		}); err != nil {
+			return errors.Wrap(err, "could not add repository task to queue")
+		}
+	}
 
 	// upsert the user to the c.graphStore
 	user := &model.User{
 		Name:  task.Name,
 		Stars: stars,
+		Owns:  repos,
 	}
 
 	if err := c.graphStore.PutUser(user); err != nil {
@@ -197,22 +287,29 @@ func (c *Crawler) handleUserFolloweeTask(task *model.UserFolloweeTask) error {
 	return nil
 }
 
-func (c *Crawler) handleUserTask(task *model.UserTask) error {
+func (c *Crawler) handleUserTask(ctx context.Context, task *model.UserTask) error {
 	logger := logrus.WithFields(logrus.Fields{
-		"logger": "crawler/handleUserTask.handleUserOnboardingTask",
+		"logger": "crawler/Github.handleUserTask",
 		"task":   task,
 	})
 
 	logger.Info("handling model.UserTask")
 
-	// TODO handle model.UserTask
+	// get user's profile
+	profile, err := c.provider.GetUser(ctx, task.Name)
+	if err != nil {
+		return errors.Wrap(err, "could not get user's profile")
+	}
+
+	// upsert the user's profile
+	if err := c.graphStore.PutUserProfile(profile); err != nil {
+		return errors.Wrap(err, "could not store user's profile")
+	}
 
 	return nil
 }
 
-func (c *Crawler) handleRepositoryTask(task *model.RepositoryTask) error {
-	ctx := context.Background()
-
+func (c *Crawler) handleRepositoryTask(ctx context.Context, task *model.RepositoryTask) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"logger": "crawler/Github.handleRepositoryTask",
 		"task":   task,
@@ -220,12 +317,15 @@ func (c *Crawler) handleRepositoryTask(task *model.RepositoryTask) error {
 
 	logger.Info("handling model.RepositoryTask")
 
-	// check if repository is in the cache
-	if err := c.cache.LockRepository(task.Name); err != nil {
-		if err == cache.ErrAlreadyLocked {
-			logger.Info("Repository's cached, skipping")
-			return nil
-		}
+	// check if repository is in the cache, retries hold the lock of their
+	// first attempt so they are not skipped
+	err := c.cache.LockRepository(task.Name)
+	switch {
+	case err == cache.ErrAlreadyLocked && task.Attempts > 0:
+	case err == cache.ErrAlreadyLocked:
+		logger.Info("Repository's cached, skipping")
+		return nil
+	case err != nil:
 		return errors.Wrap(err, "could not cache repository")
 	}
 
@@ -240,113 +340,258 @@ func (c *Crawler) handleRepositoryTask(task *model.RepositoryTask) error {
 		return errors.Wrap(err, "could not store repository")
 	}
 
+	// schedule profile updates for the repository's stargazers
+	for _, star := range repository.Stars {
+		if err := c.scheduleUserTask(star.User); err != nil {
+			return errors.Wrap(err, "could not schedule user task")
+		}
+	}
+
 	return nil
 }
 
-// Start crawling
-func (c *Crawler) Start(ctx context.Context) error {
-	cctx, _ := context.WithCancel(ctx)
+// throttle blocks while the provider has no rate limit budget left
+func (c *Crawler) throttle(ctx context.Context) {
+	rate := c.provider.RateLimit()
+	if !rate.Exhausted() {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"logger":     "crawler/Github.throttle",
+		"rate.reset": rate.Reset,
+	}).Info("provider rate limit exhausted, throttling")
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Until(rate.Reset)):
+	}
+}
+
+// startWorkers starts a number of workers that pop tasks from the given queue
+// and handle them until the context is cancelled, failed tasks are retried
+// and eventually moved to the dead-letter queue
+// Tasks are handled with handlerCtx so that they can finish after ctx is done
+func (c *Crawler) startWorkers(
+	ctx context.Context,
+	handlerCtx context.Context,
+	wg *sync.WaitGroup,
+	name string,
+	count int,
+	q queue.Queue,
+	deadLetterQueue queue.Queue,
+	handle func(ctx context.Context, task interface{}) error,
+) {
 	logger := logrus.WithFields(logrus.Fields{
-		"logger": "crawler/Github.Start",
+		"logger":  "crawler/Github.startWorkers",
+		"queue":   name,
+		"workers": count,
 	})
 
-	userOnboardingTasks := make(chan *model.UserOnboardingTask, 10000)
-	userFolloweeTasks := make(chan *model.UserFolloweeTask, 10000)
-	userTasks := make(chan *model.UserTask, 10000)
-	repositoryTasks := make(chan *model.RepositoryTask, 10000)
-
-	// pop tasks from userOnboardingQueue and push them to a local channel
-	go func() {
-		logger.Info("starting to pop tasks from userOnboardingQueue")
-		for {
-			task, _ := c.userOnboardingQueue.Pop() // TODO handle error
-			if task == nil {
-				time.Sleep(time.Second)
-				continue
-			}
-			if okTask, ok := task.(*model.UserOnboardingTask); ok {
-				userOnboardingTasks <- okTask
+	logger.Info("starting workers")
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			workerLogger := logger.WithField("worker", worker)
+			for {
+				c.throttle(ctx)
+
+				lease, err := q.PopContext(ctx)
+				if ctx.Err() != nil {
+					// the task might have been popped just before stopping
+					if lease != nil {
+						nackLease(workerLogger, q, lease)
+					}
+					return
+				}
+				if err != nil {
+					workerLogger.WithError(err).Warn("could not pop task")
+					time.Sleep(time.Second)
+					continue
+				}
+
+				c.handleLease(
+					handlerCtx,
+					workerLogger,
+					q,
+					deadLetterQueue,
+					lease,
+					handle,
+				)
 			}
+		}(i)
+	}
+}
+
+// handleLease handles a leased task and acknowledges it once the task has been
+// either handled or rescheduled
+func (c *Crawler) handleLease(
+	ctx context.Context,
+	logger *logrus.Entry,
+	q queue.Queue,
+	deadLetterQueue queue.Queue,
+	lease *queue.Lease,
+	handle func(ctx context.Context, task interface{}) error,
+) {
+	task := lease.Task
+
+	// queues hold delayed tasks back until they are due, but some of them
+	// return them early after a restart
+	if at, ok := retryAt(task); ok {
+		if err := q.PushDelayed(task, at); err != nil {
+			logger.WithError(err).Warn("could not push back delayed task")
+			nackLease(logger, q, lease)
+			return
 		}
-	}()
+		ackLease(logger, q, lease)
+		return
+	}
 
-	// pop tasks from userFolloweeQueue and push them to a local channel
-	go func() {
-		logger.Info("starting to pop tasks from userFolloweeQueue")
-		for {
-			task, _ := c.userFolloweeQueue.Pop() // TODO handle error
-			if task == nil {
-				time.Sleep(time.Second)
-				continue
-			}
-			if okTask, ok := task.(*model.UserFolloweeTask); ok {
-				userFolloweeTasks <- okTask
-			}
+	if err := handle(ctx, task); err != nil {
+		// tasks interrupted by shutting down don't count as failed attempts
+		if ctx.Err() != nil {
+			logger.WithError(err).Warn("task interrupted, returning to queue")
+			nackLease(logger, q, lease)
+			return
 		}
-	}()
 
-	// pop tasks from userQueue and push them to a local channel
-	go func() {
-		logger.Info("starting to pop tasks from userQueue")
-		for {
-			task, _ := c.userQueue.Pop() // TODO handle error
-			if task == nil {
-				time.Sleep(time.Second)
-				continue
-			}
-			if okTask, ok := task.(*model.UserTask); ok {
-				userTasks <- okTask
-			}
+		logger.WithError(err).Warn("failed to handle task, retrying")
+		if err := c.retryTask(q, deadLetterQueue, task); err != nil {
+			logger.WithError(err).Error("could not retry task")
+			nackLease(logger, q, lease)
+			return
 		}
-	}()
+	}
 
-	// pop tasks from repositoryQueue and push them to a local channel
-	go func() {
-		logger.Info("starting to pop tasks from repositoryQueue")
-		for {
-			task, _ := c.repositoryQueue.Pop() // TODO handle error
-			if task == nil {
-				time.Sleep(time.Second)
-				continue
+	ackLease(logger, q, lease)
+}
+
+func ackLease(logger *logrus.Entry, q queue.Queue, lease *queue.Lease) {
+	if err := q.Ack(lease); err != nil {
+		logger.WithError(err).Warn("could not ack task")
+	}
+}
+
+func nackLease(logger *logrus.Entry, q queue.Queue, lease *queue.Lease) {
+	if err := q.Nack(lease); err != nil {
+		logger.WithError(err).Warn("could not nack task")
+	}
+}
+
+// Start crawling until the context is done, then wait for in-flight tasks to
+// finish for up to the shutdown timeout
+func (c *Crawler) Start(ctx context.Context) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger": "crawler/Github.Start",
+	})
+
+	// in-flight tasks are only cancelled if they exceed the shutdown timeout
+	handlerCtx, cancelHandlers := context.WithCancel(context.Background())
+	defer cancelHandlers()
+
+	wg := &sync.WaitGroup{}
+
+	c.startWorkers(
+		ctx,
+		handlerCtx,
+		wg,
+		"userOnboardingQueue",
+		c.workers.UserOnboarding,
+		c.userOnboardingQueue,
+		c.userOnboardingDeadLetterQueue,
+		func(ctx context.Context, task interface{}) error {
+			okTask, ok := task.(*model.UserOnboardingTask)
+			if !ok {
+				return errors.New("invalid model.UserOnboardingTask")
 			}
-			if okTask, ok := task.(*model.RepositoryTask); ok {
-				repositoryTasks <- okTask
+			return c.handleUserOnboardingTask(ctx, okTask)
+		},
+	)
+
+	c.startWorkers(
+		ctx,
+		handlerCtx,
+		wg,
+		"userFolloweeQueue",
+		c.workers.UserFollowee,
+		c.userFolloweeQueue,
+		c.userFolloweeDeadLetterQueue,
+		func(ctx context.Context, task interface{}) error {
+			okTask, ok := task.(*model.UserFolloweeTask)
+			if !ok {
+				return errors.New("invalid model.UserFolloweeTask")
 			}
-		}
-	}()
+			return c.handleUserFolloweeTask(ctx, okTask)
+		},
+	)
+
+	c.startWorkers(
+		ctx,
+		handlerCtx,
+		wg,
+		"userQueue",
+		c.workers.User,
+		c.userQueue,
+		c.userDeadLetterQueue,
+		func(ctx context.Context, task interface{}) error {
+			okTask, ok := task.(*model.UserTask)
+			if !ok {
+				return errors.New("invalid model.UserTask")
+			}
+			return c.handleUserTask(ctx, okTask)
+		},
+	)
+
+	c.startWorkers(
+		ctx,
+		handlerCtx,
+		wg,
+		"repositoryQueue",
+		c.workers.Repository,
+		c.repositoryQueue,
+		c.repositoryDeadLetterQueue,
+		func(ctx context.Context, task interface{}) error {
+			okTask, ok := task.(*model.RepositoryTask)
+			if !ok {
+				return errors.New("invalid model.RepositoryTask")
+			}
+			return c.handleRepositoryTask(ctx, okTask)
+		},
+	)
 
 	followerPollTicker := time.NewTicker(c.followerPollInterval)
+	defer followerPollTicker.Stop()
 
 	for {
 		select {
-		case <-cctx.Done():
-			return nil
+		case <-ctx.Done():
+			logger.Info("stopping, waiting for in-flight tasks to finish")
+			return drain(wg, c.shutdownTimeout, cancelHandlers)
 
 		case <-followerPollTicker.C:
 			if err := c.processRegisteredUsers(); err != nil {
 				logger.WithError(err).Warn("processRegisteredUsers failed")
 			}
+		}
+	}
+}
 
-		case task := <-userOnboardingTasks:
-			if err := c.handleUserOnboardingTask(task); err != nil {
-				logger.WithError(err).Warn("failed to handle model.UserOnboardingTask")
-			}
-
-		case task := <-userFolloweeTasks:
-			if err := c.handleUserFolloweeTask(task); err != nil {
-				logger.WithError(err).Warn("failed to handle model.UserFolloweeTask")
-			}
-
-		case task := <-userTasks:
-			if err := c.handleUserTask(task); err != nil {
-				logger.WithError(err).Warn("failed to handle model.UserTask")
-			}
-
-		case task := <-repositoryTasks:
-			if err := c.handleRepositoryTask(task); err != nil {
-				logger.WithError(err).Warn("failed to handle model.RepositoryTask")
-			}
+// drain waits for the wait group, cancelling and abandoning it after the
+// timeout
+func drain(wg *sync.WaitGroup, timeout time.Duration, cancel func()) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
-		}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		cancel()
+		return ErrShutdownTimeout
 	}
 }
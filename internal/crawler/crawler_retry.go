@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/queue"
+)
+
+// Retry defines how failed tasks are retried
+type Retry struct {
+	// MaxAttempts before a task is moved to its dead-letter queue
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on every attempt
+	Backoff time.Duration
+}
+
+// delay returns the backoff delay for the given attempt
+func (r Retry) delay(attempt int) time.Duration {
+	if attempt < 1 {
+		return 0
+	}
+	return r.Backoff * time.Duration(1<<uint(attempt-1))
+}
+
+// retryTask pushes a failed task back to its queue with an increased attempt
+// counter, or to the dead-letter queue once it has exhausted its attempts
+func (c *Crawler) retryTask(q, deadLetterQueue queue.Queue, task interface{}) error {
+	retryableTask, ok := task.(model.RetryableTask)
+	if !ok {
+		return errors.New("task is not retryable")
+	}
+
+	retry := retryableTask.GetRetry()
+	retry.Attempts++
+
+	if retry.Attempts >= c.retry.MaxAttempts {
+		if err := deadLetterQueue.Push(task); err != nil {
+			return errors.Wrap(err, "could not add task to dead-letter queue")
+		}
+		return nil
+	}
+
+	retryAt := time.Now().Add(c.retry.delay(retry.Attempts))
+	retry.RetryAt = retryAt.Unix()
+	if err := q.PushDelayed(task, retryAt); err != nil {
+		return errors.Wrap(err, "could not add task back to queue")
+	}
+
+	return nil
+}
+
+// retryAt returns when a task is due and true if it should not be handled yet
+func retryAt(task interface{}) (time.Time, bool) {
+	retryableTask, ok := task.(model.RetryableTask)
+	if !ok {
+		return time.Time{}, false
+	}
+	at := time.Unix(retryableTask.GetRetry().RetryAt, 0)
+	return at, at.After(time.Now())
+}
@@ -0,0 +1,217 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/cache"
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/provider/providerfakes"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/queue/queuefakes"
+)
+
+func TestRetry_delay(t *testing.T) {
+	r := Retry{
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+	}
+
+	require.Equal(t, time.Duration(0), r.delay(0))
+	require.Equal(t, time.Second, r.delay(1))
+	require.Equal(t, time.Second*2, r.delay(2))
+	require.Equal(t, time.Second*4, r.delay(3))
+	require.Equal(t, time.Second*8, r.delay(4))
+}
+
+func TestCrawler_retryTask(t *testing.T) {
+	c := &Crawler{
+		retry: Retry{
+			MaxAttempts: 3,
+			Backoff:     time.Minute,
+		},
+	}
+	q := &queuefakes.FakeQueue{}
+	deadLetterQueue := &queuefakes.FakeQueue{}
+	task := &model.UserTask{Name: "foo"}
+
+	// first attempts are delayed with an exponential backoff
+	for attempt := 1; attempt < 3; attempt++ {
+		before := time.Now()
+		require.NoError(t, c.retryTask(q, deadLetterQueue, task))
+		require.Equal(t, attempt, task.Attempts)
+		require.Equal(t, attempt, q.PushDelayedCallCount())
+
+		gotTask, gotAt := q.PushDelayedArgsForCall(attempt - 1)
+		require.Equal(t, task, gotTask)
+		require.WithinDuration(t, before.Add(c.retry.delay(attempt)), gotAt, time.Second)
+		require.Equal(t, gotAt.Unix(), task.RetryAt)
+	}
+
+	// exhausted tasks go to the dead-letter queue
+	require.NoError(t, c.retryTask(q, deadLetterQueue, task))
+	require.Equal(t, 3, task.Attempts)
+	require.Equal(t, 2, q.PushDelayedCallCount())
+	require.Equal(t, 1, deadLetterQueue.PushCallCount())
+	require.Equal(t, task, deadLetterQueue.PushArgsForCall(0))
+
+	// failing to push keeps the lease
+	q.PushDelayedReturns(errors.New("push failed"))
+	require.Error(t, c.retryTask(q, deadLetterQueue, &model.UserTask{Name: "bar"}))
+
+	// only retryable tasks can be retried
+	require.Error(t, c.retryTask(q, deadLetterQueue, "foo"))
+}
+
+func TestRetryAt(t *testing.T) {
+	// due tasks
+	_, ok := retryAt(&model.UserTask{})
+	require.False(t, ok)
+	_, ok = retryAt(&model.UserTask{
+		TaskRetry: model.TaskRetry{RetryAt: time.Now().Add(-time.Minute).Unix()},
+	})
+	require.False(t, ok)
+
+	// delayed tasks
+	at := time.Now().Add(time.Minute)
+	gotAt, ok := retryAt(&model.UserTask{
+		TaskRetry: model.TaskRetry{RetryAt: at.Unix()},
+	})
+	require.True(t, ok)
+	require.Equal(t, at.Unix(), gotAt.Unix())
+
+	// tasks that are not retryable are never delayed
+	_, ok = retryAt("foo")
+	require.False(t, ok)
+}
+
+func TestCrawler_handleLease(t *testing.T) {
+	c := &Crawler{
+		retry: Retry{
+			MaxAttempts: 2,
+			Backoff:     time.Millisecond * 50,
+		},
+	}
+	logger := logrus.WithFields(logrus.Fields{})
+
+	q, err := queue.NewMemory(time.Minute)
+	require.NoError(t, err)
+	deadLetterQueue, err := queue.NewMemory(time.Minute)
+	require.NoError(t, err)
+
+	handled := 0
+	handle := func(ctx context.Context, task interface{}) error {
+		handled++
+		return errors.New("handle failed")
+	}
+
+	popLease := func() *queue.Lease {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		lease, err := q.PopContext(ctx)
+		require.NoError(t, err)
+		return lease
+	}
+
+	// failed tasks are held back until they are due
+	require.NoError(t, q.Push(&model.UserTask{Name: "foo"}))
+	c.handleLease(context.Background(), logger, q, deadLetterQueue, popLease(), handle)
+	require.Equal(t, 1, handled)
+	require.Equal(t, 0, q.Len())
+
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	// and handled again once they are
+	c.handleLease(context.Background(), logger, q, deadLetterQueue, popLease(), handle)
+	require.Equal(t, 2, handled)
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 0, q.Leased())
+	require.Equal(t, 1, deadLetterQueue.Len())
+
+	// delayed tasks returned early are pushed back without being handled
+	require.NoError(t, q.Push(&model.UserTask{
+		Name:      "bar",
+		TaskRetry: model.TaskRetry{RetryAt: time.Now().Add(time.Minute).Unix()},
+	}))
+	c.handleLease(context.Background(), logger, q, deadLetterQueue, popLease(), handle)
+	require.Equal(t, 2, handled)
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 1, q.Leased())
+}
+
+func TestCrawler_handleLease_locked(t *testing.T) {
+	provider := &providerfakes.FakeProvider{}
+	provider.GetUserStarsReturns(nil, errors.New("stars failed"))
+	provider.GetRepositoryReturns(nil, errors.New("repository failed"))
+
+	memoryCache, err := cache.NewMemory(time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	c := &Crawler{
+		retry: Retry{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond * 10,
+		},
+		cache:    memoryCache,
+		provider: provider,
+	}
+	logger := logrus.WithFields(logrus.Fields{})
+
+	tests := []struct {
+		name   string
+		task   interface{}
+		handle func(ctx context.Context, task interface{}) error
+		calls  func() int
+	}{
+		{
+			name: "followee",
+			task: &model.UserFolloweeTask{Name: "foo"},
+			handle: func(ctx context.Context, task interface{}) error {
+				return c.handleUserFolloweeTask(ctx, task.(*model.UserFolloweeTask))
+			},
+			calls: provider.GetUserStarsCallCount,
+		},
+		{
+			name: "repository",
+			task: &model.RepositoryTask{Name: "foo/bar"},
+			handle: func(ctx context.Context, task interface{}) error {
+				return c.handleRepositoryTask(ctx, task.(*model.RepositoryTask))
+			},
+			calls: provider.GetRepositoryCallCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := queue.NewMemory(time.Minute)
+			require.NoError(t, err)
+			deadLetterQueue, err := queue.NewMemory(time.Minute)
+			require.NoError(t, err)
+
+			require.NoError(t, q.Push(tt.task))
+
+			// retries are handled despite the lock taken by the first attempt
+			for attempt := 1; attempt <= 3; attempt++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				lease, err := q.PopContext(ctx)
+				cancel()
+				require.NoError(t, err)
+
+				c.handleLease(context.Background(), logger, q, deadLetterQueue, lease, tt.handle)
+				require.Equal(t, attempt, tt.calls())
+			}
+
+			// until they are dead-lettered
+			require.Equal(t, 0, q.Len())
+			require.Equal(t, 0, q.Leased())
+			require.Equal(t, 1, deadLetterQueue.Len())
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/cache"
+	"github.com/kbariotis/go-discover/internal/cache/cachefakes"
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/provider/providerfakes"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/store/storefakes"
+)
+
+func TestCrawler_Start(t *testing.T) {
+	// construct fakes
+	graphStore := &storefakes.FakeGraphStore{}
+	suggestionStore := &storefakes.FakeSuggestionStore{}
+	fakeCache := &cachefakes.FakeCache{}
+	provider := &providerfakes.FakeProvider{}
+
+	lockedRepositories := &sync.Map{}
+	fakeCache.LockRepositoryStub = func(name string) error {
+		if _, locked := lockedRepositories.LoadOrStore(name, true); locked {
+			return cache.ErrAlreadyLocked
+		}
+		return nil
+	}
+
+	provider.GetUserFolloweesReturns([]string{"bar"}, nil)
+	provider.GetUserStarsReturns([]model.StarredRepository{
+		{
+			Repository: "baz/starred",
+			StarredAt:  1,
+		},
+	}, nil)
+	provider.GetUserRepositoriesReturns([]model.OwnedRepository{
+		{
+			Repository: "bar/owned",
+			CreatedAt:  1,
+		},
+	}, nil)
+	provider.GetRepositoryStub = func(
+		ctx context.Context,
+		name string,
+	) (*model.Repository, error) {
+		return &model.Repository{
+			Name: name,
+		}, nil
+	}
+	provider.GetUserStub = func(
+		ctx context.Context,
+		name string,
+	) (*model.UserProfile, error) {
+		return &model.UserProfile{
+			Name: name,
+		}, nil
+	}
+
+	// construct queues
+	queues := make([]*queue.Memory, 8)
+	for i := range queues {
+		q, err := queue.NewMemory(time.Minute)
+		require.NoError(t, err)
+		queues[i] = q
+	}
+
+	// construct crawler
+	crw, err := New(
+		time.Hour,
+		time.Second*10,
+		Workers{
+			UserOnboarding: 1,
+			UserFollowee:   2,
+			User:           2,
+			Repository:     2,
+		},
+		Retry{
+			MaxAttempts: 1,
+		},
+		graphStore,
+		suggestionStore,
+		fakeCache,
+		provider,
+		queues[0],
+		queues[1],
+		queues[2],
+		queues[3],
+		queues[4],
+		queues[5],
+		queues[6],
+		queues[7],
+	)
+	require.NoError(t, err)
+
+	// onboard user
+	require.NoError(t, queues[0].Push(&model.UserOnboardingTask{
+		Name: "foo",
+	}))
+
+	// start crawler
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- crw.Start(ctx)
+	}()
+
+	// wait until all queues have been drained
+	waitFor(t, func() bool {
+		for _, q := range queues {
+			if q.Len() > 0 || q.Leased() > 0 {
+				return false
+			}
+		}
+		return graphStore.PutRepositoryCallCount() == 2
+	})
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// check stored user
+	require.Equal(t, 3, graphStore.PutUserCallCount())
+	require.Equal(t, 1, graphStore.PutUserProfileCallCount())
+	require.Equal(t, "bar", graphStore.PutUserProfileArgsForCall(0).Name)
+
+	// check stored repositories
+	gotRepositories := []string{}
+	for i := 0; i < graphStore.PutRepositoryCallCount(); i++ {
+		gotRepositories = append(
+			gotRepositories,
+			graphStore.PutRepositoryArgsForCall(i).Name,
+		)
+	}
+	require.ElementsMatch(t, []string{"baz/starred", "bar/owned"}, gotRepositories)
+}
+
+func TestCrawler_StartShutdown(t *testing.T) {
+	provider := &providerfakes.FakeProvider{}
+
+	// block onboarding until the test allows it to finish
+	started := make(chan struct{})
+	release := make(chan struct{})
+	provider.GetUserFolloweesStub = func(
+		ctx context.Context,
+		name string,
+	) ([]string, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}
+
+	crw, queues := newOnboardingCrawler(t, time.Second*10, provider)
+	require.NoError(t, queues[0].Push(&model.UserOnboardingTask{
+		Name: "foo",
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- crw.Start(ctx)
+	}()
+
+	<-started
+	cancel()
+
+	// the in-flight task should be allowed to finish
+	select {
+	case <-done:
+		require.FailNow(t, "crawler stopped before in-flight task finished")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+	require.Equal(t, 0, queues[0].Len())
+	require.Equal(t, 0, queues[0].Leased())
+}
+
+func TestCrawler_StartShutdownTimeout(t *testing.T) {
+	provider := &providerfakes.FakeProvider{}
+
+	// block onboarding until the task is cancelled
+	started := make(chan struct{})
+	provider.GetUserFolloweesStub = func(
+		ctx context.Context,
+		name string,
+	) ([]string, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	crw, queues := newOnboardingCrawler(t, time.Millisecond*50, provider)
+	require.NoError(t, queues[0].Push(&model.UserOnboardingTask{
+		Name: "foo",
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- crw.Start(ctx)
+	}()
+
+	<-started
+	cancel()
+	require.Equal(t, ErrShutdownTimeout, <-done)
+
+	// the cancelled task should be returned to the queue
+	waitFor(t, func() bool {
+		return queues[0].Len() == 1 && queues[0].Leased() == 0
+	})
+	require.Equal(t, 0, queues[4].Len())
+}
+
+// newOnboardingCrawler constructs a crawler that only handles onboarding tasks,
+// its other queues are left empty
+func newOnboardingCrawler(
+	t *testing.T,
+	shutdownTimeout time.Duration,
+	provider *providerfakes.FakeProvider,
+) (*Crawler, []*queue.Memory) {
+	queues := make([]*queue.Memory, 8)
+	for i := range queues {
+		q, err := queue.NewMemory(time.Minute)
+		require.NoError(t, err)
+		queues[i] = q
+	}
+
+	crw, err := New(
+		time.Hour,
+		shutdownTimeout,
+		Workers{
+			UserOnboarding: 1,
+			UserFollowee:   1,
+			User:           1,
+			Repository:     1,
+		},
+		Retry{
+			MaxAttempts: 1,
+		},
+		&storefakes.FakeGraphStore{},
+		&storefakes.FakeSuggestionStore{},
+		&cachefakes.FakeCache{},
+		provider,
+		queues[0],
+		queues[1],
+		queues[2],
+		queues[3],
+		queues[4],
+		queues[5],
+		queues[6],
+		queues[7],
+	)
+	require.NoError(t, err)
+
+	return crw, queues
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second * 10)
+	for !condition() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "condition not met in time")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -2,6 +2,7 @@ package extraction
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,12 +11,37 @@ import (
 	"github.com/kbariotis/go-discover/internal/mailer"
 	"github.com/kbariotis/go-discover/internal/model"
 	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/ranker"
 	"github.com/kbariotis/go-discover/internal/store"
 )
 
+const (
+	// suggestionItemsLimit is the number of items a suggestion is topped up
+	// to with co-starred repositories
+	suggestionItemsLimit = 5
+	// suggestionFollowsLimit is the number of users suggested along with the
+	// repositories
+	suggestionFollowsLimit = 3
+)
+
+// ErrShutdownTimeout is returned when the task being handled did not finish
+// in time
+var ErrShutdownTimeout = errors.New("timed out waiting for the task being handled")
+
 // Extraction is our main orchestrating service
 type Extraction struct {
 	extractionInterval time.Duration
+	shutdownTimeout    time.Duration
+	// candidates are the repositories starred by followees within
+	// suggestionWindow
+	suggestionWindow time.Duration
+	// repositories suggested within suggestionHistory are not suggested again
+	suggestionHistory time.Duration
+
+	// ranker is used for users that have not picked one
+	ranker ranker.Ranker
+	// rankerHalfLife is the half-life of stars for rankers picked by users
+	rankerHalfLife time.Duration
 
 	graphStore      store.GraphStore
 	suggestionStore store.SuggestionStore // Rename because it includes all SQL store
@@ -28,6 +54,11 @@ type Extraction struct {
 // New constructs a Github extraction
 func New(
 	extractionInterval time.Duration,
+	shutdownTimeout time.Duration,
+	suggestionWindow time.Duration,
+	suggestionHistory time.Duration,
+	ranker ranker.Ranker,
+	rankerHalfLife time.Duration,
 	graphStore store.GraphStore,
 	suggestionStore store.SuggestionStore,
 	suggestionExtractionQueue queue.Queue,
@@ -38,6 +69,11 @@ func New(
 		graphStore:                graphStore,
 		suggestionStore:           suggestionStore,
 		extractionInterval:        extractionInterval,
+		shutdownTimeout:           shutdownTimeout,
+		suggestionWindow:          suggestionWindow,
+		suggestionHistory:         suggestionHistory,
+		ranker:                    ranker,
+		rankerHalfLife:            rankerHalfLife,
 		suggestionExtractionQueue: suggestionExtractionQueue,
 		mailer:                    mailer,
 	}
@@ -59,17 +95,66 @@ func (e *Extraction) handleSuggestionExtractionTask(task *model.SuggestionExtrac
 		return errors.Wrap(err, "could not retrieve user")
 	}
 
-	suggestion, err := e.graphStore.GetUserSuggestion(user)
+	excluded, err := e.excludedRepositories(user)
+	if err != nil {
+		return errors.Wrap(err, "could not get excluded repositories")
+	}
+
+	candidates, err := e.graphStore.GetUserCandidates(
+		user,
+		time.Now().Add(-e.suggestionWindow),
+	)
 	if err != nil {
 		return errors.Wrap(err, "could not extract suggestions")
 	}
 
+	suggestion := &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+	}
+
+	suggestion.Items = appendSuggestionItems(
+		nil,
+		filterSuggestionItems(
+			logger,
+			e.userRanker(logger, user).Rank(candidates),
+			excluded,
+		),
+		suggestionItemsLimit,
+	)
+
+	// users with few followees get repositories co-starred with their own
+	// stars instead
+	if len(suggestion.Items) < suggestionItemsLimit {
+		coStarred, err := e.graphStore.GetUserCoStarredSuggestion(user)
+		if err != nil {
+			return errors.Wrap(err, "could not extract co-starred suggestions")
+		}
+
+		suggestion.Items = appendSuggestionItems(
+			suggestion.Items,
+			filterSuggestionItems(logger, coStarred.Items, excluded),
+			suggestionItemsLimit,
+		)
+	}
+
+	follows, err := e.graphStore.GetUserFollowSuggestion(user)
+	if err != nil {
+		return errors.Wrap(err, "could not extract follow suggestions")
+	}
+
+	suggestion.Items = appendSuggestionItems(
+		suggestion.Items,
+		filterSuggestionItems(logger, follows.Items, excluded),
+		len(suggestion.Items)+suggestionFollowsLimit,
+	)
+
 	if err := e.suggestionStore.PutSuggestion(suggestion); err != nil {
 		return errors.Wrap(err, "could not put suggestion")
 	}
 
 	html, err := suggestion.ToHTML()
-	if err := e.suggestionStore.PutSuggestion(suggestion); err != nil {
+	if err != nil {
 		return errors.Wrap(err, "could not generate html")
 	}
 
@@ -80,6 +165,113 @@ func (e *Extraction) handleSuggestionExtractionTask(task *model.SuggestionExtrac
 	return nil
 }
 
+// userRanker returns the ranker the user picked, or the default one
+func (e *Extraction) userRanker(logger *logrus.Entry, user *model.User) ranker.Ranker {
+	if user.Ranker == "" {
+		return e.ranker
+	}
+
+	r, err := ranker.New(user.Ranker, e.rankerHalfLife)
+	if err != nil {
+		logger.
+			WithError(err).
+			WithField("user.ranker", user.Ranker).
+			Warn("could not construct user ranker, using the default one")
+		return e.ranker
+	}
+
+	return r
+}
+
+// excludedRepositories returns the repositories that should not be suggested
+// to a user and the reason why
+func (e *Extraction) excludedRepositories(user *model.User) (map[string]string, error) {
+	excluded := map[string]string{}
+
+	suggestions, err := e.suggestionStore.GetSuggestionsForUserSince(
+		user.Name,
+		time.Now().Add(-e.suggestionHistory),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get past suggestions")
+	}
+
+	for _, suggestion := range suggestions {
+		for _, item := range suggestion.Items {
+			excluded[item.Value] = "recently suggested"
+		}
+	}
+
+	repositories, err := e.graphStore.GetUserRepositories(user.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get user repositories")
+	}
+
+	for _, owned := range repositories.Owns {
+		excluded[owned.Repository] = "owned"
+	}
+
+	for _, star := range repositories.Stars {
+		excluded[star.Repository] = "starred"
+	}
+
+	return excluded, nil
+}
+
+// filterSuggestionItems removes the excluded items and archived repositories,
+// logging why each one was removed
+func filterSuggestionItems(
+	logger *logrus.Entry,
+	items []model.SuggestionItem,
+	excluded map[string]string,
+) []model.SuggestionItem {
+	filtered := []model.SuggestionItem{}
+	for _, item := range items {
+		reason, ok := excluded[item.Value]
+		if !ok && item.Repository != nil && item.Repository.Archived {
+			reason, ok = "archived", true
+		}
+		if ok {
+			logger.
+				WithFields(logrus.Fields{
+					"item.value":    item.Value,
+					"filter.reason": reason,
+				}).
+				Debug("filtered out suggestion item")
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	return filtered
+}
+
+// appendSuggestionItems appends the items that are not suggested already, up
+// to limit items
+func appendSuggestionItems(
+	items []model.SuggestionItem,
+	more []model.SuggestionItem,
+	limit int,
+) []model.SuggestionItem {
+	suggested := map[string]bool{}
+	for _, item := range items {
+		suggested[item.Type+"/"+item.Value] = true
+	}
+
+	for _, item := range more {
+		if len(items) >= limit {
+			break
+		}
+		if suggested[item.Type+"/"+item.Value] {
+			continue
+		}
+		suggested[item.Type+"/"+item.Value] = true
+		items = append(items, item)
+	}
+
+	return items
+}
+
 // extractSuggestions feed the suggestionExtractionQueue
 func (e *Extraction) extractSuggestions() error {
 	logger := logrus.WithFields(logrus.Fields{
@@ -109,49 +301,115 @@ func (e *Extraction) extractSuggestions() error {
 	return nil
 }
 
-// Start extracing
+// Start extracing until the context is done, the task being handled at that
+// point is allowed to finish within the shutdown timeout
 func (e *Extraction) Start(ctx context.Context) error {
-	cctx, _ := context.WithCancel(ctx)
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	logger := logrus.WithFields(logrus.Fields{
 		"logger": "extraction/Github.Start",
 	})
 
-	suggestionExtractionTasks := make(chan *model.SuggestionExtractionTask, 10000)
+	wg := &sync.WaitGroup{}
 
-	// pop tasks from suggestionExtractionTasks and push them to a local channel
+	// unbuffered so that only the lease being handled is popped
+	suggestionExtractionLeases := make(chan *queue.Lease)
+
+	// pop tasks from suggestionExtractionQueue and push them to a local channel
+	wg.Add(1)
 	go func() {
-		logger.Info("starting to pop tasks from suggestionExtractionTasks")
+		defer wg.Done()
+		logger.Info("starting to pop tasks from suggestionExtractionQueue")
 		for {
-			task, err := e.suggestionExtractionQueue.Pop()
+			lease, err := e.suggestionExtractionQueue.PopContext(cctx)
+			if cctx.Err() != nil {
+				// the task might have been popped just before stopping
+				if lease != nil {
+					if err := e.suggestionExtractionQueue.Nack(lease); err != nil {
+						logger.WithError(err).Warn("could not nack model.SuggestionExtractionTask")
+					}
+				}
+				return
+			}
 			if err != nil {
 				logger.WithError(err).Fatal("could not pop from suggestionExtractionQueue")
 			}
-			if task == nil {
-				time.Sleep(time.Second)
-				continue
-			}
-			if okTask, ok := task.(*model.SuggestionExtractionTask); ok {
-				suggestionExtractionTasks <- okTask
+			select {
+			case suggestionExtractionLeases <- lease:
+			case <-cctx.Done():
+				// return the task to the queue as it will not be handled
+				if err := e.suggestionExtractionQueue.Nack(lease); err != nil {
+					logger.WithError(err).Warn("could not nack model.SuggestionExtractionTask")
+				}
+				return
 			}
 		}
 	}()
 
-	extractSuggestionsTicker := time.NewTicker(e.extractionInterval)
+	// extract suggestions and handle tasks one at a time
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
 
-	for {
-		select {
-		case <-cctx.Done():
-			return nil
+		extractSuggestionsTicker := time.NewTicker(e.extractionInterval)
+		defer extractSuggestionsTicker.Stop()
 
-		case <-extractSuggestionsTicker.C:
-			if err := e.extractSuggestions(); err != nil {
-				logger.WithError(err).Warn("extractSuggestions failed")
-			}
+		for {
+			select {
+			case <-cctx.Done():
+				return
+
+			case <-extractSuggestionsTicker.C:
+				if err := e.extractSuggestions(); err != nil {
+					logger.WithError(err).Warn("extractSuggestions failed")
+				}
 
-		case task := <-suggestionExtractionTasks:
-			if err := e.handleSuggestionExtractionTask(task); err != nil {
-				logger.WithError(err).Warn("failed to handle model.SuggestionExtractionTask")
+			case lease := <-suggestionExtractionLeases:
+				e.handleLease(logger, lease)
 			}
 		}
+	}()
+
+	<-cctx.Done()
+	logger.Info("stopping, waiting for the task being handled to finish")
+
+	return drain(wg, e.shutdownTimeout)
+}
+
+// handleLease handles a leased task and acknowledges it, tasks that fail are
+// returned to the queue to be handled again
+func (e *Extraction) handleLease(logger *logrus.Entry, lease *queue.Lease) {
+	task, ok := lease.Task.(*model.SuggestionExtractionTask)
+	if !ok {
+		// tasks of other types can never be handled
+		logger.Warn("invalid model.SuggestionExtractionTask, dropping")
+	} else if err := e.handleSuggestionExtractionTask(task); err != nil {
+		logger.WithError(err).Warn("failed to handle model.SuggestionExtractionTask")
+		if err := e.suggestionExtractionQueue.Nack(lease); err != nil {
+			logger.WithError(err).Warn("could not nack model.SuggestionExtractionTask")
+		}
+		return
+	}
+
+	if err := e.suggestionExtractionQueue.Ack(lease); err != nil {
+		logger.WithError(err).Warn("could not ack model.SuggestionExtractionTask")
+	}
+}
+
+// drain waits for the wait group, abandoning it after the timeout, the
+// abandoned task is not acknowledged and returns to the queue once its lease
+// expires
+func drain(wg *sync.WaitGroup, timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return ErrShutdownTimeout
 	}
 }
@@ -0,0 +1,396 @@
+package extraction
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/mailer/mailerfakes"
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/ranker"
+	"github.com/kbariotis/go-discover/internal/ranker/rankerfakes"
+	"github.com/kbariotis/go-discover/internal/store/storefakes"
+)
+
+func TestExtraction_Start(t *testing.T) {
+	// construct fakes
+	graphStore := &storefakes.FakeGraphStore{}
+	suggestionStore := &storefakes.FakeSuggestionStore{}
+	mailer := &mailerfakes.FakeMailer{}
+
+	user := &model.User{
+		Name:  "foo",
+		Email: "foo@bar.io",
+	}
+	candidates := []*model.SuggestionCandidate{
+		{
+			Repository: &model.Repository{
+				Name: "github.com/kbariotis/go-discover",
+			},
+			FolloweeStars: 2,
+		},
+	}
+	coStarred := &model.Suggestion{
+		UserID: "foo",
+		Items: []model.SuggestionItem{
+			{
+				Type:   model.SuggestionTypeStarRepository,
+				Value:  "github.com/kbariotis/go-discover",
+				Reason: "co-starred",
+			},
+			{
+				Type:   model.SuggestionTypeStarRepository,
+				Value:  "github.com/foo/starred",
+				Reason: "co-starred",
+			},
+			{
+				Type:   model.SuggestionTypeStarRepository,
+				Value:  "github.com/foo/bar",
+				Reason: "co-starred",
+			},
+		},
+	}
+	suggestionStore.GetUserReturns(user, nil)
+	graphStore.GetUserRepositoriesReturns(&model.User{
+		Name: "foo",
+		Stars: []model.StarredRepository{
+			{Repository: "github.com/foo/starred"},
+		},
+	}, nil)
+	graphStore.GetUserCandidatesReturns(candidates, nil)
+	graphStore.GetUserCoStarredSuggestionReturns(coStarred, nil)
+	graphStore.GetUserFollowSuggestionReturns(&model.Suggestion{
+		UserID: "foo",
+		Items: []model.SuggestionItem{
+			{
+				Type:   model.SuggestionTypeFollowUser,
+				Value:  "octocat",
+				Reason: "followed by 4 people you follow",
+				User: &model.UserProfile{
+					Name:        "octocat",
+					DisplayName: "The <Octocat>",
+				},
+			},
+			{
+				Type:   model.SuggestionTypeFollowUser,
+				Value:  "recent",
+				Reason: "followed by 2 people you follow",
+			},
+		},
+	}, nil)
+	suggestionStore.GetSuggestionsForUserSinceReturns([]*model.Suggestion{
+		{
+			Items: []model.SuggestionItem{
+				{Type: model.SuggestionTypeFollowUser, Value: "recent"},
+			},
+		},
+	}, nil)
+
+	// construct queue
+	suggestionExtractionQueue, err := queue.NewMemory(time.Minute)
+	require.NoError(t, err)
+
+	// construct extraction
+	rnk := &rankerfakes.FakeRanker{}
+	rnk.RankStub = func(candidates []*model.SuggestionCandidate) []model.SuggestionItem {
+		return []model.SuggestionItem{
+			{
+				Type:   model.SuggestionTypeStarRepository,
+				Value:  candidates[0].Repository.Name,
+				Reason: "because it's epic",
+			},
+		}
+	}
+	extr, err := New(
+		time.Hour,
+		time.Second*10,
+		time.Hour*24*7,
+		time.Hour*24*28,
+		rnk,
+		time.Hour*24*7,
+		graphStore,
+		suggestionStore,
+		suggestionExtractionQueue,
+		mailer,
+	)
+	require.NoError(t, err)
+
+	// queue extraction
+	require.NoError(t, suggestionExtractionQueue.Push(
+		&model.SuggestionExtractionTask{
+			UserName: "foo",
+		},
+	))
+
+	// start extraction
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- extr.Start(ctx)
+	}()
+
+	// wait until the task has been handled
+	deadline := time.Now().Add(time.Second * 10)
+	for suggestionExtractionQueue.Len() > 0 ||
+		suggestionExtractionQueue.Leased() > 0 {
+		require.True(t, time.Now().Before(deadline), "task not handled in time")
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// check suggestion
+	require.Equal(t, 1, suggestionStore.GetUserCallCount())
+	require.Equal(t, "foo", suggestionStore.GetUserArgsForCall(0))
+	require.Equal(t, 1, graphStore.GetUserCandidatesCallCount())
+	gotUser, gotWindow := graphStore.GetUserCandidatesArgsForCall(0)
+	require.Equal(t, user, gotUser)
+	require.WithinDuration(t, time.Now().Add(-time.Hour*24*7), gotWindow, time.Minute)
+	require.Equal(t, 1, rnk.RankCallCount())
+	require.Equal(t, candidates, rnk.RankArgsForCall(0))
+	require.Equal(t, 1, suggestionStore.PutSuggestionCallCount())
+	require.Equal(t, "foo", suggestionStore.PutSuggestionArgsForCall(0).UserID)
+
+	// topped up with the co-starred repositories that were not suggested or
+	// starred
+	require.Equal(t, "foo", graphStore.GetUserRepositoriesArgsForCall(0))
+	require.Equal(t, 1, suggestionStore.GetSuggestionsForUserSinceCallCount())
+	gotName, gotSince := suggestionStore.GetSuggestionsForUserSinceArgsForCall(0)
+	require.Equal(t, "foo", gotName)
+	require.WithinDuration(t, time.Now().Add(-time.Hour*24*28), gotSince, time.Minute)
+	require.Equal(t, 1, graphStore.GetUserCoStarredSuggestionCallCount())
+	require.Equal(t, []model.SuggestionItem{
+		{
+			Type:   model.SuggestionTypeStarRepository,
+			Value:  "github.com/kbariotis/go-discover",
+			Reason: "because it's epic",
+		},
+		coStarred.Items[2],
+		// followed by users that were not suggested recently
+		{
+			Type:   model.SuggestionTypeFollowUser,
+			Value:  "octocat",
+			Reason: "followed by 4 people you follow",
+			User: &model.UserProfile{
+				Name:        "octocat",
+				DisplayName: "The <Octocat>",
+			},
+		},
+	}, suggestionStore.PutSuggestionArgsForCall(0).Items)
+	require.Equal(t, 1, graphStore.GetUserFollowSuggestionCallCount())
+
+	// check email
+	require.Equal(t, 1, mailer.MailCallCount())
+	gotEmail, gotHTML := mailer.MailArgsForCall(0)
+	require.Equal(t, "foo@bar.io", gotEmail)
+	require.Contains(t, gotHTML, "Repository: github.com/kbariotis/go-discover")
+	require.Contains(t, gotHTML, "User: octocat because followed by 4 people you follow")
+	require.Contains(t, gotHTML, "The &lt;Octocat&gt;")
+}
+
+func TestExtraction_StartShutdownTimeout(t *testing.T) {
+	graphStore := &storefakes.FakeGraphStore{}
+	suggestionStore := &storefakes.FakeSuggestionStore{}
+	suggestionStore.GetUserReturns(&model.User{Name: "foo"}, nil)
+
+	// block extraction for good
+	started := make(chan struct{})
+	graphStore.GetUserRepositoriesStub = func(name string) (*model.User, error) {
+		close(started)
+		select {}
+	}
+
+	suggestionExtractionQueue, err := queue.NewMemory(time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, suggestionExtractionQueue.Push(
+		&model.SuggestionExtractionTask{
+			UserName: "foo",
+		},
+	))
+
+	extr, err := New(
+		time.Hour,
+		time.Millisecond*50,
+		time.Hour,
+		time.Hour,
+		&rankerfakes.FakeRanker{},
+		time.Hour,
+		graphStore,
+		suggestionStore,
+		suggestionExtractionQueue,
+		&mailerfakes.FakeMailer{},
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- extr.Start(ctx)
+	}()
+
+	<-started
+	cancel()
+	require.Equal(t, ErrShutdownTimeout, <-done)
+
+	// the abandoned task is left leased, to be retried once its lease expires
+	require.Equal(t, 0, suggestionExtractionQueue.Len())
+	require.Equal(t, 1, suggestionExtractionQueue.Leased())
+}
+
+func TestExtraction_handleLease(t *testing.T) {
+	suggestionStore := &storefakes.FakeSuggestionStore{}
+	suggestionStore.GetUserReturns(nil, errors.New("get user failed"))
+
+	q, err := queue.NewMemory(time.Minute)
+	require.NoError(t, err)
+
+	extr := &Extraction{
+		suggestionStore:           suggestionStore,
+		suggestionExtractionQueue: q,
+	}
+	logger := logrus.WithFields(logrus.Fields{})
+
+	popLease := func() *queue.Lease {
+		lease, err := q.Pop()
+		require.NoError(t, err)
+		require.NotNil(t, lease)
+		return lease
+	}
+
+	// failed tasks are returned to the queue
+	require.NoError(t, q.Push(&model.SuggestionExtractionTask{UserName: "foo"}))
+	extr.handleLease(logger, popLease())
+	require.Equal(t, 1, suggestionStore.GetUserCallCount())
+	require.Equal(t, 1, q.Len())
+	require.Equal(t, 0, q.Leased())
+
+	require.NoError(t, q.Ack(popLease()))
+
+	// tasks of other types are dropped
+	require.NoError(t, q.Push("foo"))
+	extr.handleLease(logger, popLease())
+	require.Equal(t, 1, suggestionStore.GetUserCallCount())
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 0, q.Leased())
+}
+
+func TestAppendSuggestionItems(t *testing.T) {
+	items := func(values ...string) []model.SuggestionItem {
+		res := []model.SuggestionItem{}
+		for _, value := range values {
+			res = append(res, model.SuggestionItem{
+				Type:  model.SuggestionTypeStarRepository,
+				Value: value,
+			})
+		}
+		return res
+	}
+
+	require.Equal(
+		t,
+		items("a", "b", "c"),
+		appendSuggestionItems(items("a", "b"), items("b", "c", "c"), 5),
+	)
+	require.Equal(
+		t,
+		items("a", "b", "c"),
+		appendSuggestionItems(items("a"), items("b", "c", "d"), 3),
+	)
+	require.Equal(
+		t,
+		items("a"),
+		appendSuggestionItems(items("a"), nil, 3),
+	)
+}
+
+func TestExtraction_excludedRepositories(t *testing.T) {
+	graphStore := &storefakes.FakeGraphStore{}
+	suggestionStore := &storefakes.FakeSuggestionStore{}
+
+	suggestionStore.GetSuggestionsForUserSinceReturns([]*model.Suggestion{
+		{
+			Items: []model.SuggestionItem{
+				{Value: "suggested"},
+				{Value: "starred"},
+			},
+		},
+	}, nil)
+	graphStore.GetUserRepositoriesReturns(&model.User{
+		Name: "foo",
+		Stars: []model.StarredRepository{
+			{Repository: "starred"},
+		},
+		Owns: []model.OwnedRepository{
+			{Repository: "owned"},
+		},
+	}, nil)
+
+	extr, err := New(time.Hour, time.Hour, time.Hour, time.Hour, nil, time.Hour, graphStore, suggestionStore, nil, nil)
+	require.NoError(t, err)
+
+	excluded, err := extr.excludedRepositories(&model.User{Name: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"suggested": "recently suggested",
+		"starred":   "starred",
+		"owned":     "owned",
+	}, excluded)
+}
+
+func TestExtraction_userRanker(t *testing.T) {
+	logger := logrus.WithField("logger", "test")
+	defaultRanker := &rankerfakes.FakeRanker{}
+
+	extr, err := New(time.Hour, time.Hour, time.Hour, time.Hour, defaultRanker, time.Hour, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, defaultRanker, extr.userRanker(logger, &model.User{}))
+	require.IsType(t, &ranker.Popular{}, extr.userRanker(logger, &model.User{
+		Ranker: "popular",
+	}))
+	require.IsType(t, &ranker.Decayed{}, extr.userRanker(logger, &model.User{
+		Ranker: "decayed",
+	}))
+	require.Equal(t, defaultRanker, extr.userRanker(logger, &model.User{
+		Ranker: "unknown",
+	}))
+}
+
+func TestFilterSuggestionItems(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	items := []model.SuggestionItem{
+		{Value: "a"},
+		{Value: "starred"},
+		{Value: "b"},
+		{Value: "owned"},
+		{Value: "archived", Repository: &model.Repository{Archived: true}},
+	}
+	excluded := map[string]string{
+		"starred": "starred",
+		"owned":   "owned",
+	}
+
+	require.Equal(
+		t,
+		[]model.SuggestionItem{{Value: "a"}, {Value: "b"}},
+		filterSuggestionItems(logrus.NewEntry(logger), items, excluded),
+	)
+
+	// the reason of every filtered item is logged
+	require.Len(t, hook.AllEntries(), 3)
+	require.Equal(t, "starred", hook.AllEntries()[0].Data["item.value"])
+	require.Equal(t, "starred", hook.AllEntries()[0].Data["filter.reason"])
+	require.Equal(t, "owned", hook.AllEntries()[1].Data["item.value"])
+	require.Equal(t, "owned", hook.AllEntries()[1].Data["filter.reason"])
+	require.Equal(t, "archived", hook.AllEntries()[2].Data["item.value"])
+	require.Equal(t, "archived", hook.AllEntries()[2].Data["filter.reason"])
+}
@@ -1,5 +1,7 @@
 package mailer
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Mailer
+
 // Mailer interface
 type Mailer interface {
 	Mail(email string, html string) error
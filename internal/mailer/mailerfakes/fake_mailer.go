@@ -0,0 +1,113 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package mailerfakes
+
+import (
+	"sync"
+
+	"github.com/kbariotis/go-discover/internal/mailer"
+)
+
+type FakeMailer struct {
+	MailStub        func(string, string) error
+	mailMutex       sync.RWMutex
+	mailArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	mailReturns struct {
+		result1 error
+	}
+	mailReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeMailer) Mail(arg1 string, arg2 string) error {
+	fake.mailMutex.Lock()
+	ret, specificReturn := fake.mailReturnsOnCall[len(fake.mailArgsForCall)]
+	fake.mailArgsForCall = append(fake.mailArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.MailStub
+	fakeReturns := fake.mailReturns
+	fake.recordInvocation("Mail", []interface{}{arg1, arg2})
+	fake.mailMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeMailer) MailCallCount() int {
+	fake.mailMutex.RLock()
+	defer fake.mailMutex.RUnlock()
+	return len(fake.mailArgsForCall)
+}
+
+func (fake *FakeMailer) MailCalls(stub func(string, string) error) {
+	fake.mailMutex.Lock()
+	defer fake.mailMutex.Unlock()
+	fake.MailStub = stub
+}
+
+func (fake *FakeMailer) MailArgsForCall(i int) (string, string) {
+	fake.mailMutex.RLock()
+	defer fake.mailMutex.RUnlock()
+	argsForCall := fake.mailArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeMailer) MailReturns(result1 error) {
+	fake.mailMutex.Lock()
+	defer fake.mailMutex.Unlock()
+	fake.MailStub = nil
+	fake.mailReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeMailer) MailReturnsOnCall(i int, result1 error) {
+	fake.mailMutex.Lock()
+	defer fake.mailMutex.Unlock()
+	fake.MailStub = nil
+	if fake.mailReturnsOnCall == nil {
+		fake.mailReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.mailReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeMailer) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.mailMutex.RLock()
+	defer fake.mailMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeMailer) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ mailer.Mailer = new(FakeMailer)
@@ -6,10 +6,31 @@ type UserStar struct {
 	StarredAt int64  `json:"starredAt,omitempty"`
 }
 
+// RepositoryLanguage is a language a repository is written in, Share is the
+// fraction of the repository's bytes written in it
+type RepositoryLanguage struct {
+	Name  string  `json:"name,omitempty"`
+	Bytes int64   `json:"bytes,omitempty"`
+	Share float64 `json:"share,omitempty"`
+}
+
 // Repository representation
+// Stars and Languages are complete sets, graph stores remove the ones that are
+// missing; they are left as they are when nil
+// License is the SPDX id of the repository's license, CreatedAt and PushedAt
+// are unix timestamps
 type Repository struct {
-	Name      string     `json:"name,omitempty"`
-	Labels    []string   `json:"labels,omitempty"`
-	Stars     []UserStar `json:"stars,omitempty"`
-	Languages []string   `json:"languages,omitempty"`
+	Name            string               `json:"name,omitempty"`
+	Description     string               `json:"description,omitempty"`
+	Homepage        string               `json:"homepage,omitempty"`
+	StargazersCount int                  `json:"stargazersCount,omitempty"`
+	ForksCount      int                  `json:"forksCount,omitempty"`
+	License         string               `json:"license,omitempty"`
+	Archived        bool                 `json:"archived,omitempty"`
+	Fork            bool                 `json:"fork,omitempty"`
+	CreatedAt       int64                `json:"createdAt,omitempty"`
+	PushedAt        int64                `json:"pushedAt,omitempty"`
+	Labels          []string             `json:"labels,omitempty"`
+	Stars           []UserStar           `json:"stars,omitempty"`
+	Languages       []RepositoryLanguage `json:"languages,omitempty"`
 }
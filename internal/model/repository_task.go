@@ -3,4 +3,5 @@ package model
 // RepositoryTask represents a task in the repository queue
 type RepositoryTask struct {
 	Name string
+	TaskRetry
 }
@@ -19,7 +19,20 @@ const (
 				<ul>
 				{{range .Suggestion.Items}}
 					<li>
+					{{if eq .Type "FOLLOW_USER"}}
+						User: {{.Value}} because {{.Reason}}
+						{{with .User}}
+							{{if .DisplayName}}<br/>{{html .DisplayName}}{{end}}
+							{{if .Bio}}<br/>{{html .Bio}}{{end}}
+							<br/>{{.Followers}} followers
+						{{end}}
+					{{else}}
 						Repository: {{.Value}} because {{.Reason}}
+						{{with .Repository}}
+							{{if .Description}}<br/>{{html .Description}}{{end}}
+							<br/>{{.StargazersCount}} stars, {{.ForksCount}} forks{{if .License}}, {{html .License}}{{end}}
+						{{end}}
+					{{end}}
 					</li>
 				{{end}}
 				</ul>
@@ -40,13 +53,45 @@ const (
 	SuggestionTypeFollowUser = "FOLLOW_USER"
 )
 
-// SuggestionItem is a single repository suggestion
+// SuggestionItem is a single repository or user suggestion
+// Repository and User hold the details of suggested repositories and users as
+// known by the graph store, they are not persisted
 type SuggestionItem struct {
 	ID           uint `gorm:"primary_key"`
 	SuggestionID uint
 	Type         string
 	Value        string
 	Reason       string
+	Repository   *Repository  `json:"repository,omitempty" gorm:"-"`
+	User         *UserProfile `json:"user,omitempty" gorm:"-"`
+}
+
+// CandidateStar is a star of a candidate by one of the user's followees
+type CandidateStar struct {
+	Followee  string
+	StarredAt int64
+	// Affinity is the number of repositories both the user and the followee
+	// starred
+	Affinity int64
+}
+
+// SuggestionCandidate is a repository that could be suggested to a user, with
+// the features rankers score it by
+type SuggestionCandidate struct {
+	// Repository holds the details of the repository, StargazersCount being
+	// its global stars
+	Repository *Repository
+	// FolloweeStars is the number of stars by the user's followees
+	FolloweeStars int64
+	// Stars are the stars by the user's followees
+	Stars []CandidateStar
+	// LastStarredAt is the time of the latest star by a followee
+	LastStarredAt int64
+	// MatchingTopics is the number of its topics found in the user's stars
+	MatchingTopics int64
+	// MatchingLanguages is the number of its languages found in the user's
+	// stars
+	MatchingLanguages int64
 }
 
 // Suggestion contains a list of suggestions
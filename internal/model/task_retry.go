@@ -0,0 +1,17 @@
+package model
+
+// TaskRetry keeps track of the failed attempts of a task
+type TaskRetry struct {
+	Attempts int
+	RetryAt  int64
+}
+
+// GetRetry returns the task's retry information
+func (r *TaskRetry) GetRetry() *TaskRetry {
+	return r
+}
+
+// RetryableTask is implemented by tasks that embed TaskRetry
+type RetryableTask interface {
+	GetRetry() *TaskRetry
+}
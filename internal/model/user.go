@@ -6,10 +6,35 @@ type StarredRepository struct {
 	StarredAt  int64  `json:"starredAt,omitempty"`
 }
 
+// OwnedRepository representation of user's own repositories
+type OwnedRepository struct {
+	Repository string `json:"repository,omitempty"`
+	Fork       bool   `json:"fork,omitempty"`
+	CreatedAt  int64  `json:"createdAt,omitempty"`
+}
+
 // User representation
+// Followees and Stars are complete sets, graph stores remove the ones that are
+// missing; they are left as they are when nil
+// Ranker overrides the ranker of the deployment when set
 type User struct {
 	Name      string              `json:"name,omitempty" gorm:"primary_key"`
 	Email     string              `json:"-" gorm:"column:email"`
+	Token     string              `json:"-" gorm:"column:github_token"`
+	Ranker    string              `json:"-" gorm:"column:ranker"`
 	Followees []string            `json:"followees,omitempty" gorm:"-"`
 	Stars     []StarredRepository `json:"stars,omitempty" gorm:"-"`
+	Owns      []OwnedRepository   `json:"owns,omitempty" gorm:"-"`
+}
+
+// UserProfile representation of a user's public profile
+type UserProfile struct {
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	Company     string `json:"company,omitempty"`
+	Location    string `json:"location,omitempty"`
+	AvatarURL   string `json:"avatarURL,omitempty"`
+	Followers   int    `json:"followers,omitempty"`
+	CreatedAt   int64  `json:"createdAt,omitempty"`
 }
@@ -3,4 +3,5 @@ package model
 // UserFolloweeTask represents a task in the userFollowee queue
 type UserFolloweeTask struct {
 	Name string
+	TaskRetry
 }
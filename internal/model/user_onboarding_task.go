@@ -3,4 +3,5 @@ package model
 // UserOnboardingTask represents a task in the userOnboarding queue
 type UserOnboardingTask struct {
 	Name string
+	TaskRetry
 }
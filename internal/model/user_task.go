@@ -3,4 +3,5 @@ package model
 // UserTask represents a task in the user queue
 type UserTask struct {
 	Name string
+	TaskRetry
 }
@@ -2,17 +2,35 @@ package provider
 
 import (
 	"context"
+	"time"
 
 	"github.com/kbariotis/go-discover/internal/model"
 )
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Provider
+
 // Provider represents a backend for our crawler
 // Even though currenly only Github is supported this is separated to help out
 // with testing.
 type Provider interface {
+	GetUser(context.Context, string) (*model.UserProfile, error)
 	GetUserStars(context.Context, string) ([]model.StarredRepository, error)
 	GetUserFollowees(context.Context, string) ([]string, error)
-	GetUserRepositories(context.Context, string) ([]string, error)
+	GetUserRepositories(context.Context, string) ([]model.OwnedRepository, error)
 	GetRepository(context.Context, string) (*model.Repository, error)
 	FollowUser(context.Context, string) error
+	RateLimit() RateLimit
+	AddToken(string)
+}
+
+// RateLimit represents the request budget of a provider
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Exhausted returns true if there is no budget left until the next reset
+func (r RateLimit) Exhausted() bool {
+	return r.Limit > 0 && r.Remaining == 0 && time.Now().Before(r.Reset)
 }
@@ -3,28 +3,337 @@ package provider
 import (
 	"context"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v25/github"
 	"github.com/kbariotis/go-discover/internal/model"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
 )
 
+const (
+	// githubDefaultRetryAfter is used when a secondary rate limit response
+	// does not include a Retry-After or X-RateLimit-Reset header
+	githubDefaultRetryAfter = time.Minute
+)
+
+// ErrNoTokens is returned when all of the provider's tokens have been revoked
+var ErrNoTokens = errors.New("no usable tokens")
+
+// ErrNoBotToken is returned when constructing a provider without a bot token
+var ErrNoBotToken = errors.New("no bot token")
+
+// githubToken is a single token of the pool along with its rate limit
+type githubToken struct {
+	client      *github.Client
+	rate        github.Rate
+	pausedUntil time.Time
+	revoked     bool
+}
+
+// availableAt returns when the token can next be used
+func (t *githubToken) availableAt() time.Time {
+	until := t.pausedUntil
+	if t.rate.Limit > 0 && t.rate.Remaining == 0 && t.rate.Reset.After(until) {
+		until = t.rate.Reset.Time
+	}
+	return until
+}
+
 // Github provider
+// Reads are made with any token of the pool, write actions only with the
+// bot's own token and never with the ones users lend to the pool
 type Github struct {
-	client *github.Client
+	tokensMutex sync.RWMutex
+	tokens      map[string]*githubToken
+	botToken    string
+	// newClient constructs the client of each token
+	newClient func(token string) *github.Client
 }
 
-// NewGithub constrcuts a new Github provider
-func NewGithub(client *github.Client) (Provider, error) {
+// NewGithub constrcuts a new Github provider given the bot's token and a pool
+// of additional tokens
+func NewGithub(botToken string, tokens []string) (Provider, error) {
+	if botToken == "" {
+		return nil, ErrNoBotToken
+	}
+
 	prv := &Github{
-		client: client,
+		tokens:    map[string]*githubToken{},
+		botToken:  botToken,
+		newClient: newGithubClient,
+	}
+
+	prv.AddToken(botToken)
+	for _, token := range tokens {
+		prv.AddToken(token)
 	}
 
 	return prv, nil
 }
 
+// newGithubClient constructs a github client for the given token
+func newGithubClient(token string) *github.Client {
+	ghTokenSource := oauth2.StaticTokenSource(
+		&oauth2.Token{
+			AccessToken: token,
+		},
+	)
+
+	ghClient := github.NewClient(
+		oauth2.NewClient(context.Background(), ghTokenSource),
+	)
+
+	return ghClient
+}
+
+// AddToken adds a token to the pool, tokens that already exist in the pool,
+// including revoked ones, are ignored
+func (g *Github) AddToken(token string) {
+	if token == "" {
+		return
+	}
+
+	g.tokensMutex.Lock()
+	defer g.tokensMutex.Unlock()
+
+	if _, ok := g.tokens[token]; ok {
+		return
+	}
+
+	g.tokens[token] = &githubToken{
+		client: g.newClient(token),
+	}
+}
+
+// RateLimit returns the last known rate limit of the provider, summed across
+// all usable tokens
+func (g *Github) RateLimit() RateLimit {
+	g.tokensMutex.RLock()
+	defer g.tokensMutex.RUnlock()
+
+	rate := RateLimit{}
+	first := true
+	for _, token := range g.tokens {
+		if token.revoked {
+			continue
+		}
+
+		rate.Limit += token.rate.Limit
+		rate.Remaining += token.rate.Remaining
+
+		if at := token.availableAt(); first || at.Before(rate.Reset) {
+			rate.Reset = at
+			first = false
+		}
+	}
+
+	return rate
+}
+
+// acquire blocks until a token is available and returns the one with the
+// most remaining budget, or the bot's token if asBot is set
+func (g *Github) acquire(ctx context.Context, asBot bool) (*githubToken, error) {
+	for {
+		g.tokensMutex.RLock()
+		now := time.Now()
+		var best *githubToken
+		var next time.Time
+		for key, token := range g.tokens {
+			if token.revoked || (asBot && key != g.botToken) {
+				continue
+			}
+
+			at := token.availableAt()
+			if at.After(now) {
+				if next.IsZero() || at.Before(next) {
+					next = at
+				}
+				continue
+			}
+
+			// tokens we have not used yet have the whole budget available
+			if best == nil ||
+				token.rate.Limit == 0 ||
+				(best.rate.Limit > 0 && token.rate.Remaining > best.rate.Remaining) {
+				best = token
+			}
+		}
+		g.tokensMutex.RUnlock()
+
+		if best != nil {
+			return best, nil
+		}
+
+		if next.IsZero() {
+			return nil, ErrNoTokens
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"logger": "providers/Github.acquire",
+			"until":  next,
+		}).Warn("rate limited, pausing requests")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// track updates the token's rate limit given a response and its error,
+// returns true if the request failed because of the token and should be
+// retried
+func (g *Github) track(token *githubToken, res *github.Response, err error) bool {
+	g.tokensMutex.Lock()
+	defer g.tokensMutex.Unlock()
+
+	if res != nil && res.Rate.Limit > 0 {
+		token.rate = res.Rate
+	}
+
+	switch rerr := err.(type) {
+	case *github.RateLimitError:
+		token.rate = rerr.Rate
+		token.pausedUntil = time.Now().Add(time.Second)
+		return true
+	case *github.AbuseRateLimitError:
+		retryAfter := githubDefaultRetryAfter
+		if rerr.RetryAfter != nil {
+			retryAfter = *rerr.RetryAfter
+		}
+		token.pausedUntil = time.Now().Add(retryAfter)
+		return true
+	case *github.ErrorResponse:
+		if rerr.Response == nil {
+			return false
+		}
+
+		if rerr.Response.StatusCode == http.StatusUnauthorized {
+			logrus.WithFields(logrus.Fields{
+				"logger": "providers/Github.track",
+			}).Warn("token has been revoked, removing from pool")
+			token.revoked = true
+			return true
+		}
+
+		// secondary and newer primary rate limits are not recognised by
+		// go-github
+		if retryAt, ok := githubRetryAt(rerr.Response, time.Now()); ok {
+			token.pausedUntil = retryAt
+			return true
+		}
+	}
+
+	return false
+}
+
+// githubRetryAt returns when a rate limited request can be retried given its
+// response, false if the response is not a rate limit one
+func githubRetryAt(res *http.Response, now time.Time) (time.Time, bool) {
+	if res.StatusCode != http.StatusForbidden &&
+		res.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+
+	if retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		return now.Add(time.Duration(retryAfter) * time.Second), true
+	}
+
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			return time.Unix(reset, 0), true
+		}
+	}
+
+	// forbidden responses without rate limit headers are permission errors
+	if res.StatusCode == http.StatusTooManyRequests {
+		return now.Add(githubDefaultRetryAfter), true
+	}
+
+	return time.Time{}, false
+}
+
+// do runs a request with a token from the pool, pausing and retrying it for
+// as long as it's rate limited or its token is revoked
+func (g *Github) do(
+	ctx context.Context,
+	req func(client *github.Client) (*github.Response, error),
+) (*github.Response, error) {
+	return g.doWith(ctx, false, req)
+}
+
+// doAsBot runs a request like do, but only ever with the bot's token
+func (g *Github) doAsBot(
+	ctx context.Context,
+	req func(client *github.Client) (*github.Response, error),
+) (*github.Response, error) {
+	return g.doWith(ctx, true, req)
+}
+
+func (g *Github) doWith(
+	ctx context.Context,
+	asBot bool,
+	req func(client *github.Client) (*github.Response, error),
+) (*github.Response, error) {
+	for {
+		token, err := g.acquire(ctx, asBot)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := req(token.client)
+		if g.track(token, res, err) {
+			continue
+		}
+
+		return res, err
+	}
+}
+
+// GetUser returns the user's public profile
+func (g *Github) GetUser(ctx context.Context, name string) (*model.UserProfile, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":     "providers/Github.GetUser",
+		"user.login": name,
+	})
+
+	logger.Info("getting user's profile")
+
+	var user *github.User
+	res, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+		user, res, err = client.Users.Get(ctx, name)
+		return res, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve user")
+	}
+
+	logger.
+		WithField("res.code", res.StatusCode).
+		Debug("got user")
+
+	profile := &model.UserProfile{
+		Name:        user.GetLogin(),
+		DisplayName: user.GetName(),
+		Bio:         user.GetBio(),
+		Company:     user.GetCompany(),
+		Location:    user.GetLocation(),
+		AvatarURL:   user.GetAvatarURL(),
+		Followers:   user.GetFollowers(),
+		CreatedAt:   user.GetCreatedAt().Unix(),
+	}
+
+	return profile, nil
+}
+
 // GetUserStars returns the user's starred repositories
 func (g *Github) GetUserStars(ctx context.Context, name string) ([]model.StarredRepository, error) {
 	logger := logrus.WithFields(logrus.Fields{
@@ -45,7 +354,11 @@ func (g *Github) GetUserStars(ctx context.Context, name string) ([]model.Starred
 			},
 		}
 
-		moreRepos, res, err := g.client.Activity.ListStarred(ctx, name, opts)
+		var moreRepos []*github.StarredRepository
+		res, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+			moreRepos, res, err = client.Activity.ListStarred(ctx, name, opts)
+			return res, err
+		})
 		if err != nil {
 			return nil, errors.Wrap(err, "could not retrieve user's stars")
 		}
@@ -60,7 +373,10 @@ func (g *Github) GetUserStars(ctx context.Context, name string) ([]model.Starred
 			Debug("got stars")
 
 		for _, repo := range moreRepos {
-			stars = append(stars, model.StarredRepository{repo.Repository.GetFullName(), repo.StarredAt.Unix()})
+			stars = append(stars, model.StarredRepository{
+				Repository: repo.Repository.GetFullName(),
+				StarredAt:  repo.StarredAt.Unix(),
+			})
 		}
 
 		currentPage = res.NextPage
@@ -87,7 +403,11 @@ func (g *Github) GetUserFollowees(ctx context.Context, name string) ([]string, e
 			PerPage: 100,
 		}
 
-		moreFollowees, res, err := g.client.Users.ListFollowing(ctx, name, opts)
+		var moreFollowees []*github.User
+		res, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+			moreFollowees, res, err = client.Users.ListFollowing(ctx, name, opts)
+			return res, err
+		})
 		if err != nil {
 			return nil, errors.Wrap(err, "could not retrieve user's followees")
 		}
@@ -111,9 +431,57 @@ func (g *Github) GetUserFollowees(ctx context.Context, name string) ([]string, e
 	return followees, nil
 }
 
-// GetUserRepositories returns the user's repositories
-func (g *Github) GetUserRepositories(ctx context.Context, name string) ([]string, error) {
-	return nil, nil
+// GetUserRepositories returns the user's own repositories, including forks
+func (g *Github) GetUserRepositories(ctx context.Context, name string) ([]model.OwnedRepository, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":     "providers/Github.GetUserRepositories",
+		"user.login": name,
+	})
+
+	logger.Info("getting user's repositories")
+
+	repos := []model.OwnedRepository{}
+
+	currentPage := 1
+	for currentPage != 0 {
+		opts := &github.RepositoryListOptions{
+			Type: "owner",
+			ListOptions: github.ListOptions{
+				Page:    currentPage,
+				PerPage: 100,
+			},
+		}
+
+		var moreRepos []*github.Repository
+		res, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+			moreRepos, res, err = client.Repositories.List(ctx, name, opts)
+			return res, err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not retrieve user's repositories")
+		}
+
+		logger.
+			WithFields(logrus.Fields{
+				"current_page":  currentPage,
+				"count":         len(repos),
+				"res.code":      res.StatusCode,
+				"res.next_page": res.NextPage,
+			}).
+			Debug("got repositories")
+
+		for _, repo := range moreRepos {
+			repos = append(repos, model.OwnedRepository{
+				Repository: repo.GetFullName(),
+				Fork:       repo.GetFork(),
+				CreatedAt:  repo.GetCreatedAt().Unix(),
+			})
+		}
+
+		currentPage = res.NextPage
+	}
+
+	return repos, nil
 }
 
 // GetRepository returns a repository
@@ -129,7 +497,11 @@ func (g *Github) GetRepository(ctx context.Context, name string) (*model.Reposit
 		"repository.repoName":  repoName,
 	})
 
-	repo, _, err := g.client.Repositories.Get(ctx, repoOwner, repoName)
+	var repo *github.Repository
+	_, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+		repo, res, err = client.Repositories.Get(ctx, repoOwner, repoName)
+		return res, err
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve repository")
 	}
@@ -145,7 +517,11 @@ func (g *Github) GetRepository(ctx context.Context, name string) (*model.Reposit
 			PerPage: 100,
 		}
 
-		mporeStars, res, err := g.client.Activity.ListStargazers(ctx, repoOwner, repoName, opts)
+		var moreStars []*github.Stargazer
+		res, err := g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+			moreStars, res, err = client.Activity.ListStargazers(ctx, repoOwner, repoName, opts)
+			return res, err
+		})
 		if err != nil {
 			return nil, errors.Wrap(err, "could not retrieve repo's stars")
 		}
@@ -159,7 +535,7 @@ func (g *Github) GetRepository(ctx context.Context, name string) (*model.Reposit
 			}).
 			Debug("got repo's stars")
 
-		for _, user := range mporeStars {
+		for _, user := range moreStars {
 			stars = append(
 				stars,
 				model.UserStar{
@@ -172,25 +548,79 @@ func (g *Github) GetRepository(ctx context.Context, name string) (*model.Reposit
 		currentPage = res.NextPage
 	}
 
-	topics, _, err := g.client.Repositories.ListAllTopics(ctx, repoOwner, repoName)
+	var topics []string
+	_, err = g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+		topics, res, err = client.Repositories.ListAllTopics(ctx, repoOwner, repoName)
+		return res, err
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve topics")
 	}
 
 	logger.Debug("got repository topics")
 
+	var languages map[string]int
+	_, err = g.do(ctx, func(client *github.Client) (res *github.Response, err error) {
+		languages, res, err = client.Repositories.ListLanguages(ctx, repoOwner, repoName)
+		return res, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve languages")
+	}
+
+	logger.
+		WithField("languages.count", len(languages)).
+		Debug("got repository languages")
+
 	mRepo := &model.Repository{
-		Name:   name,
-		Labels: topics,
-		Stars:  stars,
-		Languages: []string{
-			repo.GetLanguage(),
-		},
+		Name:            name,
+		Description:     repo.GetDescription(),
+		Homepage:        repo.GetHomepage(),
+		StargazersCount: repo.GetStargazersCount(),
+		ForksCount:      repo.GetForksCount(),
+		License:         repo.GetLicense().GetSPDXID(),
+		Archived:        repo.GetArchived(),
+		Fork:            repo.GetFork(),
+		CreatedAt:       repo.GetCreatedAt().Unix(),
+		PushedAt:        repo.GetPushedAt().Unix(),
+		Labels:          topics,
+		Stars:           stars,
+		Languages:       repositoryLanguages(languages),
 	}
 	return mRepo, nil
 }
 
-// FollowUser follows a user give their login
+// repositoryLanguages returns the share of each language given the bytes
+// written in it, sorted by bytes
+func repositoryLanguages(languages map[string]int) []model.RepositoryLanguage {
+	total := 0
+	for _, bytes := range languages {
+		total += bytes
+	}
+
+	res := make([]model.RepositoryLanguage, 0, len(languages))
+	for name, bytes := range languages {
+		language := model.RepositoryLanguage{
+			Name:  name,
+			Bytes: int64(bytes),
+		}
+		if total > 0 {
+			language.Share = float64(bytes) / float64(total)
+		}
+		res = append(res, language)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Bytes != res[j].Bytes {
+			return res[i].Bytes > res[j].Bytes
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// FollowUser follows a user give their login, as the bot
 func (g *Github) FollowUser(ctx context.Context, name string) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"logger":     "providers/Github.FollowUser",
@@ -199,7 +629,9 @@ func (g *Github) FollowUser(ctx context.Context, name string) error {
 
 	logger.Info("following user")
 
-	res, err := g.client.Users.Follow(ctx, name)
+	res, err := g.doAsBot(ctx, func(client *github.Client) (*github.Response, error) {
+		return client.Users.Follow(ctx, name)
+	})
 	if err != nil {
 		return errors.Wrap(err, "could not follow user")
 	}
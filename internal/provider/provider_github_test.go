@@ -0,0 +1,446 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v25/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGithub is a github api that answers GET /users/foo with the responses
+// scripted for each token, tokens without a script succeed
+type fakeGithub struct {
+	server *httptest.Server
+
+	mutex     sync.Mutex
+	responses map[string][]func(w http.ResponseWriter)
+	requests  []string
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{
+		responses: map[string][]func(w http.ResponseWriter){},
+	}
+
+	f.server = httptest.NewServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		f.mutex.Lock()
+		f.requests = append(f.requests, token)
+		responses := f.responses[token]
+		if len(responses) > 0 {
+			f.responses[token] = responses[1:]
+		}
+		f.mutex.Unlock()
+
+		if len(responses) > 0 {
+			responses[0](w)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.Write([]byte(`{"login": "foo"}`))
+	}))
+
+	return f
+}
+
+// respond returns a scripted response
+func respond(
+	code int,
+	headers map[string]string,
+	body string,
+) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}
+}
+
+// getGithub constructs a provider with the given tokens talking to the fake
+func getGithub(t *testing.T, f *fakeGithub, tokens ...string) *Github {
+	baseURL, err := url.Parse(f.server.URL + "/")
+	require.NoError(t, err)
+
+	g := &Github{
+		tokens: map[string]*githubToken{},
+		newClient: func(token string) *github.Client {
+			client := newGithubClient(token)
+			client.BaseURL = baseURL
+			return client
+		},
+	}
+	for _, token := range tokens {
+		g.AddToken(token)
+	}
+
+	return g
+}
+
+func TestGithub_RateLimits(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	resetHeader := strconv.FormatInt(reset.Unix(), 10)
+
+	tests := []struct {
+		name        string
+		response    func(w http.ResponseWriter)
+		pausedUntil time.Time
+		revoked     bool
+		err         bool
+	}{
+		{
+			name: "primary limit",
+			response: respond(http.StatusForbidden, map[string]string{
+				"X-RateLimit-Limit":     "5000",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     resetHeader,
+			}, `{"message": "API rate limit exceeded for user ID 1."}`),
+			pausedUntil: reset,
+		},
+		{
+			name: "primary limit with too many requests",
+			response: respond(http.StatusTooManyRequests, map[string]string{
+				"X-RateLimit-Limit":     "5000",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     resetHeader,
+			}, `{"message": "API rate limit exceeded"}`),
+			pausedUntil: reset,
+		},
+		{
+			name: "secondary limit",
+			response: respond(http.StatusForbidden, map[string]string{
+				"Retry-After": "60",
+			}, `{
+				"message": "You have exceeded a secondary rate limit.",
+				"documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"
+			}`),
+			pausedUntil: time.Now().Add(time.Minute),
+		},
+		{
+			name: "secondary limit with too many requests",
+			response: respond(http.StatusTooManyRequests, map[string]string{
+				"Retry-After": "120",
+			}, `{"message": "You have exceeded a secondary rate limit."}`),
+			pausedUntil: time.Now().Add(time.Minute * 2),
+		},
+		{
+			name:        "secondary limit without headers",
+			response:    respond(http.StatusTooManyRequests, nil, `{"message": "slow down"}`),
+			pausedUntil: time.Now().Add(githubDefaultRetryAfter),
+		},
+		{
+			name: "abuse limit",
+			response: respond(http.StatusForbidden, map[string]string{
+				"Retry-After": "30",
+			}, `{
+				"message": "You have triggered an abuse detection mechanism.",
+				"documentation_url": "https://developer.github.com/v3/#abuse-rate-limits"
+			}`),
+			pausedUntil: time.Now().Add(time.Second * 30),
+		},
+		{
+			name:     "revoked",
+			response: respond(http.StatusUnauthorized, nil, `{"message": "Bad credentials"}`),
+			revoked:  true,
+		},
+		{
+			name:     "forbidden",
+			response: respond(http.StatusForbidden, nil, `{"message": "Forbidden"}`),
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeGithub(t)
+			defer f.server.Close()
+			f.responses["limited"] = []func(w http.ResponseWriter){test.response}
+
+			g := getGithub(t, f, "limited", "spare")
+			// tokens we have not used yet are preferred, so limited goes first
+			g.tokens["spare"].rate = github.Rate{Limit: 5000, Remaining: 1}
+
+			user, err := g.GetUser(context.Background(), "foo")
+			if test.err {
+				require.Error(t, err)
+				require.Equal(t, []string{"limited"}, f.requests)
+				return
+			}
+
+			// the request is retried with the other token
+			require.NoError(t, err)
+			require.Equal(t, "foo", user.Name)
+			require.Equal(t, []string{"limited", "spare"}, f.requests)
+
+			limited := g.tokens["limited"]
+			require.Equal(t, test.revoked, limited.revoked)
+			if !test.revoked {
+				require.WithinDuration(t, test.pausedUntil, limited.availableAt(), time.Second*2)
+			}
+
+			// and the limited token is not used again for now
+			_, err = g.GetUser(context.Background(), "foo")
+			require.NoError(t, err)
+			require.Equal(t, []string{"limited", "spare", "spare"}, f.requests)
+		})
+	}
+}
+
+func TestGithubRetryAt(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	response := func(code int, headers map[string]string) *http.Response {
+		res := &http.Response{StatusCode: code, Header: http.Header{}}
+		for k, v := range headers {
+			res.Header.Set(k, v)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		response *http.Response
+		retryAt  time.Time
+		ok       bool
+	}{
+		{
+			name: "retry after",
+			response: response(http.StatusForbidden, map[string]string{
+				"Retry-After": "10",
+			}),
+			retryAt: now.Add(time.Second * 10),
+			ok:      true,
+		},
+		{
+			name: "rate limit reset",
+			response: response(http.StatusForbidden, map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			}),
+			retryAt: now.Add(time.Hour),
+			ok:      true,
+		},
+		{
+			name:     "too many requests",
+			response: response(http.StatusTooManyRequests, nil),
+			retryAt:  now.Add(githubDefaultRetryAfter),
+			ok:       true,
+		},
+		{
+			name:     "forbidden",
+			response: response(http.StatusForbidden, nil),
+		},
+		{
+			name: "remaining budget",
+			response: response(http.StatusForbidden, map[string]string{
+				"X-RateLimit-Remaining": "10",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			}),
+		},
+		{
+			name: "not found",
+			response: response(http.StatusNotFound, map[string]string{
+				"Retry-After": "10",
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retryAt, ok := githubRetryAt(test.response, now)
+			require.Equal(t, test.ok, ok)
+			require.True(t, test.retryAt.Equal(retryAt), "expected %s, got %s", test.retryAt, retryAt)
+		})
+	}
+}
+
+func TestGithub_Tokens(t *testing.T) {
+	reset := github.Timestamp{Time: time.Now().Add(time.Hour)}
+	rate := func(remaining int) github.Rate {
+		return github.Rate{Limit: 5000, Remaining: remaining, Reset: reset}
+	}
+	unauthorized := respond(http.StatusUnauthorized, nil, `{"message": "Bad credentials"}`)
+	limited := respond(http.StatusForbidden, map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	}, `{"message": "API rate limit exceeded for user ID 1."}`)
+
+	tests := []struct {
+		name      string
+		tokens    map[string]githubToken
+		responses map[string][]func(w http.ResponseWriter)
+		requests  []string
+		revoked   []string
+		err       error
+	}{
+		{
+			name: "most remaining budget",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(10)},
+				"b": {rate: rate(100)},
+				"c": {rate: rate(50)},
+			},
+			requests: []string{"b"},
+		},
+		{
+			name: "unused token",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(4000)},
+				"b": {},
+			},
+			requests: []string{"b"},
+		},
+		{
+			name: "exhausted token",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(0)},
+				"b": {rate: rate(1)},
+			},
+			requests: []string{"b"},
+		},
+		{
+			name: "paused token",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(4000), pausedUntil: time.Now().Add(time.Minute)},
+				"b": {rate: rate(1)},
+			},
+			requests: []string{"b"},
+		},
+		{
+			name: "rotate when rate limited",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(100)},
+				"b": {rate: rate(10)},
+			},
+			responses: map[string][]func(w http.ResponseWriter){
+				"a": {limited},
+			},
+			requests: []string{"a", "b"},
+		},
+		{
+			name: "drop revoked token",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(100)},
+				"b": {rate: rate(10)},
+			},
+			responses: map[string][]func(w http.ResponseWriter){
+				"a": {unauthorized},
+			},
+			requests: []string{"a", "b"},
+			revoked:  []string{"a"},
+		},
+		{
+			name: "all tokens revoked",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(100)},
+				"b": {rate: rate(10)},
+			},
+			responses: map[string][]func(w http.ResponseWriter){
+				"a": {unauthorized},
+				"b": {unauthorized},
+			},
+			requests: []string{"a", "b"},
+			revoked:  []string{"a", "b"},
+			err:      ErrNoTokens,
+		},
+		{
+			name: "all tokens paused",
+			tokens: map[string]githubToken{
+				"a": {rate: rate(0)},
+				"b": {rate: rate(100), pausedUntil: time.Now().Add(time.Minute)},
+			},
+			err: context.DeadlineExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeGithub(t)
+			defer f.server.Close()
+			for token, responses := range test.responses {
+				f.responses[token] = responses
+			}
+
+			tokens := []string{}
+			for token := range test.tokens {
+				tokens = append(tokens, token)
+			}
+			g := getGithub(t, f, tokens...)
+			for token, state := range test.tokens {
+				g.tokens[token].rate = state.rate
+				g.tokens[token].pausedUntil = state.pausedUntil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+
+			_, err := g.GetUser(ctx, "foo")
+			require.Equal(t, test.err, errors.Cause(err))
+			require.Equal(t, test.requests, f.requests)
+
+			revoked := []string{}
+			for token, state := range g.tokens {
+				if state.revoked {
+					revoked = append(revoked, token)
+				}
+			}
+			sort.Strings(revoked)
+			require.Equal(t, append([]string{}, test.revoked...), revoked)
+
+			// revoked tokens are not added back to the pool
+			for _, token := range test.revoked {
+				g.AddToken(token)
+				require.True(t, g.tokens[token].revoked)
+			}
+		})
+	}
+}
+
+func TestNewGithub(t *testing.T) {
+	_, err := NewGithub("", []string{"a"})
+	require.Equal(t, ErrNoBotToken, err)
+
+	prv, err := NewGithub("bot", []string{"a", "bot"})
+	require.NoError(t, err)
+	require.Len(t, prv.(*Github).tokens, 2)
+}
+
+func TestGithub_FollowUser(t *testing.T) {
+	unauthorized := respond(http.StatusUnauthorized, nil, `{"message": "Bad credentials"}`)
+
+	f := newFakeGithub(t)
+	defer f.server.Close()
+
+	g := getGithub(t, f, "bot", "a")
+	g.botToken = "bot"
+	g.tokens["a"].rate = github.Rate{Limit: 5000, Remaining: 5000}
+	g.tokens["bot"].rate = github.Rate{Limit: 5000, Remaining: 1}
+
+	// users are followed as the bot even if other tokens have more budget
+	require.NoError(t, g.FollowUser(context.Background(), "foo"))
+	require.Equal(t, []string{"bot"}, f.requests)
+
+	// and never with other tokens, even if the bot's is revoked
+	f.responses["bot"] = []func(w http.ResponseWriter){unauthorized}
+	err := g.FollowUser(context.Background(), "foo")
+	require.Equal(t, ErrNoTokens, errors.Cause(err))
+	require.Equal(t, []string{"bot", "bot"}, f.requests)
+}
@@ -0,0 +1,624 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package providerfakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/provider"
+)
+
+type FakeProvider struct {
+	AddTokenStub        func(string)
+	addTokenMutex       sync.RWMutex
+	addTokenArgsForCall []struct {
+		arg1 string
+	}
+	FollowUserStub        func(context.Context, string) error
+	followUserMutex       sync.RWMutex
+	followUserArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	followUserReturns struct {
+		result1 error
+	}
+	followUserReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GetRepositoryStub        func(context.Context, string) (*model.Repository, error)
+	getRepositoryMutex       sync.RWMutex
+	getRepositoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getRepositoryReturns struct {
+		result1 *model.Repository
+		result2 error
+	}
+	getRepositoryReturnsOnCall map[int]struct {
+		result1 *model.Repository
+		result2 error
+	}
+	GetUserStub        func(context.Context, string) (*model.UserProfile, error)
+	getUserMutex       sync.RWMutex
+	getUserArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getUserReturns struct {
+		result1 *model.UserProfile
+		result2 error
+	}
+	getUserReturnsOnCall map[int]struct {
+		result1 *model.UserProfile
+		result2 error
+	}
+	GetUserFolloweesStub        func(context.Context, string) ([]string, error)
+	getUserFolloweesMutex       sync.RWMutex
+	getUserFolloweesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getUserFolloweesReturns struct {
+		result1 []string
+		result2 error
+	}
+	getUserFolloweesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	GetUserRepositoriesStub        func(context.Context, string) ([]model.OwnedRepository, error)
+	getUserRepositoriesMutex       sync.RWMutex
+	getUserRepositoriesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getUserRepositoriesReturns struct {
+		result1 []model.OwnedRepository
+		result2 error
+	}
+	getUserRepositoriesReturnsOnCall map[int]struct {
+		result1 []model.OwnedRepository
+		result2 error
+	}
+	GetUserStarsStub        func(context.Context, string) ([]model.StarredRepository, error)
+	getUserStarsMutex       sync.RWMutex
+	getUserStarsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getUserStarsReturns struct {
+		result1 []model.StarredRepository
+		result2 error
+	}
+	getUserStarsReturnsOnCall map[int]struct {
+		result1 []model.StarredRepository
+		result2 error
+	}
+	RateLimitStub        func() provider.RateLimit
+	rateLimitMutex       sync.RWMutex
+	rateLimitArgsForCall []struct {
+	}
+	rateLimitReturns struct {
+		result1 provider.RateLimit
+	}
+	rateLimitReturnsOnCall map[int]struct {
+		result1 provider.RateLimit
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeProvider) AddToken(arg1 string) {
+	fake.addTokenMutex.Lock()
+	fake.addTokenArgsForCall = append(fake.addTokenArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AddTokenStub
+	fake.recordInvocation("AddToken", []interface{}{arg1})
+	fake.addTokenMutex.Unlock()
+	if stub != nil {
+		fake.AddTokenStub(arg1)
+	}
+}
+
+func (fake *FakeProvider) AddTokenCallCount() int {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	return len(fake.addTokenArgsForCall)
+}
+
+func (fake *FakeProvider) AddTokenCalls(stub func(string)) {
+	fake.addTokenMutex.Lock()
+	defer fake.addTokenMutex.Unlock()
+	fake.AddTokenStub = stub
+}
+
+func (fake *FakeProvider) AddTokenArgsForCall(i int) string {
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	argsForCall := fake.addTokenArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeProvider) FollowUser(arg1 context.Context, arg2 string) error {
+	fake.followUserMutex.Lock()
+	ret, specificReturn := fake.followUserReturnsOnCall[len(fake.followUserArgsForCall)]
+	fake.followUserArgsForCall = append(fake.followUserArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.FollowUserStub
+	fakeReturns := fake.followUserReturns
+	fake.recordInvocation("FollowUser", []interface{}{arg1, arg2})
+	fake.followUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeProvider) FollowUserCallCount() int {
+	fake.followUserMutex.RLock()
+	defer fake.followUserMutex.RUnlock()
+	return len(fake.followUserArgsForCall)
+}
+
+func (fake *FakeProvider) FollowUserCalls(stub func(context.Context, string) error) {
+	fake.followUserMutex.Lock()
+	defer fake.followUserMutex.Unlock()
+	fake.FollowUserStub = stub
+}
+
+func (fake *FakeProvider) FollowUserArgsForCall(i int) (context.Context, string) {
+	fake.followUserMutex.RLock()
+	defer fake.followUserMutex.RUnlock()
+	argsForCall := fake.followUserArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) FollowUserReturns(result1 error) {
+	fake.followUserMutex.Lock()
+	defer fake.followUserMutex.Unlock()
+	fake.FollowUserStub = nil
+	fake.followUserReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeProvider) FollowUserReturnsOnCall(i int, result1 error) {
+	fake.followUserMutex.Lock()
+	defer fake.followUserMutex.Unlock()
+	fake.FollowUserStub = nil
+	if fake.followUserReturnsOnCall == nil {
+		fake.followUserReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.followUserReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeProvider) GetRepository(arg1 context.Context, arg2 string) (*model.Repository, error) {
+	fake.getRepositoryMutex.Lock()
+	ret, specificReturn := fake.getRepositoryReturnsOnCall[len(fake.getRepositoryArgsForCall)]
+	fake.getRepositoryArgsForCall = append(fake.getRepositoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetRepositoryStub
+	fakeReturns := fake.getRepositoryReturns
+	fake.recordInvocation("GetRepository", []interface{}{arg1, arg2})
+	fake.getRepositoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeProvider) GetRepositoryCallCount() int {
+	fake.getRepositoryMutex.RLock()
+	defer fake.getRepositoryMutex.RUnlock()
+	return len(fake.getRepositoryArgsForCall)
+}
+
+func (fake *FakeProvider) GetRepositoryCalls(stub func(context.Context, string) (*model.Repository, error)) {
+	fake.getRepositoryMutex.Lock()
+	defer fake.getRepositoryMutex.Unlock()
+	fake.GetRepositoryStub = stub
+}
+
+func (fake *FakeProvider) GetRepositoryArgsForCall(i int) (context.Context, string) {
+	fake.getRepositoryMutex.RLock()
+	defer fake.getRepositoryMutex.RUnlock()
+	argsForCall := fake.getRepositoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) GetRepositoryReturns(result1 *model.Repository, result2 error) {
+	fake.getRepositoryMutex.Lock()
+	defer fake.getRepositoryMutex.Unlock()
+	fake.GetRepositoryStub = nil
+	fake.getRepositoryReturns = struct {
+		result1 *model.Repository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetRepositoryReturnsOnCall(i int, result1 *model.Repository, result2 error) {
+	fake.getRepositoryMutex.Lock()
+	defer fake.getRepositoryMutex.Unlock()
+	fake.GetRepositoryStub = nil
+	if fake.getRepositoryReturnsOnCall == nil {
+		fake.getRepositoryReturnsOnCall = make(map[int]struct {
+			result1 *model.Repository
+			result2 error
+		})
+	}
+	fake.getRepositoryReturnsOnCall[i] = struct {
+		result1 *model.Repository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUser(arg1 context.Context, arg2 string) (*model.UserProfile, error) {
+	fake.getUserMutex.Lock()
+	ret, specificReturn := fake.getUserReturnsOnCall[len(fake.getUserArgsForCall)]
+	fake.getUserArgsForCall = append(fake.getUserArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetUserStub
+	fakeReturns := fake.getUserReturns
+	fake.recordInvocation("GetUser", []interface{}{arg1, arg2})
+	fake.getUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeProvider) GetUserCallCount() int {
+	fake.getUserMutex.RLock()
+	defer fake.getUserMutex.RUnlock()
+	return len(fake.getUserArgsForCall)
+}
+
+func (fake *FakeProvider) GetUserCalls(stub func(context.Context, string) (*model.UserProfile, error)) {
+	fake.getUserMutex.Lock()
+	defer fake.getUserMutex.Unlock()
+	fake.GetUserStub = stub
+}
+
+func (fake *FakeProvider) GetUserArgsForCall(i int) (context.Context, string) {
+	fake.getUserMutex.RLock()
+	defer fake.getUserMutex.RUnlock()
+	argsForCall := fake.getUserArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) GetUserReturns(result1 *model.UserProfile, result2 error) {
+	fake.getUserMutex.Lock()
+	defer fake.getUserMutex.Unlock()
+	fake.GetUserStub = nil
+	fake.getUserReturns = struct {
+		result1 *model.UserProfile
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserReturnsOnCall(i int, result1 *model.UserProfile, result2 error) {
+	fake.getUserMutex.Lock()
+	defer fake.getUserMutex.Unlock()
+	fake.GetUserStub = nil
+	if fake.getUserReturnsOnCall == nil {
+		fake.getUserReturnsOnCall = make(map[int]struct {
+			result1 *model.UserProfile
+			result2 error
+		})
+	}
+	fake.getUserReturnsOnCall[i] = struct {
+		result1 *model.UserProfile
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserFollowees(arg1 context.Context, arg2 string) ([]string, error) {
+	fake.getUserFolloweesMutex.Lock()
+	ret, specificReturn := fake.getUserFolloweesReturnsOnCall[len(fake.getUserFolloweesArgsForCall)]
+	fake.getUserFolloweesArgsForCall = append(fake.getUserFolloweesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetUserFolloweesStub
+	fakeReturns := fake.getUserFolloweesReturns
+	fake.recordInvocation("GetUserFollowees", []interface{}{arg1, arg2})
+	fake.getUserFolloweesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeProvider) GetUserFolloweesCallCount() int {
+	fake.getUserFolloweesMutex.RLock()
+	defer fake.getUserFolloweesMutex.RUnlock()
+	return len(fake.getUserFolloweesArgsForCall)
+}
+
+func (fake *FakeProvider) GetUserFolloweesCalls(stub func(context.Context, string) ([]string, error)) {
+	fake.getUserFolloweesMutex.Lock()
+	defer fake.getUserFolloweesMutex.Unlock()
+	fake.GetUserFolloweesStub = stub
+}
+
+func (fake *FakeProvider) GetUserFolloweesArgsForCall(i int) (context.Context, string) {
+	fake.getUserFolloweesMutex.RLock()
+	defer fake.getUserFolloweesMutex.RUnlock()
+	argsForCall := fake.getUserFolloweesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) GetUserFolloweesReturns(result1 []string, result2 error) {
+	fake.getUserFolloweesMutex.Lock()
+	defer fake.getUserFolloweesMutex.Unlock()
+	fake.GetUserFolloweesStub = nil
+	fake.getUserFolloweesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserFolloweesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.getUserFolloweesMutex.Lock()
+	defer fake.getUserFolloweesMutex.Unlock()
+	fake.GetUserFolloweesStub = nil
+	if fake.getUserFolloweesReturnsOnCall == nil {
+		fake.getUserFolloweesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.getUserFolloweesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserRepositories(arg1 context.Context, arg2 string) ([]model.OwnedRepository, error) {
+	fake.getUserRepositoriesMutex.Lock()
+	ret, specificReturn := fake.getUserRepositoriesReturnsOnCall[len(fake.getUserRepositoriesArgsForCall)]
+	fake.getUserRepositoriesArgsForCall = append(fake.getUserRepositoriesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetUserRepositoriesStub
+	fakeReturns := fake.getUserRepositoriesReturns
+	fake.recordInvocation("GetUserRepositories", []interface{}{arg1, arg2})
+	fake.getUserRepositoriesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeProvider) GetUserRepositoriesCallCount() int {
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
+	return len(fake.getUserRepositoriesArgsForCall)
+}
+
+func (fake *FakeProvider) GetUserRepositoriesCalls(stub func(context.Context, string) ([]model.OwnedRepository, error)) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = stub
+}
+
+func (fake *FakeProvider) GetUserRepositoriesArgsForCall(i int) (context.Context, string) {
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
+	argsForCall := fake.getUserRepositoriesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) GetUserRepositoriesReturns(result1 []model.OwnedRepository, result2 error) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = nil
+	fake.getUserRepositoriesReturns = struct {
+		result1 []model.OwnedRepository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserRepositoriesReturnsOnCall(i int, result1 []model.OwnedRepository, result2 error) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = nil
+	if fake.getUserRepositoriesReturnsOnCall == nil {
+		fake.getUserRepositoriesReturnsOnCall = make(map[int]struct {
+			result1 []model.OwnedRepository
+			result2 error
+		})
+	}
+	fake.getUserRepositoriesReturnsOnCall[i] = struct {
+		result1 []model.OwnedRepository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserStars(arg1 context.Context, arg2 string) ([]model.StarredRepository, error) {
+	fake.getUserStarsMutex.Lock()
+	ret, specificReturn := fake.getUserStarsReturnsOnCall[len(fake.getUserStarsArgsForCall)]
+	fake.getUserStarsArgsForCall = append(fake.getUserStarsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetUserStarsStub
+	fakeReturns := fake.getUserStarsReturns
+	fake.recordInvocation("GetUserStars", []interface{}{arg1, arg2})
+	fake.getUserStarsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeProvider) GetUserStarsCallCount() int {
+	fake.getUserStarsMutex.RLock()
+	defer fake.getUserStarsMutex.RUnlock()
+	return len(fake.getUserStarsArgsForCall)
+}
+
+func (fake *FakeProvider) GetUserStarsCalls(stub func(context.Context, string) ([]model.StarredRepository, error)) {
+	fake.getUserStarsMutex.Lock()
+	defer fake.getUserStarsMutex.Unlock()
+	fake.GetUserStarsStub = stub
+}
+
+func (fake *FakeProvider) GetUserStarsArgsForCall(i int) (context.Context, string) {
+	fake.getUserStarsMutex.RLock()
+	defer fake.getUserStarsMutex.RUnlock()
+	argsForCall := fake.getUserStarsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeProvider) GetUserStarsReturns(result1 []model.StarredRepository, result2 error) {
+	fake.getUserStarsMutex.Lock()
+	defer fake.getUserStarsMutex.Unlock()
+	fake.GetUserStarsStub = nil
+	fake.getUserStarsReturns = struct {
+		result1 []model.StarredRepository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) GetUserStarsReturnsOnCall(i int, result1 []model.StarredRepository, result2 error) {
+	fake.getUserStarsMutex.Lock()
+	defer fake.getUserStarsMutex.Unlock()
+	fake.GetUserStarsStub = nil
+	if fake.getUserStarsReturnsOnCall == nil {
+		fake.getUserStarsReturnsOnCall = make(map[int]struct {
+			result1 []model.StarredRepository
+			result2 error
+		})
+	}
+	fake.getUserStarsReturnsOnCall[i] = struct {
+		result1 []model.StarredRepository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeProvider) RateLimit() provider.RateLimit {
+	fake.rateLimitMutex.Lock()
+	ret, specificReturn := fake.rateLimitReturnsOnCall[len(fake.rateLimitArgsForCall)]
+	fake.rateLimitArgsForCall = append(fake.rateLimitArgsForCall, struct {
+	}{})
+	stub := fake.RateLimitStub
+	fakeReturns := fake.rateLimitReturns
+	fake.recordInvocation("RateLimit", []interface{}{})
+	fake.rateLimitMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeProvider) RateLimitCallCount() int {
+	fake.rateLimitMutex.RLock()
+	defer fake.rateLimitMutex.RUnlock()
+	return len(fake.rateLimitArgsForCall)
+}
+
+func (fake *FakeProvider) RateLimitCalls(stub func() provider.RateLimit) {
+	fake.rateLimitMutex.Lock()
+	defer fake.rateLimitMutex.Unlock()
+	fake.RateLimitStub = stub
+}
+
+func (fake *FakeProvider) RateLimitReturns(result1 provider.RateLimit) {
+	fake.rateLimitMutex.Lock()
+	defer fake.rateLimitMutex.Unlock()
+	fake.RateLimitStub = nil
+	fake.rateLimitReturns = struct {
+		result1 provider.RateLimit
+	}{result1}
+}
+
+func (fake *FakeProvider) RateLimitReturnsOnCall(i int, result1 provider.RateLimit) {
+	fake.rateLimitMutex.Lock()
+	defer fake.rateLimitMutex.Unlock()
+	fake.RateLimitStub = nil
+	if fake.rateLimitReturnsOnCall == nil {
+		fake.rateLimitReturnsOnCall = make(map[int]struct {
+			result1 provider.RateLimit
+		})
+	}
+	fake.rateLimitReturnsOnCall[i] = struct {
+		result1 provider.RateLimit
+	}{result1}
+}
+
+func (fake *FakeProvider) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.addTokenMutex.RLock()
+	defer fake.addTokenMutex.RUnlock()
+	fake.followUserMutex.RLock()
+	defer fake.followUserMutex.RUnlock()
+	fake.getRepositoryMutex.RLock()
+	defer fake.getRepositoryMutex.RUnlock()
+	fake.getUserMutex.RLock()
+	defer fake.getUserMutex.RUnlock()
+	fake.getUserFolloweesMutex.RLock()
+	defer fake.getUserFolloweesMutex.RUnlock()
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
+	fake.getUserStarsMutex.RLock()
+	defer fake.getUserStarsMutex.RUnlock()
+	fake.rateLimitMutex.RLock()
+	defer fake.rateLimitMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeProvider) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ provider.Provider = new(FakeProvider)
@@ -1,9 +1,44 @@
 package queue
 
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Queue
 
+// ErrClosed is returned when pushing to or popping from a closed queue
+var ErrClosed = errors.New("queue closed")
+
+// ErrLeaseNotFound is returned when acknowledging a lease that has already
+// been acknowledged or has expired
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// Lease is a task popped from a queue, it has to be acknowledged once the
+// task has been handled or it will reappear in the queue after the queue's
+// visibility timeout
+type Lease struct {
+	ID   string
+	Task interface{}
+}
+
 // Queue represents the interface for our queue implementations
 type Queue interface {
 	Push(interface{}) error
-	Pop() (interface{}, error)
+	// PushDelayed pushes an item that will not be popped before the given
+	// time
+	PushDelayed(interface{}, time.Time) error
+	// Pop returns nil if the queue is empty
+	Pop() (*Lease, error)
+	// PopContext blocks until an item is available or the context is done
+	PopContext(context.Context) (*Lease, error)
+	// Ack removes a leased task from the queue
+	Ack(*Lease) error
+	// Nack returns a leased task to the queue
+	Nack(*Lease) error
+	// Close stops the queue from accepting or returning new tasks, leases
+	// can still be acknowledged
+	Close() error
 }
@@ -1,19 +1,53 @@
 package queue
 
 import (
+	"context"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
 	"github.com/joncrlsn/dque"
 	"github.com/pkg/errors"
 )
 
+const (
+	dqueueLeaseExtension = ".lease"
+)
+
 // DQueue implements a Queue using a DQueue as the underlying provider
+// Leased tasks are persisted in a directory next to the queue until they are
+// acknowledged, so they are not lost if the process dies while handling them
 type DQueue struct {
 	dque *dque.DQue
+
+	taskType          reflect.Type
+	leasesDir         string
+	visibilityTimeout time.Duration
+
+	mutex  sync.Mutex
+	leases map[string]time.Time
+	// pushed is closed and replaced every time items become available
+	pushed chan struct{}
+	closed bool
 }
 
 // NewDQueue constrcuts a new Queue with an underlying DQueue provider
-func NewDQueue(name, dir string, task interface{}) (Queue, error) {
+func NewDQueue(
+	name string,
+	dir string,
+	task interface{},
+	visibilityTimeout time.Duration,
+) (Queue, error) {
+	// every item needs its own instance of the task's type
+	taskType := reflect.TypeOf(task).Elem()
 	d, err := dque.NewOrOpen(name, dir, 50, func() interface{} {
-		return task
+		return reflect.New(taskType).Interface()
 	})
 
 	if err != nil {
@@ -21,7 +55,21 @@ func NewDQueue(name, dir string, task interface{}) (Queue, error) {
 	}
 
 	q := &DQueue{
-		dque: d,
+		dque:              d,
+		taskType:          taskType,
+		leasesDir:         filepath.Join(dir, name+".leases"),
+		visibilityTimeout: visibilityTimeout,
+		leases:            map[string]time.Time{},
+		pushed:            make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(q.leasesDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create leases dir")
+	}
+
+	// leases left over from a previous run will never be acknowledged
+	if err := q.restoreLeases(); err != nil {
+		return nil, errors.Wrap(err, "failed to restore leases")
 	}
 
 	return q, nil
@@ -29,10 +77,299 @@ func NewDQueue(name, dir string, task interface{}) (Queue, error) {
 
 // Push item to the end of the queue
 func (q *DQueue) Push(o interface{}) error {
-	return q.dque.Enqueue(o)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	return q.enqueue(o)
+}
+
+// PushDelayed holds an item back until the given time, it is persisted as a
+// lease that nobody holds and that expires once the item is due
+// Delayed items are returned to the queue straight away after a restart
+func (q *DQueue) PushDelayed(o interface{}, at time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return errors.Wrap(err, "could not create lease id")
+	}
+
+	lease := &Lease{
+		ID:   id.String(),
+		Task: o,
+	}
+
+	if err := q.writeLease(lease); err != nil {
+		return errors.Wrap(err, "could not persist delayed item")
+	}
+
+	q.leases[lease.ID] = at
+
+	// wake up everyone waiting for items so they wait for the new deadline
+	close(q.pushed)
+	q.pushed = make(chan struct{})
+
+	return nil
+}
+
+// Pop leases item from top of the queue, returns nil if the queue is empty
+func (q *DQueue) Pop() (*Lease, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.pop()
+}
+
+// PopContext leases item from top of the queue, blocking until an item is
+// available or the context is done
+// The underlying dque does not support blocking, but as it cannot be shared
+// between processes all items are pushed through this queue
+func (q *DQueue) PopContext(ctx context.Context) (*Lease, error) {
+	for {
+		q.mutex.Lock()
+		lease, err := q.pop()
+		pushed := q.pushed
+		expiry := q.nextExpiry()
+		q.mutex.Unlock()
+
+		if lease != nil || err != nil {
+			return lease, err
+		}
+
+		// wake up when the next lease expires
+		var expired <-chan time.Time
+		var timer *time.Timer
+		if !expiry.IsZero() {
+			timer = time.NewTimer(time.Until(expiry))
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-pushed:
+		case <-expired:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (q *DQueue) pop() (*Lease, error) {
+	if q.closed {
+		return nil, ErrClosed
+	}
+
+	if err := q.requeueExpiredLeases(); err != nil {
+		return nil, errors.Wrap(err, "could not requeue expired leases")
+	}
+
+	// persist the lease before removing the item from the queue
+	o, err := q.dque.Peek()
+	if err == dque.ErrEmpty {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create lease id")
+	}
+
+	lease := &Lease{
+		ID:   id.String(),
+		Task: o,
+	}
+
+	if err := q.writeLease(lease); err != nil {
+		return nil, errors.Wrap(err, "could not persist lease")
+	}
+
+	if _, err := q.dque.Dequeue(); err != nil {
+		return nil, err
+	}
+
+	q.leases[lease.ID] = time.Now().Add(q.visibilityTimeout)
+
+	return lease, nil
+}
+
+// Close stops the queue from returning new items and wakes up everyone
+// waiting for them, items and leases are already persisted
+func (q *DQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return nil
+	}
+
+	q.closed = true
+	close(q.pushed)
+
+	return nil
+}
+
+// Ack removes a leased item from the queue
+func (q *DQueue) Ack(lease *Lease) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.leases[lease.ID]; !ok {
+		return ErrLeaseNotFound
+	}
+
+	return q.removeLease(lease.ID)
+}
+
+// Nack returns a leased item to the end of the queue
+func (q *DQueue) Nack(lease *Lease) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.leases[lease.ID]; !ok {
+		return ErrLeaseNotFound
+	}
+
+	if err := q.enqueue(lease.Task); err != nil {
+		return err
+	}
+
+	return q.removeLease(lease.ID)
+}
+
+// requeueExpiredLeases returns items whose lease has expired to the queue
+func (q *DQueue) requeueExpiredLeases() error {
+	now := time.Now()
+	for id, deadline := range q.leases {
+		if now.Before(deadline) {
+			continue
+		}
+
+		o, err := q.readLease(id)
+		if err != nil {
+			return err
+		}
+
+		if err := q.enqueue(o); err != nil {
+			return err
+		}
+
+		if err := q.removeLease(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreLeases returns all persisted leases to the queue
+func (q *DQueue) restoreLeases() error {
+	files, err := ioutil.ReadDir(q.leasesDir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), dqueueLeaseExtension) {
+			continue
+		}
+
+		id := strings.TrimSuffix(file.Name(), dqueueLeaseExtension)
+		o, err := q.readLease(id)
+		if err != nil {
+			return err
+		}
+
+		if err := q.dque.Enqueue(o); err != nil {
+			return err
+		}
+
+		if err := os.Remove(q.leasePath(id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueue pushes an item and wakes up everyone waiting for items
+func (q *DQueue) enqueue(o interface{}) error {
+	if err := q.dque.Enqueue(o); err != nil {
+		return err
+	}
+
+	// after closing there is no one left to wake up
+	if !q.closed {
+		close(q.pushed)
+		q.pushed = make(chan struct{})
+	}
+
+	return nil
+}
+
+// nextExpiry returns the earliest lease deadline, or zero if there are none
+func (q *DQueue) nextExpiry() time.Time {
+	next := time.Time{}
+	for _, deadline := range q.leases {
+		if next.IsZero() || deadline.Before(next) {
+			next = deadline
+		}
+	}
+	return next
+}
+
+func (q *DQueue) leasePath(id string) string {
+	return filepath.Join(q.leasesDir, id+dqueueLeaseExtension)
+}
+
+func (q *DQueue) writeLease(lease *Lease) error {
+	f, err := os.Create(q.leasePath(lease.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := gob.NewEncoder(f).Encode(lease.Task); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+func (q *DQueue) readLease(id string) (interface{}, error) {
+	f, err := os.Open(q.leasePath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	o := reflect.New(q.taskType).Interface()
+	if err := gob.NewDecoder(f).Decode(o); err != nil {
+		return nil, err
+	}
+
+	return o, nil
 }
 
-// Pop item from top of the queue
-func (q *DQueue) Pop() (interface{}, error) {
-	return q.dque.Dequeue()
+func (q *DQueue) removeLease(id string) error {
+	delete(q.leases, id)
+	return os.Remove(q.leasePath(id))
 }
@@ -0,0 +1,206 @@
+package queue
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTask struct {
+	Name string
+}
+
+func TestDQueue_Leases(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue
+	q, err := NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// pop from empty queue
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	// push and pop task
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+	// leased task should not be popped again
+	gotEmpty, err := q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotEmpty)
+
+	// nack returns the task to the queue
+	require.NoError(t, q.Nack(gotLease))
+	require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+	// ack removes the task
+	require.NoError(t, q.Ack(gotLease))
+	require.Equal(t, ErrLeaseNotFound, q.Nack(gotLease))
+	gotEmpty, err = q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotEmpty)
+}
+
+func TestDQueue_ExpiredLeases(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue with an already expired visibility timeout
+	q, err := NewDQueue("test.queue", dir, &testTask{}, 0)
+	require.NoError(t, err)
+
+	// push and pop task
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+	// expired lease reappears
+	gotExpiredLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotExpiredLease.Task)
+	require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+	require.NoError(t, q.Ack(gotExpiredLease))
+}
+
+func TestDQueue_RestoreLeases(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue
+	q, err := NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// push and pop task without acking it
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.NotNil(t, gotLease)
+
+	// reopen queue, as if the process died while handling the task
+	q, err = NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// unacked task reappears
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+}
+
+func getDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "go-discover-queue")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestDQueue_PopContext(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue
+	q, err := NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// cancelled pop
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	gotLease, err := q.PopContext(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Nil(t, gotLease)
+
+	// blocked pop wakes up on push
+	leases := make(chan *Lease)
+	go func() {
+		lease, err := q.PopContext(context.Background())
+		require.NoError(t, err)
+		leases <- lease
+	}()
+	time.Sleep(time.Millisecond * 10)
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+
+	select {
+	case gotLease = <-leases:
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+	case <-time.After(time.Second):
+		require.FailNow(t, "pop did not wake up")
+	}
+}
+
+func TestDQueue_Close(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue
+	q, err := NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+
+	// blocked pops return once the queue is closed
+	popped := make(chan error)
+	go func() {
+		_, err := q.PopContext(context.Background())
+		popped <- err
+	}()
+
+	require.NoError(t, q.Close())
+	require.Equal(t, ErrClosed, <-popped)
+
+	// closed queues don't accept or return tasks
+	require.Equal(t, ErrClosed, q.Push(&testTask{Name: "bar"}))
+	_, err = q.Pop()
+	require.Equal(t, ErrClosed, err)
+
+	// leases can still be returned
+	require.NoError(t, q.Nack(gotLease))
+
+	// the returned task is there when reopening the queue
+	q, err = NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+}
+
+func TestDQueue_PushDelayed(t *testing.T) {
+	dir := getDir(t)
+
+	// construct queue
+	q, err := NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// delayed task is held back until it's due
+	require.NoError(t, q.PushDelayed(&testTask{Name: "foo"}, time.Now().Add(time.Millisecond*50)))
+	require.NoError(t, q.Push(&testTask{Name: "bar"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "bar"}, gotLease.Task)
+	require.NoError(t, q.Ack(gotLease))
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	// blocked pop wakes up once it's due
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	gotLease, err = q.PopContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+	require.NoError(t, q.Ack(gotLease))
+
+	// delayed tasks are persisted
+	require.NoError(t, q.PushDelayed(&testTask{Name: "baz"}, time.Now().Add(time.Hour)))
+	q, err = NewDQueue("test.queue", dir, &testTask{}, time.Hour)
+	require.NoError(t, err)
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "baz"}, gotLease.Task)
+}
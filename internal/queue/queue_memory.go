@@ -0,0 +1,253 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// memoryLease is a task popped from a Memory queue
+type memoryLease struct {
+	task     interface{}
+	deadline time.Time
+}
+
+// Memory implements a concurrency-safe Queue in memory
+// Items are lost when the process exits, it's meant for tests and for running
+// everything in a single process
+type Memory struct {
+	visibilityTimeout time.Duration
+
+	mutex  sync.Mutex
+	items  []interface{}
+	leases map[string]*memoryLease
+	// pushed is closed and replaced every time items become available
+	pushed chan struct{}
+	closed bool
+}
+
+// NewMemory constructs a new in-memory Queue
+func NewMemory(visibilityTimeout time.Duration) (*Memory, error) {
+	q := &Memory{
+		visibilityTimeout: visibilityTimeout,
+		leases:            map[string]*memoryLease{},
+		pushed:            make(chan struct{}),
+	}
+
+	return q, nil
+}
+
+// Push item to the end of the queue
+func (q *Memory) Push(o interface{}) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.push(o)
+
+	return nil
+}
+
+// PushDelayed holds an item back until the given time, it is kept as a lease
+// that nobody holds and that expires once the item is due
+func (q *Memory) PushDelayed(o interface{}, at time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return errors.Wrap(err, "could not create lease id")
+	}
+
+	q.leases[id.String()] = &memoryLease{
+		task:     o,
+		deadline: at,
+	}
+
+	// wake up everyone waiting for items so they wait for the new deadline
+	close(q.pushed)
+	q.pushed = make(chan struct{})
+
+	return nil
+}
+
+// Pop leases item from top of the queue, returns nil if the queue is empty
+func (q *Memory) Pop() (*Lease, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.pop()
+}
+
+// PopContext leases item from top of the queue, blocking until an item is
+// available or the context is done
+func (q *Memory) PopContext(ctx context.Context) (*Lease, error) {
+	for {
+		q.mutex.Lock()
+		lease, err := q.pop()
+		pushed := q.pushed
+		expiry := q.nextExpiry()
+		q.mutex.Unlock()
+
+		if lease != nil || err != nil {
+			return lease, err
+		}
+
+		// wake up when the next lease expires
+		var expired <-chan time.Time
+		var timer *time.Timer
+		if !expiry.IsZero() {
+			timer = time.NewTimer(time.Until(expiry))
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-pushed:
+		case <-expired:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Ack removes a leased item from the queue
+func (q *Memory) Ack(lease *Lease) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.leases[lease.ID]; !ok {
+		return ErrLeaseNotFound
+	}
+
+	delete(q.leases, lease.ID)
+
+	return nil
+}
+
+// Nack returns a leased item to the end of the queue
+func (q *Memory) Nack(lease *Lease) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.leases[lease.ID]; !ok {
+		return ErrLeaseNotFound
+	}
+
+	delete(q.leases, lease.ID)
+	q.push(lease.Task)
+
+	return nil
+}
+
+// Close stops the queue from returning new items and wakes up everyone
+// waiting for them
+func (q *Memory) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return nil
+	}
+
+	q.closed = true
+	close(q.pushed)
+
+	return nil
+}
+
+// Len returns the number of items in the queue, excluding leased ones
+func (q *Memory) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.items)
+}
+
+// Leased returns the number of leased items that have not been acknowledged,
+// including delayed ones
+func (q *Memory) Leased() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.leases)
+}
+
+func (q *Memory) push(o interface{}) {
+	q.items = append(q.items, o)
+
+	// wake up everyone waiting for items
+	if !q.closed {
+		close(q.pushed)
+		q.pushed = make(chan struct{})
+	}
+}
+
+func (q *Memory) pop() (*Lease, error) {
+	if q.closed {
+		return nil, ErrClosed
+	}
+
+	// return expired leases to the queue
+	now := time.Now()
+	for id, lease := range q.leases {
+		if now.Before(lease.deadline) {
+			continue
+		}
+		delete(q.leases, id)
+		q.items = append(q.items, lease.task)
+	}
+
+	if len(q.items) == 0 {
+		return nil, nil
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create lease id")
+	}
+
+	o := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+
+	q.leases[id.String()] = &memoryLease{
+		task:     o,
+		deadline: now.Add(q.visibilityTimeout),
+	}
+
+	lease := &Lease{
+		ID:   id.String(),
+		Task: o,
+	}
+
+	return lease, nil
+}
+
+// nextExpiry returns the earliest lease deadline, or zero if there are none
+func (q *Memory) nextExpiry() time.Time {
+	next := time.Time{}
+	for _, lease := range q.leases {
+		if next.IsZero() || lease.deadline.Before(next) {
+			next = lease.deadline
+		}
+	}
+	return next
+}
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_Leases(t *testing.T) {
+	// construct queue
+	q, err := NewMemory(time.Hour)
+	require.NoError(t, err)
+
+	// pop from empty queue
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	// push and pop task
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	require.Equal(t, 1, q.Len())
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 1, q.Leased())
+
+	// nack returns the task to the queue
+	require.NoError(t, q.Nack(gotLease))
+	require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+	require.Equal(t, 1, q.Len())
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+
+	// ack removes the task
+	require.NoError(t, q.Ack(gotLease))
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 0, q.Leased())
+}
+
+func TestMemory_ExpiredLeases(t *testing.T) {
+	// construct queue
+	q, err := NewMemory(time.Millisecond * 10)
+	require.NoError(t, err)
+
+	// push and pop task
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+
+	// expired lease reappears
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	gotExpiredLease, err := q.PopContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, gotLease.Task, gotExpiredLease.Task)
+	require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+}
+
+func TestMemory_PopContext(t *testing.T) {
+	// construct queue
+	q, err := NewMemory(time.Hour)
+	require.NoError(t, err)
+
+	// cancelled pop
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gotLease, err := q.PopContext(ctx)
+	require.Equal(t, context.Canceled, err)
+	require.Nil(t, gotLease)
+
+	// concurrent consumers
+	wg := &sync.WaitGroup{}
+	results := make(chan string, 10)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				lease, err := q.PopContext(context.Background())
+				require.NoError(t, err)
+				if lease.Task == nil {
+					return
+				}
+				results <- lease.Task.(*testTask).Name
+				require.NoError(t, q.Ack(lease))
+			}
+		}()
+	}
+
+	// push tasks, then one nil task per consumer to stop them
+	for i := 0; i < 10; i++ {
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, q.Push(nil))
+	}
+
+	wg.Wait()
+	close(results)
+	require.Len(t, results, 10)
+	require.Equal(t, 0, q.Len())
+}
+
+func TestMemory_PushDelayed(t *testing.T) {
+	// construct queue
+	q, err := NewMemory(time.Hour)
+	require.NoError(t, err)
+
+	// delayed task is held back until it's due
+	require.NoError(t, q.PushDelayed(&testTask{Name: "foo"}, time.Now().Add(time.Millisecond*50)))
+	require.NoError(t, q.Push(&testTask{Name: "bar"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "bar"}, gotLease.Task)
+	gotLease, err = q.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	// blocked pop wakes up once it's due
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	gotLease, err = q.PopContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+	// closed queues don't accept delayed tasks
+	require.NoError(t, q.Close())
+	require.Equal(t, ErrClosed, q.PushDelayed(&testTask{Name: "baz"}, time.Now()))
+}
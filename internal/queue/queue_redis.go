@@ -0,0 +1,329 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// redisBlockTimeout is how long blocking pops wait before checking for
+	// expired leases, due delayed items, and whether the context is done or
+	// the queue closed
+	redisBlockTimeout = time.Second
+	// redisNotifyLimit caps the wake-ups kept for consumers that are not
+	// waiting yet
+	redisNotifyLimit = 100
+)
+
+var (
+	// redisPushScript pushes an item and wakes up a consumer waiting for it
+	redisPushScript = redis.NewScript(`
+		redis.call('LPUSH', KEYS[1], ARGV[1])
+		redis.call('LPUSH', KEYS[4], 1)
+		redis.call('LTRIM', KEYS[4], 0, ARGV[2] - 1)
+		return 1
+	`)
+	// redisPopScript returns expired leases and due delayed items to the
+	// queue, then atomically pops the next item and leases it, leases are the
+	// item prefixed by the lease id so that a stale lease cannot acknowledge
+	// the item once it has been leased again
+	redisPopScript = redis.NewScript(`
+		local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+		for _, lease in ipairs(expired) do
+			redis.call('ZREM', KEYS[2], lease)
+			local item = string.sub(lease, string.find(lease, ':', 1, true) + 1)
+			redis.call('LPUSH', KEYS[1], item)
+		end
+		local due = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', ARGV[1])
+		for _, item in ipairs(due) do
+			redis.call('ZREM', KEYS[3], item)
+			redis.call('LPUSH', KEYS[1], item)
+		end
+		local item = redis.call('RPOP', KEYS[1])
+		if item then
+			redis.call('ZADD', KEYS[2], ARGV[2], ARGV[3] .. ':' .. item)
+		end
+		return item
+	`)
+	// redisAckScript removes a lease
+	redisAckScript = redis.NewScript(`
+		return redis.call('ZREM', KEYS[2], ARGV[1] .. ':' .. ARGV[2])
+	`)
+	// redisNackScript moves a leased item back to the queue
+	redisNackScript = redis.NewScript(`
+		if redis.call('ZREM', KEYS[2], ARGV[1] .. ':' .. ARGV[2]) == 0 then
+			return 0
+		end
+		redis.call('LPUSH', KEYS[1], ARGV[2])
+		redis.call('LPUSH', KEYS[4], 1)
+		redis.call('LTRIM', KEYS[4], 0, ARGV[3] - 1)
+		return 1
+	`)
+)
+
+// redisItem is the JSON envelope of a task stored in redis, the ID keeps
+// otherwise identical tasks distinct in the delayed set
+type redisItem struct {
+	ID   string          `json:"id"`
+	Task json.RawMessage `json:"task"`
+}
+
+// Redis implements a Queue using Redis lists, it can be shared between
+// processes
+// Popped items are moved to a sorted set scored by their lease's deadline
+// until they are acknowledged, delayed items wait in a sorted set scored by
+// when they are due
+type Redis struct {
+	client *redis.Client
+
+	taskType          reflect.Type
+	visibilityTimeout time.Duration
+
+	queueKey   string
+	leasesKey  string
+	delayedKey string
+	notifyKey  string
+
+	mutex sync.Mutex
+	// raw items of the leases popped by this process
+	leases map[string]string
+	closed bool
+}
+
+// NewRedis constructs a new Queue backed by Redis
+func NewRedis(
+	client *redis.Client,
+	name string,
+	task interface{},
+	visibilityTimeout time.Duration,
+) (Queue, error) {
+	q := &Redis{
+		client:            client,
+		taskType:          reflect.TypeOf(task).Elem(),
+		visibilityTimeout: visibilityTimeout,
+		queueKey:          "queue/" + name,
+		leasesKey:         "queue/" + name + "/leases",
+		delayedKey:        "queue/" + name + "/delayed",
+		notifyKey:         "queue/" + name + "/notify",
+		leases:            map[string]string{},
+	}
+
+	return q, nil
+}
+
+func (q *Redis) keys() []string {
+	return []string{
+		q.queueKey,
+		q.leasesKey,
+		q.delayedKey,
+		q.notifyKey,
+	}
+}
+
+// Push item to the end of the queue
+func (q *Redis) Push(o interface{}) error {
+	if q.isClosed() {
+		return ErrClosed
+	}
+
+	item, err := q.item(o)
+	if err != nil {
+		return err
+	}
+
+	return redisPushScript.Run(
+		q.client,
+		q.keys(),
+		item,
+		redisNotifyLimit,
+	).Err()
+}
+
+// PushDelayed adds an item to the delayed set, it is moved to the end of the
+// queue by the first pop after it is due
+func (q *Redis) PushDelayed(o interface{}, at time.Time) error {
+	if q.isClosed() {
+		return ErrClosed
+	}
+
+	item, err := q.item(o)
+	if err != nil {
+		return err
+	}
+
+	return q.client.ZAdd(q.delayedKey, redis.Z{
+		Score:  toRedisScore(at),
+		Member: item,
+	}).Err()
+}
+
+// item encodes a task in its envelope
+func (q *Redis) item(o interface{}) ([]byte, error) {
+	task, err := json.Marshal(o)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode task")
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create item id")
+	}
+
+	item, err := json.Marshal(&redisItem{
+		ID:   id.String(),
+		Task: task,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode item")
+	}
+
+	return item, nil
+}
+
+// Pop leases item from top of the queue, returns nil if the queue is empty
+func (q *Redis) Pop() (*Lease, error) {
+	if q.isClosed() {
+		return nil, ErrClosed
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create lease id")
+	}
+
+	now := time.Now()
+	deadline := now.Add(q.visibilityTimeout)
+
+	raw, err := redisPopScript.Run(
+		q.client,
+		q.keys(),
+		toRedisScore(now),
+		toRedisScore(deadline),
+		id.String(),
+	).String()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return q.lease(id.String(), raw)
+}
+
+// PopContext leases item from top of the queue, blocking until an item is
+// available or the context is done
+// Consumers wait for a push on the notify list, and check for expired leases
+// and due delayed items every redisBlockTimeout, so they also notice a
+// closed queue or a done context within that time
+func (q *Redis) PopContext(ctx context.Context) (*Lease, error) {
+	for {
+		lease, err := q.Pop()
+		if lease != nil || err != nil {
+			return lease, err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err = q.client.BRPop(redisBlockTimeout, q.notifyKey).Err()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+}
+
+// lease decodes a raw item and keeps track of its lease
+func (q *Redis) lease(id string, raw string) (*Lease, error) {
+	item := &redisItem{}
+	if err := json.Unmarshal([]byte(raw), item); err != nil {
+		return nil, errors.Wrap(err, "could not decode item")
+	}
+
+	task := reflect.New(q.taskType).Interface()
+	if err := json.Unmarshal(item.Task, task); err != nil {
+		return nil, errors.Wrap(err, "could not decode task")
+	}
+
+	lease := &Lease{
+		ID:   id,
+		Task: task,
+	}
+
+	q.mutex.Lock()
+	q.leases[lease.ID] = raw
+	q.mutex.Unlock()
+
+	return lease, nil
+}
+
+// Ack removes a leased item from the queue
+func (q *Redis) Ack(lease *Lease) error {
+	return q.finish(redisAckScript, lease)
+}
+
+// Nack returns a leased item to the end of the queue
+func (q *Redis) Nack(lease *Lease) error {
+	return q.finish(redisNackScript, lease, redisNotifyLimit)
+}
+
+// Close stops the queue from accepting or returning new items, blocked pops
+// return within redisBlockTimeout
+// The client is owned by the caller and is left open, items that are still
+// leased are requeued once their lease expires unless acknowledged
+func (q *Redis) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+
+	return nil
+}
+
+func (q *Redis) isClosed() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.closed
+}
+
+// toRedisScore converts a time to a sorted set score in milliseconds
+func toRedisScore(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// finish runs the given script on a lease's item and forgets the lease
+func (q *Redis) finish(script *redis.Script, lease *Lease, args ...interface{}) error {
+	q.mutex.Lock()
+	raw, ok := q.leases[lease.ID]
+	delete(q.leases, lease.ID)
+	q.mutex.Unlock()
+
+	if !ok {
+		return ErrLeaseNotFound
+	}
+
+	found, err := script.Run(
+		q.client,
+		q.keys(),
+		append([]interface{}{lease.ID, raw}, args...)...,
+	).Int64()
+	if err != nil {
+		return err
+	}
+
+	// the lease has expired and the item was returned to the queue
+	if found == 0 {
+		return ErrLeaseNotFound
+	}
+
+	return nil
+}
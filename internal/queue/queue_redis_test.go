@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// getRedisClient returns a client of an in-memory redis server
+func getRedisClient(t *testing.T) *redis.Client {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	client := redis.NewClient(&redis.Options{
+		Addr: server.Addr(),
+	})
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	return client
+}
+
+// getRedisName returns a random queue name, so that tests can share a server
+func getRedisName(t *testing.T) string {
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	return "go-discover-test-" + id.String()
+}
+
+func TestRedis_Contract(t *testing.T) {
+	testQueue(t, func(t *testing.T, visibilityTimeout time.Duration) Queue {
+		q, err := NewRedis(getRedisClient(t), getRedisName(t), &testTask{}, visibilityTimeout)
+		require.NoError(t, err)
+		return q
+	})
+}
+
+// TestRedis_ContractServer runs against a real redis, it only runs when
+// REDIS_TEST_HOST is set
+func TestRedis_ContractServer(t *testing.T) {
+	host := os.Getenv("REDIS_TEST_HOST")
+	if host == "" {
+		t.Skip("REDIS_TEST_HOST not set")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: host,
+	})
+	defer client.Close()
+
+	testQueue(t, func(t *testing.T, visibilityTimeout time.Duration) Queue {
+		q, err := NewRedis(client, getRedisName(t), &testTask{}, visibilityTimeout)
+		require.NoError(t, err)
+		return q
+	})
+}
+
+func TestRedis_RestoreLeases(t *testing.T) {
+	client := getRedisClient(t)
+	name := getRedisName(t)
+
+	// construct queue
+	q, err := NewRedis(client, name, &testTask{}, time.Millisecond*10)
+	require.NoError(t, err)
+
+	// push and pop task without acking it
+	require.NoError(t, q.Push(&testTask{Name: "foo"}))
+	gotLease, err := q.Pop()
+	require.NoError(t, err)
+	require.NotNil(t, gotLease)
+
+	// another process sharing the queue gets the task once its lease expires
+	other, err := NewRedis(client, name, &testTask{}, time.Hour)
+	require.NoError(t, err)
+	gotLease, err = other.Pop()
+	require.NoError(t, err)
+	require.Nil(t, gotLease)
+
+	time.Sleep(time.Millisecond * 20)
+	gotLease, err = other.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+	// and so do delayed tasks that are due
+	require.NoError(t, q.PushDelayed(&testTask{Name: "bar"}, time.Now()))
+	gotLease, err = other.Pop()
+	require.NoError(t, err)
+	require.Equal(t, &testTask{Name: "bar"}, gotLease.Task)
+}
+
+func TestRedis_PopLeasesOnly(t *testing.T) {
+	client := getRedisClient(t)
+	name := getRedisName(t)
+
+	q, err := NewRedis(client, name, &testTask{}, time.Hour)
+	require.NoError(t, err)
+
+	// leasing many tasks keeps them in the leases set only
+	for i := 0; i < 100; i++ {
+		require.NoError(t, q.Push(&testTask{Name: strconv.Itoa(i)}))
+	}
+	for i := 0; i < 100; i++ {
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: strconv.Itoa(i)}, gotLease.Task)
+	}
+
+	require.Equal(t, int64(0), client.LLen("queue/"+name).Val())
+	require.Equal(t, int64(100), client.ZCard("queue/"+name+"/leases").Val())
+}
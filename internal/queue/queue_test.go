@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testQueue is the contract every Queue implementation must pass, newQueue
+// constructs an empty queue of testTasks
+// Waits are generous as queues shared between processes might only poll for
+// expired leases and due delayed tasks every second
+func testQueue(
+	t *testing.T,
+	newQueue func(t *testing.T, visibilityTimeout time.Duration) Queue,
+) {
+	popContext := func(t *testing.T, q Queue) *Lease {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		lease, err := q.PopContext(ctx)
+		require.NoError(t, err)
+		return lease
+	}
+
+	t.Run("leases", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		// pop from empty queue
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+		require.Nil(t, gotLease)
+
+		// push and pop task
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+		gotLease, err = q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+		// nack returns the task to the queue
+		require.NoError(t, q.Nack(gotLease))
+		require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+		require.Equal(t, ErrLeaseNotFound, q.Nack(gotLease))
+		gotLease, err = q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+
+		// ack removes the task
+		require.NoError(t, q.Ack(gotLease))
+		require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+		gotLease, err = q.Pop()
+		require.NoError(t, err)
+		require.Nil(t, gotLease)
+	})
+
+	t.Run("order", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		for _, name := range []string{"foo", "bar", "baz"} {
+			require.NoError(t, q.Push(&testTask{Name: name}))
+		}
+
+		// nacked tasks go to the end of the queue
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+		require.NoError(t, q.Nack(gotLease))
+
+		for _, name := range []string{"bar", "baz", "foo"} {
+			gotLease, err := q.Pop()
+			require.NoError(t, err)
+			require.Equal(t, &testTask{Name: name}, gotLease.Task)
+		}
+	})
+
+	t.Run("identical tasks", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+
+		first, err := q.Pop()
+		require.NoError(t, err)
+		second, err := q.Pop()
+		require.NoError(t, err)
+		require.NotNil(t, second)
+
+		// each lease is acknowledged on its own
+		require.NoError(t, q.Ack(first))
+		require.NoError(t, q.Nack(second))
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+	})
+
+	t.Run("expired leases", func(t *testing.T) {
+		q := newQueue(t, time.Millisecond*10)
+
+		// push and pop task
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+
+		// expired lease reappears
+		gotExpiredLease := popContext(t, q)
+		require.Equal(t, gotLease.Task, gotExpiredLease.Task)
+		require.Equal(t, ErrLeaseNotFound, q.Ack(gotLease))
+		require.Equal(t, ErrLeaseNotFound, q.Nack(gotLease))
+	})
+
+	t.Run("pop context", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		// cancelled pop
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		gotLease, err := q.PopContext(ctx)
+		require.Equal(t, context.Canceled, err)
+		require.Nil(t, gotLease)
+
+		// blocked pop wakes up on push
+		leases := make(chan *Lease)
+		go func() {
+			leases <- popContext(t, q)
+		}()
+		time.Sleep(time.Millisecond * 10)
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+
+		select {
+		case gotLease = <-leases:
+			require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+		case <-time.After(time.Second * 5):
+			require.FailNow(t, "pop did not wake up")
+		}
+	})
+
+	t.Run("delayed tasks", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		// delayed task is held back until it's due
+		require.NoError(t, q.PushDelayed(&testTask{Name: "foo"}, time.Now().Add(time.Millisecond*50)))
+		require.NoError(t, q.Push(&testTask{Name: "bar"}))
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+		require.Equal(t, &testTask{Name: "bar"}, gotLease.Task)
+		gotLease, err = q.Pop()
+		require.NoError(t, err)
+		require.Nil(t, gotLease)
+
+		// blocked pop wakes up once it's due
+		gotLease = popContext(t, q)
+		require.Equal(t, &testTask{Name: "foo"}, gotLease.Task)
+		require.NoError(t, q.Ack(gotLease))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		q := newQueue(t, time.Hour)
+
+		require.NoError(t, q.Push(&testTask{Name: "foo"}))
+		gotLease, err := q.Pop()
+		require.NoError(t, err)
+
+		// blocked pops return once the queue is closed
+		popped := make(chan error)
+		go func() {
+			_, err := q.PopContext(context.Background())
+			popped <- err
+		}()
+		time.Sleep(time.Millisecond * 10)
+		require.NoError(t, q.Close())
+
+		select {
+		case err := <-popped:
+			require.Equal(t, ErrClosed, err)
+		case <-time.After(time.Second * 5):
+			require.FailNow(t, "pop did not return")
+		}
+
+		// closed queues don't accept or return tasks
+		require.Equal(t, ErrClosed, q.Push(&testTask{Name: "bar"}))
+		require.Equal(t, ErrClosed, q.PushDelayed(&testTask{Name: "bar"}, time.Now()))
+		_, err = q.Pop()
+		require.Equal(t, ErrClosed, err)
+
+		// leases can still be returned
+		require.NoError(t, q.Nack(gotLease))
+
+		// closing twice is fine
+		require.NoError(t, q.Close())
+	})
+}
+
+func TestMemory_Contract(t *testing.T) {
+	testQueue(t, func(t *testing.T, visibilityTimeout time.Duration) Queue {
+		q, err := NewMemory(visibilityTimeout)
+		require.NoError(t, err)
+		return q
+	})
+}
+
+func TestDQueue_Contract(t *testing.T) {
+	testQueue(t, func(t *testing.T, visibilityTimeout time.Duration) Queue {
+		q, err := NewDQueue("test.queue", getDir(t), &testTask{}, visibilityTimeout)
+		require.NoError(t, err)
+		return q
+	})
+}
@@ -2,22 +2,69 @@
 package queuefakes
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/kbariotis/go-discover/internal/queue"
 )
 
 type FakeQueue struct {
-	PopStub        func() (interface{}, error)
+	AckStub        func(*queue.Lease) error
+	ackMutex       sync.RWMutex
+	ackArgsForCall []struct {
+		arg1 *queue.Lease
+	}
+	ackReturns struct {
+		result1 error
+	}
+	ackReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct {
+	}
+	closeReturns struct {
+		result1 error
+	}
+	closeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	NackStub        func(*queue.Lease) error
+	nackMutex       sync.RWMutex
+	nackArgsForCall []struct {
+		arg1 *queue.Lease
+	}
+	nackReturns struct {
+		result1 error
+	}
+	nackReturnsOnCall map[int]struct {
+		result1 error
+	}
+	PopStub        func() (*queue.Lease, error)
 	popMutex       sync.RWMutex
 	popArgsForCall []struct {
 	}
 	popReturns struct {
-		result1 interface{}
+		result1 *queue.Lease
 		result2 error
 	}
 	popReturnsOnCall map[int]struct {
-		result1 interface{}
+		result1 *queue.Lease
+		result2 error
+	}
+	PopContextStub        func(context.Context) (*queue.Lease, error)
+	popContextMutex       sync.RWMutex
+	popContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	popContextReturns struct {
+		result1 *queue.Lease
+		result2 error
+	}
+	popContextReturnsOnCall map[int]struct {
+		result1 *queue.Lease
 		result2 error
 	}
 	PushStub        func(interface{}) error
@@ -31,24 +78,212 @@ type FakeQueue struct {
 	pushReturnsOnCall map[int]struct {
 		result1 error
 	}
+	PushDelayedStub        func(interface{}, time.Time) error
+	pushDelayedMutex       sync.RWMutex
+	pushDelayedArgsForCall []struct {
+		arg1 interface{}
+		arg2 time.Time
+	}
+	pushDelayedReturns struct {
+		result1 error
+	}
+	pushDelayedReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeQueue) Pop() (interface{}, error) {
+func (fake *FakeQueue) Ack(arg1 *queue.Lease) error {
+	fake.ackMutex.Lock()
+	ret, specificReturn := fake.ackReturnsOnCall[len(fake.ackArgsForCall)]
+	fake.ackArgsForCall = append(fake.ackArgsForCall, struct {
+		arg1 *queue.Lease
+	}{arg1})
+	stub := fake.AckStub
+	fakeReturns := fake.ackReturns
+	fake.recordInvocation("Ack", []interface{}{arg1})
+	fake.ackMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeQueue) AckCallCount() int {
+	fake.ackMutex.RLock()
+	defer fake.ackMutex.RUnlock()
+	return len(fake.ackArgsForCall)
+}
+
+func (fake *FakeQueue) AckCalls(stub func(*queue.Lease) error) {
+	fake.ackMutex.Lock()
+	defer fake.ackMutex.Unlock()
+	fake.AckStub = stub
+}
+
+func (fake *FakeQueue) AckArgsForCall(i int) *queue.Lease {
+	fake.ackMutex.RLock()
+	defer fake.ackMutex.RUnlock()
+	argsForCall := fake.ackArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeQueue) AckReturns(result1 error) {
+	fake.ackMutex.Lock()
+	defer fake.ackMutex.Unlock()
+	fake.AckStub = nil
+	fake.ackReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) AckReturnsOnCall(i int, result1 error) {
+	fake.ackMutex.Lock()
+	defer fake.ackMutex.Unlock()
+	fake.AckStub = nil
+	if fake.ackReturnsOnCall == nil {
+		fake.ackReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.ackReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) Close() error {
+	fake.closeMutex.Lock()
+	ret, specificReturn := fake.closeReturnsOnCall[len(fake.closeArgsForCall)]
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct {
+	}{})
+	stub := fake.CloseStub
+	fakeReturns := fake.closeReturns
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeQueue) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeQueue) CloseCalls(stub func() error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = stub
+}
+
+func (fake *FakeQueue) CloseReturns(result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) CloseReturnsOnCall(i int, result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	if fake.closeReturnsOnCall == nil {
+		fake.closeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) Nack(arg1 *queue.Lease) error {
+	fake.nackMutex.Lock()
+	ret, specificReturn := fake.nackReturnsOnCall[len(fake.nackArgsForCall)]
+	fake.nackArgsForCall = append(fake.nackArgsForCall, struct {
+		arg1 *queue.Lease
+	}{arg1})
+	stub := fake.NackStub
+	fakeReturns := fake.nackReturns
+	fake.recordInvocation("Nack", []interface{}{arg1})
+	fake.nackMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeQueue) NackCallCount() int {
+	fake.nackMutex.RLock()
+	defer fake.nackMutex.RUnlock()
+	return len(fake.nackArgsForCall)
+}
+
+func (fake *FakeQueue) NackCalls(stub func(*queue.Lease) error) {
+	fake.nackMutex.Lock()
+	defer fake.nackMutex.Unlock()
+	fake.NackStub = stub
+}
+
+func (fake *FakeQueue) NackArgsForCall(i int) *queue.Lease {
+	fake.nackMutex.RLock()
+	defer fake.nackMutex.RUnlock()
+	argsForCall := fake.nackArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeQueue) NackReturns(result1 error) {
+	fake.nackMutex.Lock()
+	defer fake.nackMutex.Unlock()
+	fake.NackStub = nil
+	fake.nackReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) NackReturnsOnCall(i int, result1 error) {
+	fake.nackMutex.Lock()
+	defer fake.nackMutex.Unlock()
+	fake.NackStub = nil
+	if fake.nackReturnsOnCall == nil {
+		fake.nackReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.nackReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) Pop() (*queue.Lease, error) {
 	fake.popMutex.Lock()
 	ret, specificReturn := fake.popReturnsOnCall[len(fake.popArgsForCall)]
 	fake.popArgsForCall = append(fake.popArgsForCall, struct {
 	}{})
+	stub := fake.PopStub
+	fakeReturns := fake.popReturns
 	fake.recordInvocation("Pop", []interface{}{})
 	fake.popMutex.Unlock()
-	if fake.PopStub != nil {
-		return fake.PopStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.popReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -58,34 +293,98 @@ func (fake *FakeQueue) PopCallCount() int {
 	return len(fake.popArgsForCall)
 }
 
-func (fake *FakeQueue) PopCalls(stub func() (interface{}, error)) {
+func (fake *FakeQueue) PopCalls(stub func() (*queue.Lease, error)) {
 	fake.popMutex.Lock()
 	defer fake.popMutex.Unlock()
 	fake.PopStub = stub
 }
 
-func (fake *FakeQueue) PopReturns(result1 interface{}, result2 error) {
+func (fake *FakeQueue) PopReturns(result1 *queue.Lease, result2 error) {
 	fake.popMutex.Lock()
 	defer fake.popMutex.Unlock()
 	fake.PopStub = nil
 	fake.popReturns = struct {
-		result1 interface{}
+		result1 *queue.Lease
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeQueue) PopReturnsOnCall(i int, result1 interface{}, result2 error) {
+func (fake *FakeQueue) PopReturnsOnCall(i int, result1 *queue.Lease, result2 error) {
 	fake.popMutex.Lock()
 	defer fake.popMutex.Unlock()
 	fake.PopStub = nil
 	if fake.popReturnsOnCall == nil {
 		fake.popReturnsOnCall = make(map[int]struct {
-			result1 interface{}
+			result1 *queue.Lease
 			result2 error
 		})
 	}
 	fake.popReturnsOnCall[i] = struct {
-		result1 interface{}
+		result1 *queue.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeQueue) PopContext(arg1 context.Context) (*queue.Lease, error) {
+	fake.popContextMutex.Lock()
+	ret, specificReturn := fake.popContextReturnsOnCall[len(fake.popContextArgsForCall)]
+	fake.popContextArgsForCall = append(fake.popContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.PopContextStub
+	fakeReturns := fake.popContextReturns
+	fake.recordInvocation("PopContext", []interface{}{arg1})
+	fake.popContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeQueue) PopContextCallCount() int {
+	fake.popContextMutex.RLock()
+	defer fake.popContextMutex.RUnlock()
+	return len(fake.popContextArgsForCall)
+}
+
+func (fake *FakeQueue) PopContextCalls(stub func(context.Context) (*queue.Lease, error)) {
+	fake.popContextMutex.Lock()
+	defer fake.popContextMutex.Unlock()
+	fake.PopContextStub = stub
+}
+
+func (fake *FakeQueue) PopContextArgsForCall(i int) context.Context {
+	fake.popContextMutex.RLock()
+	defer fake.popContextMutex.RUnlock()
+	argsForCall := fake.popContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeQueue) PopContextReturns(result1 *queue.Lease, result2 error) {
+	fake.popContextMutex.Lock()
+	defer fake.popContextMutex.Unlock()
+	fake.PopContextStub = nil
+	fake.popContextReturns = struct {
+		result1 *queue.Lease
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeQueue) PopContextReturnsOnCall(i int, result1 *queue.Lease, result2 error) {
+	fake.popContextMutex.Lock()
+	defer fake.popContextMutex.Unlock()
+	fake.PopContextStub = nil
+	if fake.popContextReturnsOnCall == nil {
+		fake.popContextReturnsOnCall = make(map[int]struct {
+			result1 *queue.Lease
+			result2 error
+		})
+	}
+	fake.popContextReturnsOnCall[i] = struct {
+		result1 *queue.Lease
 		result2 error
 	}{result1, result2}
 }
@@ -96,15 +395,16 @@ func (fake *FakeQueue) Push(arg1 interface{}) error {
 	fake.pushArgsForCall = append(fake.pushArgsForCall, struct {
 		arg1 interface{}
 	}{arg1})
+	stub := fake.PushStub
+	fakeReturns := fake.pushReturns
 	fake.recordInvocation("Push", []interface{}{arg1})
 	fake.pushMutex.Unlock()
-	if fake.PushStub != nil {
-		return fake.PushStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.pushReturns
 	return fakeReturns.result1
 }
 
@@ -150,13 +450,85 @@ func (fake *FakeQueue) PushReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeQueue) PushDelayed(arg1 interface{}, arg2 time.Time) error {
+	fake.pushDelayedMutex.Lock()
+	ret, specificReturn := fake.pushDelayedReturnsOnCall[len(fake.pushDelayedArgsForCall)]
+	fake.pushDelayedArgsForCall = append(fake.pushDelayedArgsForCall, struct {
+		arg1 interface{}
+		arg2 time.Time
+	}{arg1, arg2})
+	stub := fake.PushDelayedStub
+	fakeReturns := fake.pushDelayedReturns
+	fake.recordInvocation("PushDelayed", []interface{}{arg1, arg2})
+	fake.pushDelayedMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeQueue) PushDelayedCallCount() int {
+	fake.pushDelayedMutex.RLock()
+	defer fake.pushDelayedMutex.RUnlock()
+	return len(fake.pushDelayedArgsForCall)
+}
+
+func (fake *FakeQueue) PushDelayedCalls(stub func(interface{}, time.Time) error) {
+	fake.pushDelayedMutex.Lock()
+	defer fake.pushDelayedMutex.Unlock()
+	fake.PushDelayedStub = stub
+}
+
+func (fake *FakeQueue) PushDelayedArgsForCall(i int) (interface{}, time.Time) {
+	fake.pushDelayedMutex.RLock()
+	defer fake.pushDelayedMutex.RUnlock()
+	argsForCall := fake.pushDelayedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeQueue) PushDelayedReturns(result1 error) {
+	fake.pushDelayedMutex.Lock()
+	defer fake.pushDelayedMutex.Unlock()
+	fake.PushDelayedStub = nil
+	fake.pushDelayedReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeQueue) PushDelayedReturnsOnCall(i int, result1 error) {
+	fake.pushDelayedMutex.Lock()
+	defer fake.pushDelayedMutex.Unlock()
+	fake.PushDelayedStub = nil
+	if fake.pushDelayedReturnsOnCall == nil {
+		fake.pushDelayedReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.pushDelayedReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeQueue) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.ackMutex.RLock()
+	defer fake.ackMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	fake.nackMutex.RLock()
+	defer fake.nackMutex.RUnlock()
 	fake.popMutex.RLock()
 	defer fake.popMutex.RUnlock()
+	fake.popContextMutex.RLock()
+	defer fake.popContextMutex.RUnlock()
 	fake.pushMutex.RLock()
 	defer fake.pushMutex.RUnlock()
+	fake.pushDelayedMutex.RLock()
+	defer fake.pushDelayedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,98 @@
+package ranker
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+const (
+	// activeFor is how long after their last push repositories are ranked
+	// without a discount
+	activeFor = time.Hour * 24 * 365
+	// inactiveHalfLife is how long it takes for the score of a repository
+	// that is no longer active to halve
+	inactiveHalfLife = time.Hour * 24 * 180
+)
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Ranker
+
+// Ranker scores the candidate repositories of a user and returns them as
+// suggestion items, the best first
+type Ranker interface {
+	Rank(candidates []*model.SuggestionCandidate) []model.SuggestionItem
+}
+
+// New constructs the built-in ranker with the given name, halfLife is the
+// half-life of stars for the decayed ranker
+func New(name string, halfLife time.Duration) (Ranker, error) {
+	switch name {
+	case "decayed":
+		return NewDecayed(halfLife)
+	case "followees":
+		return NewFollowees()
+	case "popular":
+		return NewPopular()
+	case "weighted":
+		return NewWeighted()
+	default:
+		return nil, errors.New("unknown ranker " + name)
+	}
+}
+
+// rank sorts candidates by score, then name, and returns them as suggestion
+// items, reason formats the reason of each item
+// Scores of repositories that have not been pushed to in a while are
+// discounted
+func rank(
+	now time.Time,
+	candidates []*model.SuggestionCandidate,
+	score func(candidate *model.SuggestionCandidate) float64,
+	reason func(candidate *model.SuggestionCandidate) string,
+) []model.SuggestionItem {
+	scores := make(map[*model.SuggestionCandidate]float64, len(candidates))
+	sorted := make([]*model.SuggestionCandidate, len(candidates))
+	for i, candidate := range candidates {
+		scores[candidate] = score(candidate) * activity(now, candidate.Repository)
+		sorted[i] = candidate
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if scores[sorted[i]] != scores[sorted[j]] {
+			return scores[sorted[i]] > scores[sorted[j]]
+		}
+		return sorted[i].Repository.Name < sorted[j].Repository.Name
+	})
+
+	items := make([]model.SuggestionItem, len(sorted))
+	for i, candidate := range sorted {
+		items[i] = model.SuggestionItem{
+			Type:       "repository",
+			Value:      candidate.Repository.Name,
+			Reason:     reason(candidate),
+			Repository: candidate.Repository,
+		}
+	}
+
+	return items
+}
+
+// activity returns the factor the score of a repository is discounted by,
+// halving every inactiveHalfLife once it has not been pushed to for activeFor
+// Repositories without a push time are not discounted
+func activity(now time.Time, repository *model.Repository) float64 {
+	if repository.PushedAt == 0 {
+		return 1
+	}
+
+	inactive := now.Sub(time.Unix(repository.PushedAt, 0)) - activeFor
+	if inactive <= 0 {
+		return 1
+	}
+
+	return math.Pow(0.5, float64(inactive)/float64(inactiveHalfLife))
+}
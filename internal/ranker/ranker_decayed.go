@@ -0,0 +1,83 @@
+package ranker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// Decayed ranks repositories by the stars of the user's followees, where
+// every star weighs half as much after each half-life, and stars of followees
+// who share more stars with the user weigh more
+type Decayed struct {
+	halfLife time.Duration
+	now      func() time.Time
+}
+
+// NewDecayed constructs a new Decayed ranker given the half-life of stars
+func NewDecayed(halfLife time.Duration) (*Decayed, error) {
+	if halfLife <= 0 {
+		return nil, errors.New("half-life must be positive")
+	}
+
+	d := &Decayed{
+		halfLife: halfLife,
+		now:      time.Now,
+	}
+
+	return d, nil
+}
+
+// Rank sorts the candidates by the decayed sum of their followee stars
+func (d *Decayed) Rank(candidates []*model.SuggestionCandidate) []model.SuggestionItem {
+	now := d.now()
+	return rank(
+		now,
+		candidates,
+		func(candidate *model.SuggestionCandidate) float64 {
+			return d.score(now, candidate)
+		},
+		func(candidate *model.SuggestionCandidate) string {
+			return d.reason(now, candidate)
+		},
+	)
+}
+
+// score returns the sum of the weights of a candidate's stars, a star made
+// now by a followee who shares no stars with the user weighs 1
+func (d *Decayed) score(now time.Time, candidate *model.SuggestionCandidate) float64 {
+	score := 0.0
+	for _, star := range candidate.Stars {
+		age := now.Sub(time.Unix(star.StarredAt, 0))
+		if age < 0 {
+			age = 0
+		}
+		decay := math.Pow(0.5, float64(age)/float64(d.halfLife))
+		affinity := 1 + math.Log2(1+float64(star.Affinity))
+		score += decay * affinity
+	}
+
+	return score
+}
+
+// reason returns the followees reason along with how many of the stars were
+// made within the last half-life
+func (d *Decayed) reason(now time.Time, candidate *model.SuggestionCandidate) string {
+	recent := 0
+	for _, star := range candidate.Stars {
+		if now.Sub(time.Unix(star.StarredAt, 0)) < d.halfLife {
+			recent++
+		}
+	}
+
+	reason := followeesReason(candidate)
+	if recent > 0 && recent < len(candidate.Stars) {
+		reason += fmt.Sprintf(", %d of them recently", recent)
+	}
+
+	return reason
+}
@@ -0,0 +1,39 @@
+package ranker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// Followees ranks repositories by the stars of the user's followees
+type Followees struct {
+	now func() time.Time
+}
+
+// NewFollowees constructs a new Followees ranker
+func NewFollowees() (*Followees, error) {
+	f := &Followees{
+		now: time.Now,
+	}
+
+	return f, nil
+}
+
+// Rank sorts the candidates by followee stars
+func (f *Followees) Rank(candidates []*model.SuggestionCandidate) []model.SuggestionItem {
+	return rank(
+		f.now(),
+		candidates,
+		func(candidate *model.SuggestionCandidate) float64 {
+			return float64(candidate.FolloweeStars)
+		},
+		followeesReason,
+	)
+}
+
+// followeesReason returns the reason of a repository starred by followees
+func followeesReason(candidate *model.SuggestionCandidate) string {
+	return fmt.Sprintf("%d followers starred it", candidate.FolloweeStars)
+}
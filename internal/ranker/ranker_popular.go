@@ -0,0 +1,41 @@
+package ranker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// Popular ranks repositories starred by the user's followees by their global
+// stars
+type Popular struct {
+	now func() time.Time
+}
+
+// NewPopular constructs a new Popular ranker
+func NewPopular() (*Popular, error) {
+	p := &Popular{
+		now: time.Now,
+	}
+
+	return p, nil
+}
+
+// Rank sorts the candidates by global stars
+func (p *Popular) Rank(candidates []*model.SuggestionCandidate) []model.SuggestionItem {
+	return rank(
+		p.now(),
+		candidates,
+		func(candidate *model.SuggestionCandidate) float64 {
+			return float64(candidate.Repository.StargazersCount)
+		},
+		func(candidate *model.SuggestionCandidate) string {
+			return fmt.Sprintf(
+				"%s and %d people on GitHub did",
+				followeesReason(candidate),
+				candidate.Repository.StargazersCount,
+			)
+		},
+	)
+}
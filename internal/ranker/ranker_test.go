@@ -0,0 +1,245 @@
+package ranker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+func getValues(items []model.SuggestionItem) []string {
+	values := []string{}
+	for _, item := range items {
+		values = append(values, item.Value)
+	}
+	return values
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"decayed", "followees", "popular", "weighted"} {
+		r, err := New(name, time.Hour)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+	}
+
+	_, err := New("unknown", time.Hour)
+	require.Error(t, err)
+
+	_, err = New("decayed", 0)
+	require.Error(t, err)
+}
+
+func TestActivity(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	pushed := func(d time.Duration) *model.Repository {
+		return &model.Repository{PushedAt: now.Add(-d).Unix()}
+	}
+
+	// repositories without a push time and active ones are not discounted
+	require.Equal(t, 1.0, activity(now, &model.Repository{}))
+	require.Equal(t, 1.0, activity(now, pushed(time.Hour)))
+	require.Equal(t, 1.0, activity(now, pushed(activeFor)))
+
+	// inactive ones halve every half-life
+	require.InDelta(t, 0.5, activity(now, pushed(activeFor+inactiveHalfLife)), 0.0001)
+	require.InDelta(t, 0.25, activity(now, pushed(activeFor+inactiveHalfLife*2)), 0.0001)
+}
+
+func TestFollowees_Rank(t *testing.T) {
+	r, err := NewFollowees()
+	require.NoError(t, err)
+
+	items := r.Rank([]*model.SuggestionCandidate{
+		{Repository: &model.Repository{Name: "b"}, FolloweeStars: 2},
+		{Repository: &model.Repository{Name: "c"}, FolloweeStars: 3},
+		{Repository: &model.Repository{Name: "a"}, FolloweeStars: 2},
+	})
+	require.Equal(t, []string{"c", "a", "b"}, getValues(items))
+	require.Equal(t, "3 followers starred it", items[0].Reason)
+	require.Equal(t, "repository", items[0].Type)
+	require.Equal(t, &model.Repository{Name: "c"}, items[0].Repository)
+
+	require.Empty(t, r.Rank(nil))
+
+	// repositories that have not been pushed to in years fall behind
+	items = r.Rank([]*model.SuggestionCandidate{
+		{
+			Repository: &model.Repository{
+				Name:     "a",
+				PushedAt: time.Now().Add(-activeFor - inactiveHalfLife*2).Unix(),
+			},
+			FolloweeStars: 3,
+		},
+		{
+			Repository: &model.Repository{
+				Name:     "b",
+				PushedAt: time.Now().Unix(),
+			},
+			FolloweeStars: 2,
+		},
+	})
+	require.Equal(t, []string{"b", "a"}, getValues(items))
+}
+
+func TestPopular_Rank(t *testing.T) {
+	r, err := NewPopular()
+	require.NoError(t, err)
+
+	items := r.Rank([]*model.SuggestionCandidate{
+		{
+			Repository:    &model.Repository{Name: "a", StargazersCount: 10},
+			FolloweeStars: 3,
+		},
+		{
+			Repository:    &model.Repository{Name: "b", StargazersCount: 1000},
+			FolloweeStars: 1,
+		},
+	})
+	require.Equal(t, []string{"b", "a"}, getValues(items))
+	require.Equal(t, "1 followers starred it and 1000 people on GitHub did", items[0].Reason)
+}
+
+func TestWeighted_Rank(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("followee stars", func(t *testing.T) {
+		r, err := NewWeighted()
+		require.NoError(t, err)
+		r.now = func() time.Time { return now }
+
+		items := r.Rank([]*model.SuggestionCandidate{
+			{
+				Repository:    &model.Repository{Name: "a"},
+				FolloweeStars: 1,
+				LastStarredAt: now.Unix(),
+			},
+			{
+				Repository:    &model.Repository{Name: "b"},
+				FolloweeStars: 3,
+				LastStarredAt: now.Add(-time.Hour * 24 * 6).Unix(),
+			},
+		})
+		require.Equal(t, []string{"b", "a"}, getValues(items))
+	})
+
+	t.Run("features", func(t *testing.T) {
+		candidates := func() []*model.SuggestionCandidate {
+			return []*model.SuggestionCandidate{
+				{
+					Repository:    &model.Repository{Name: "old"},
+					LastStarredAt: now.Add(-time.Hour * 24 * 6).Unix(),
+				},
+				{
+					Repository:     &model.Repository{Name: "topics"},
+					MatchingTopics: 2,
+				},
+				{
+					Repository:        &model.Repository{Name: "languages"},
+					MatchingLanguages: 2,
+				},
+				{
+					Repository: &model.Repository{Name: "popular", StargazersCount: 999},
+				},
+			}
+		}
+
+		for _, test := range []struct {
+			weights  WeightedWeights
+			expected string
+		}{
+			{WeightedWeights{Recency: 1}, "old"},
+			{WeightedWeights{MatchingTopics: 1}, "topics"},
+			{WeightedWeights{MatchingLanguages: 1}, "languages"},
+			{WeightedWeights{GlobalStars: 1}, "popular"},
+		} {
+			r, err := NewWeightedWithWeights(test.weights)
+			require.NoError(t, err)
+			r.now = func() time.Time { return now }
+
+			items := r.Rank(candidates())
+			require.Equal(t, test.expected, items[0].Value)
+		}
+	})
+
+	t.Run("reason", func(t *testing.T) {
+		r, err := NewWeighted()
+		require.NoError(t, err)
+
+		items := r.Rank([]*model.SuggestionCandidate{
+			{
+				Repository:     &model.Repository{Name: "a"},
+				FolloweeStars:  2,
+				MatchingTopics: 1,
+			},
+		})
+		require.Equal(t, "2 followers starred it and it shares 1 topics with your stars", items[0].Reason)
+	})
+}
+
+func TestDecayed_Rank(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	week := time.Hour * 24 * 7
+	ago := func(d time.Duration) int64 {
+		return now.Add(-d).Unix()
+	}
+
+	r, err := NewDecayed(week)
+	require.NoError(t, err)
+	r.now = func() time.Time { return now }
+
+	t.Run("half-life", func(t *testing.T) {
+		candidate := &model.SuggestionCandidate{
+			Repository: &model.Repository{Name: "a"},
+			Stars: []model.CandidateStar{
+				{StarredAt: ago(0)},
+				{StarredAt: ago(week)},
+				{StarredAt: ago(week * 2)},
+			},
+		}
+		require.InDelta(t, 1+0.5+0.25, r.score(now, candidate), 0.0001)
+
+		// shared stars weigh more
+		candidate.Stars = []model.CandidateStar{
+			{StarredAt: ago(0), Affinity: 3},
+		}
+		require.InDelta(t, 3, r.score(now, candidate), 0.0001)
+	})
+
+	t.Run("momentum", func(t *testing.T) {
+		items := r.Rank([]*model.SuggestionCandidate{
+			{
+				// more stars, but months ago
+				Repository:    &model.Repository{Name: "old"},
+				FolloweeStars: 4,
+				Stars: []model.CandidateStar{
+					{StarredAt: ago(week * 8)},
+					{StarredAt: ago(week * 9)},
+					{StarredAt: ago(week * 10)},
+					{StarredAt: ago(week * 11)},
+				},
+			},
+			{
+				Repository:    &model.Repository{Name: "new"},
+				FolloweeStars: 2,
+				Stars: []model.CandidateStar{
+					{StarredAt: ago(time.Hour)},
+					{StarredAt: ago(week * 3)},
+				},
+			},
+			{
+				// starred by a followee with similar taste
+				Repository:    &model.Repository{Name: "close"},
+				FolloweeStars: 1,
+				Stars: []model.CandidateStar{
+					{StarredAt: ago(time.Hour), Affinity: 7},
+				},
+			},
+		})
+		require.Equal(t, []string{"close", "new", "old"}, getValues(items))
+		require.Equal(t, "1 followers starred it", items[0].Reason)
+		require.Equal(t, "2 followers starred it, 1 of them recently", items[1].Reason)
+		require.Equal(t, "4 followers starred it", items[2].Reason)
+	})
+}
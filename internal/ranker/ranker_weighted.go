@@ -0,0 +1,90 @@
+package ranker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// WeightedWeights are the weights of each feature of a candidate
+type WeightedWeights struct {
+	FolloweeStars float64
+	// Recency is the weight of 1 / (1 + days since a followee last starred it)
+	Recency           float64
+	MatchingTopics    float64
+	MatchingLanguages float64
+	// GlobalStars is the weight of log10(1 + global stars), so that very
+	// popular repositories do not drown everything else
+	GlobalStars float64
+}
+
+// Weighted ranks repositories by a weighted sum of their features
+type Weighted struct {
+	weights WeightedWeights
+	now     func() time.Time
+}
+
+// NewWeighted constructs a new Weighted ranker with the default weights
+func NewWeighted() (*Weighted, error) {
+	return NewWeightedWithWeights(WeightedWeights{
+		FolloweeStars:     1,
+		Recency:           1,
+		MatchingTopics:    0.5,
+		MatchingLanguages: 0.5,
+		GlobalStars:       0.25,
+	})
+}
+
+// NewWeightedWithWeights constructs a new Weighted ranker given the weight of
+// each feature
+func NewWeightedWithWeights(weights WeightedWeights) (*Weighted, error) {
+	w := &Weighted{
+		weights: weights,
+		now:     time.Now,
+	}
+
+	return w, nil
+}
+
+// Rank sorts the candidates by the weighted sum of their features
+func (w *Weighted) Rank(candidates []*model.SuggestionCandidate) []model.SuggestionItem {
+	now := w.now()
+	return rank(
+		now,
+		candidates,
+		func(candidate *model.SuggestionCandidate) float64 {
+			return w.score(now, candidate)
+		},
+		weightedReason,
+	)
+}
+
+// score returns the weighted sum of the features of a candidate
+func (w *Weighted) score(now time.Time, candidate *model.SuggestionCandidate) float64 {
+	days := now.Sub(time.Unix(candidate.LastStarredAt, 0)).Hours() / 24
+	if days < 0 {
+		days = 0
+	}
+
+	return w.weights.FolloweeStars*float64(candidate.FolloweeStars) +
+		w.weights.Recency/(1+days) +
+		w.weights.MatchingTopics*float64(candidate.MatchingTopics) +
+		w.weights.MatchingLanguages*float64(candidate.MatchingLanguages) +
+		w.weights.GlobalStars*math.Log10(1+float64(candidate.Repository.StargazersCount))
+}
+
+// weightedReason returns the followees reason along with the topics the
+// repository shares with the user's stars
+func weightedReason(candidate *model.SuggestionCandidate) string {
+	reason := followeesReason(candidate)
+	if candidate.MatchingTopics > 0 {
+		reason += fmt.Sprintf(
+			" and it shares %d topics with your stars",
+			candidate.MatchingTopics,
+		)
+	}
+
+	return reason
+}
@@ -0,0 +1,117 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package rankerfakes
+
+import (
+	"sync"
+
+	"github.com/kbariotis/go-discover/internal/model"
+	"github.com/kbariotis/go-discover/internal/ranker"
+)
+
+type FakeRanker struct {
+	RankStub        func([]*model.SuggestionCandidate) []model.SuggestionItem
+	rankMutex       sync.RWMutex
+	rankArgsForCall []struct {
+		arg1 []*model.SuggestionCandidate
+	}
+	rankReturns struct {
+		result1 []model.SuggestionItem
+	}
+	rankReturnsOnCall map[int]struct {
+		result1 []model.SuggestionItem
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRanker) Rank(arg1 []*model.SuggestionCandidate) []model.SuggestionItem {
+	var arg1Copy []*model.SuggestionCandidate
+	if arg1 != nil {
+		arg1Copy = make([]*model.SuggestionCandidate, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.rankMutex.Lock()
+	ret, specificReturn := fake.rankReturnsOnCall[len(fake.rankArgsForCall)]
+	fake.rankArgsForCall = append(fake.rankArgsForCall, struct {
+		arg1 []*model.SuggestionCandidate
+	}{arg1Copy})
+	stub := fake.RankStub
+	fakeReturns := fake.rankReturns
+	fake.recordInvocation("Rank", []interface{}{arg1Copy})
+	fake.rankMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRanker) RankCallCount() int {
+	fake.rankMutex.RLock()
+	defer fake.rankMutex.RUnlock()
+	return len(fake.rankArgsForCall)
+}
+
+func (fake *FakeRanker) RankCalls(stub func([]*model.SuggestionCandidate) []model.SuggestionItem) {
+	fake.rankMutex.Lock()
+	defer fake.rankMutex.Unlock()
+	fake.RankStub = stub
+}
+
+func (fake *FakeRanker) RankArgsForCall(i int) []*model.SuggestionCandidate {
+	fake.rankMutex.RLock()
+	defer fake.rankMutex.RUnlock()
+	argsForCall := fake.rankArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRanker) RankReturns(result1 []model.SuggestionItem) {
+	fake.rankMutex.Lock()
+	defer fake.rankMutex.Unlock()
+	fake.RankStub = nil
+	fake.rankReturns = struct {
+		result1 []model.SuggestionItem
+	}{result1}
+}
+
+func (fake *FakeRanker) RankReturnsOnCall(i int, result1 []model.SuggestionItem) {
+	fake.rankMutex.Lock()
+	defer fake.rankMutex.Unlock()
+	fake.RankStub = nil
+	if fake.rankReturnsOnCall == nil {
+		fake.rankReturnsOnCall = make(map[int]struct {
+			result1 []model.SuggestionItem
+		})
+	}
+	fake.rankReturnsOnCall[i] = struct {
+		result1 []model.SuggestionItem
+	}{result1}
+}
+
+func (fake *FakeRanker) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.rankMutex.RLock()
+	defer fake.rankMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRanker) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ ranker.Ranker = new(FakeRanker)
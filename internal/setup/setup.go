@@ -0,0 +1,210 @@
+package setup
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/Financial-Times/neoism"
+	"github.com/go-redis/redis"
+	"github.com/jinzhu/gorm"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/config"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/store"
+)
+
+// NewQueue constructs a queue of the configured type, redisClient is only
+// used by redis queues
+func NewQueue(
+	cfg *config.Config,
+	redisClient *redis.Client,
+	name string,
+	task interface{},
+) (queue.Queue, error) {
+	switch cfg.QueueType {
+	case "dque":
+		return queue.NewDQueue(
+			name,
+			cfg.QueueStoreDir,
+			task,
+			cfg.QueueVisibilityTimeout,
+		)
+	case "redis":
+		return queue.NewRedis(
+			redisClient,
+			name,
+			task,
+			cfg.QueueVisibilityTimeout,
+		)
+	default:
+		return nil, errors.New("unknown queue type " + cfg.QueueType)
+	}
+}
+
+// NewSuggestionStore constructs and sets up a suggestion store, users' tokens
+// are encrypted with the hex encoded TokenEncryptionKey
+func NewSuggestionStore(cfg *config.Config, db *gorm.DB) (*store.SuggestionSQL, error) {
+	tokenKey, err := hex.DecodeString(cfg.TokenEncryptionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode token encryption key")
+	}
+
+	suggestionStore, err := store.NewSuggestionSQL(db, tokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := suggestionStore.Setup(); err != nil {
+		return nil, errors.Wrap(err, "could not setup suggestion db")
+	}
+
+	return suggestionStore, nil
+}
+
+// NewGraphStore constructs and sets up a graph store of the configured type,
+// neo graph stores are migrated if migrate is set, or required to have been
+// migrated otherwise
+func NewGraphStore(
+	cfg *config.Config,
+	db *gorm.DB,
+	migrate bool,
+) (store.GraphStore, error) {
+	switch cfg.GraphStoreType {
+	case "neo":
+		neo, err := NewNeoGraphStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if migrate {
+			if err := neo.Migrate(); err != nil {
+				neo.Close()
+				return nil, errors.Wrap(err, "could not migrate graph")
+			}
+		} else if err := RequireMigrated(neo); err != nil {
+			neo.Close()
+			return nil, err
+		}
+
+		return neo, nil
+	case "memory":
+		return store.NewGraphMemory()
+	case "sql":
+		graphSQL, err := store.NewGraphSQL(db)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := graphSQL.Setup(); err != nil {
+			return nil, errors.Wrap(err, "could not setup graph tables")
+		}
+
+		return graphSQL, nil
+	default:
+		return nil, errors.New("unknown graph store type " + cfg.GraphStoreType)
+	}
+}
+
+// neoRetryInterval is how long to wait between attempts to reach neo
+var neoRetryInterval = time.Second
+
+// NewNeoGraphStore constructs a neo graph store using the configured driver,
+// waiting up to NeoStartTimeout for neo to accept queries so that commands
+// can be started alongside it
+func NewNeoGraphStore(cfg *config.Config) (store.MigratableGraphStore, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger": "setup/NewNeoGraphStore",
+		"driver": cfg.NeoDriver,
+	})
+
+	if cfg.NeoDriver != "rest" && cfg.NeoDriver != "bolt" {
+		return nil, errors.New("unknown neo driver " + cfg.NeoDriver)
+	}
+
+	var neo store.MigratableGraphStore
+	err := retry(logger, cfg.NeoStartTimeout, func() error {
+		var err error
+		neo, err = connectNeo(cfg)
+		if err != nil {
+			return err
+		}
+
+		// the migrations query is the first one every command runs
+		if _, err := neo.Migrations(); err != nil {
+			neo.Close()
+			return errors.Wrap(err, "could not query graph")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return neo, nil
+}
+
+// retry calls fn every neoRetryInterval until it succeeds or the timeout
+// elapses, returning its last error
+func retry(logger *logrus.Entry, timeout time.Duration, fn func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(neoRetryInterval).After(deadline) {
+			return err
+		}
+
+		logger.WithError(err).Info("waiting for neo to start")
+		time.Sleep(neoRetryInterval)
+	}
+}
+
+// connectNeo constructs a neo graph store using the configured driver
+func connectNeo(cfg *config.Config) (store.MigratableGraphStore, error) {
+	switch cfg.NeoDriver {
+	case "rest":
+		graphDB, err := neoism.Connect(cfg.NeoHost)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create neo client")
+		}
+
+		return store.NewNeo(graphDB, cfg.NeoBatchSize)
+	case "bolt":
+		driver, err := neo4j.NewDriverWithContext(
+			cfg.NeoBoltURI,
+			neo4j.BasicAuth(cfg.NeoUsername, cfg.NeoPassword, ""),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create neo driver")
+		}
+
+		return store.NewBolt(driver, cfg.NeoDatabase, cfg.NeoBatchSize)
+	default:
+		return nil, errors.New("unknown neo driver " + cfg.NeoDriver)
+	}
+}
+
+// RequireMigrated returns an error if the graph has pending migrations, they
+// are applied with the migrate command
+func RequireMigrated(migrator store.GraphMigrator) error {
+	pending, err := store.PendingGraphMigrations(migrator)
+	if err != nil {
+		return errors.Wrap(err, "could not check graph migrations")
+	}
+
+	if len(pending) > 0 {
+		return errors.Errorf(
+			"graph has %d pending migrations, run `migrate up` first",
+			len(pending),
+		)
+	}
+
+	return nil
+}
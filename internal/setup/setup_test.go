@@ -0,0 +1,168 @@
+package setup
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // required for sqlite
+
+	"github.com/kbariotis/go-discover/internal/config"
+	"github.com/kbariotis/go-discover/internal/queue"
+	"github.com/kbariotis/go-discover/internal/store"
+)
+
+type testTask struct {
+	Name string
+}
+
+func getDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "go-discover-setup")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestNewQueue(t *testing.T) {
+	q, err := NewQueue(&config.Config{
+		QueueType:     "dque",
+		QueueStoreDir: getDir(t),
+	}, nil, "test.queue", &testTask{})
+	require.NoError(t, err)
+	require.IsType(t, &queue.DQueue{}, q)
+
+	q, err = NewQueue(&config.Config{
+		QueueType: "redis",
+	}, nil, "test.queue", &testTask{})
+	require.NoError(t, err)
+	require.IsType(t, &queue.Redis{}, q)
+
+	_, err = NewQueue(&config.Config{
+		QueueType: "unknown",
+	}, nil, "test.queue", &testTask{})
+	require.Error(t, err)
+}
+
+func TestNewSuggestionStore(t *testing.T) {
+	db, err := gorm.Open("sqlite3", filepath.Join(getDir(t), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// suggestion stores are set up on construction
+	suggestionStore, err := NewSuggestionStore(&config.Config{
+		TokenEncryptionKey: "000102030405060708090a0b0c0d0e0f",
+	}, db)
+	require.NoError(t, err)
+	_, err = suggestionStore.GetAllUsers()
+	require.NoError(t, err)
+
+	_, err = NewSuggestionStore(&config.Config{
+		TokenEncryptionKey: "not hex",
+	}, db)
+	require.Error(t, err)
+}
+
+func TestNewGraphStore(t *testing.T) {
+	db, err := gorm.Open("sqlite3", filepath.Join(getDir(t), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// sql graph stores are set up on construction
+	graphStore, err := NewGraphStore(&config.Config{
+		GraphStoreType: "sql",
+	}, db, false)
+	require.NoError(t, err)
+	require.IsType(t, &store.GraphSQL{}, graphStore)
+	_, err = graphStore.GetUserRepositories("foo")
+	require.NoError(t, err)
+
+	graphStore, err = NewGraphStore(&config.Config{
+		GraphStoreType: "memory",
+	}, db, false)
+	require.NoError(t, err)
+	require.IsType(t, &store.GraphMemory{}, graphStore)
+
+	_, err = NewGraphStore(&config.Config{
+		GraphStoreType: "unknown",
+	}, db, false)
+	require.Error(t, err)
+
+	_, err = NewNeoGraphStore(&config.Config{
+		NeoDriver: "unknown",
+	})
+	require.Error(t, err)
+}
+
+func TestRetry(t *testing.T) {
+	defer func(interval time.Duration) {
+		neoRetryInterval = interval
+	}(neoRetryInterval)
+	neoRetryInterval = time.Millisecond
+
+	logger := logrus.WithFields(logrus.Fields{})
+
+	// retries until fn succeeds
+	calls := 0
+	require.NoError(t, retry(logger, time.Second, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	}))
+	require.Equal(t, 3, calls)
+
+	// returns the last error once the timeout elapses
+	calls = 0
+	err := retry(logger, time.Millisecond*20, func() error {
+		calls++
+		return errors.New("not ready")
+	})
+	require.EqualError(t, err, "not ready")
+	require.True(t, calls > 1)
+
+	// and tries only once without a timeout
+	calls = 0
+	require.Error(t, retry(logger, 0, func() error {
+		calls++
+		return errors.New("not ready")
+	}))
+	require.Equal(t, 1, calls)
+}
+
+// fakeMigrator is a GraphMigrator with the given migrations
+type fakeMigrator struct {
+	migrations []store.GraphMigration
+}
+
+func (m *fakeMigrator) Migrate() error {
+	return nil
+}
+
+func (m *fakeMigrator) Migrations() ([]store.GraphMigration, error) {
+	return m.migrations, nil
+}
+
+func (m *fakeMigrator) Close() error {
+	return nil
+}
+
+func TestRequireMigrated(t *testing.T) {
+	require.NoError(t, RequireMigrated(&fakeMigrator{
+		migrations: []store.GraphMigration{
+			{Version: 1, Applied: true},
+		},
+	}))
+
+	require.Error(t, RequireMigrated(&fakeMigrator{
+		migrations: []store.GraphMigration{
+			{Version: 1, Applied: true},
+			{Version: 2},
+		},
+	}))
+}
@@ -1,14 +1,50 @@
 package store
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/kbariotis/go-discover/internal/model"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . GraphStore
 
+// GraphSuggestionLimit is the max number of repositories suggested by a graph
+// store, more than are sent so that some can be filtered out
+const GraphSuggestionLimit = 20
+
+// GraphCandidateLimit is the max number of candidates returned by a graph
+// store for rankers to choose from
+const GraphCandidateLimit = 100
+
+// followReason returns the reason of a user suggestion, given the number of
+// followees that follow them and the topics they share with the user
+func followReason(followees int64, sharedTopics int64) string {
+	reason := fmt.Sprintf("followed by %d people you follow", followees)
+	if sharedTopics > 0 {
+		reason += fmt.Sprintf(", %d topics in common", sharedTopics)
+	}
+
+	return reason
+}
+
 // GraphStore defines the interface for the graph store implementations
 type GraphStore interface {
 	PutRepository(*model.Repository) error
 	PutUser(*model.User) error
-	GetUserSuggestion(user *model.User) (*model.Suggestion, error)
+	PutUserProfile(*model.UserProfile) error
+	// GetUserCandidates returns the repositories starred by the user's
+	// followees since the given time, the most starred first
+	GetUserCandidates(user *model.User, since time.Time) ([]*model.SuggestionCandidate, error)
+	// GetUserCoStarredSuggestion suggests repositories that are starred
+	// together with the ones the user starred
+	GetUserCoStarredSuggestion(user *model.User) (*model.Suggestion, error)
+	// GetUserFollowSuggestion suggests users followed by many of the user's
+	// followees, whom the user does not follow yet
+	GetUserFollowSuggestion(user *model.User) (*model.Suggestion, error)
+	// GetUserRepositories returns a user with the repositories they starred
+	// and own, their followees are not fetched
+	GetUserRepositories(name string) (*model.User, error)
+	// Close releases the store's connections
+	Close() error
 }
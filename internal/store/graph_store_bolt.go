@@ -0,0 +1,429 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// Bolt store implementation, talks to neo 4.4 and later over bolt using the
+// same queries as Neo
+type Bolt struct {
+	driver neo4j.DriverWithContext
+	// database is the neo database to use, empty for the default one
+	database string
+	// batchSize is the max number of rows written by a single statement
+	batchSize int
+}
+
+// NewBolt constructs a new Bolt store given a neo driver, the database to use
+// and the max number of rows written by a single statement
+func NewBolt(
+	driver neo4j.DriverWithContext,
+	database string,
+	batchSize int,
+) (*Bolt, error) {
+	if batchSize < 1 {
+		return nil, errors.New("batch size must be positive")
+	}
+
+	bolt := &Bolt{
+		driver:    driver,
+		database:  database,
+		batchSize: batchSize,
+	}
+
+	return bolt, nil
+}
+
+// Migrate applies the pending graph migrations
+func (bolt *Bolt) Migrate() error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger": "store/Bolt.Migrate",
+	})
+
+	logger.Info("migrating graph")
+
+	return neoMigrate(logger, bolt.batchSize, true, bolt.query)
+}
+
+// Migrations returns all graph migrations and whether they have been applied
+func (bolt *Bolt) Migrations() ([]GraphMigration, error) {
+	return neoMigrationsStatus(bolt.query)
+}
+
+// Close closes the driver's connections
+func (bolt *Bolt) Close() error {
+	return bolt.driver.Close(context.Background())
+}
+
+// PutRepository merges a repository's graph in neo
+func (bolt *Bolt) PutRepository(repository *model.Repository) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":                     "store/Bolt.PutRepository",
+		"repository.name":            repository.Name,
+		"repository.stars.count":     len(repository.Stars),
+		"repository.labels.count":    len(repository.Labels),
+		"repository.languages.count": len(repository.Languages),
+	})
+
+	logger.Info("saving repository")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	// edges not synced by this write are removed
+	parameters := neoSyncParameters(repository.Name, startTime)
+
+	// run queries
+	err := bolt.write(
+		logger,
+		neoPutRepositoryQuery,
+		neoRepositoryParameters(repository),
+		neoRepositoryBatches(repository, parameters),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not merge repo")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// PutUser merges a user's graph in neo
+func (bolt *Bolt) PutUser(user *model.User) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":               "store/Bolt.PutUser",
+		"user.name":            user.Name,
+		"user.followees.count": len(user.Followees),
+		"user.stars.count":     len(user.Stars),
+		"user.owns.count":      len(user.Owns),
+	})
+
+	logger.Info("saving user")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	// edges not synced by this write are removed
+	parameters := neoSyncParameters(user.Name, startTime)
+
+	// run queries
+	err := bolt.write(
+		logger,
+		neoPutUserQuery,
+		map[string]interface{}{
+			"name": user.Name,
+		},
+		neoUserBatches(user, parameters),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not merge user")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// PutUserProfile sets the profile properties of a user in neo
+func (bolt *Bolt) PutUserProfile(profile *model.UserProfile) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Bolt.PutUserProfile",
+		"user.name": profile.Name,
+	})
+
+	logger.Info("saving user profile")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	// run query
+	err := bolt.write(logger, neoPutUserProfileQuery, map[string]interface{}{
+		"name":        profile.Name,
+		"displayName": profile.DisplayName,
+		"bio":         profile.Bio,
+		"company":     profile.Company,
+		"location":    profile.Location,
+		"avatarURL":   profile.AvatarURL,
+		"followers":   profile.Followers,
+		"createdAt":   profile.CreatedAt,
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not set user profile")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// GetUserCandidates returns the repositories starred by the user's followees
+// since the given time
+func (bolt *Bolt) GetUserCandidates(
+	user *model.User,
+	since time.Time,
+) ([]*model.SuggestionCandidate, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Bolt.GetUserCandidates",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user candidates")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	rows, err := bolt.read(neoGetCandidateRepositories, map[string]interface{}{
+		"name":  user.Name,
+		"since": since.Unix(),
+		"limit": GraphCandidateLimit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not run cypher query")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return neoSuggestionCandidates(rows), nil
+}
+
+// GetUserCoStarredSuggestion suggests repositories that are starred together
+// with the ones the user starred
+func (bolt *Bolt) GetUserCoStarredSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Bolt.GetUserCoStarredSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user co-starred suggestion")
+
+	return bolt.suggestion(
+		logger,
+		user,
+		neoGetCoStarredRepositories,
+		map[string]interface{}{
+			"name":  user.Name,
+			"limit": GraphSuggestionLimit,
+		},
+		"%d co-stars with repositories you starred",
+	)
+}
+
+// GetUserFollowSuggestion suggests users followed by many of the user's
+// followees
+func (bolt *Bolt) GetUserFollowSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Bolt.GetUserFollowSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user follow suggestion")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	rows, err := bolt.read(neoGetFollowedByFollowees, map[string]interface{}{
+		"name":  user.Name,
+		"limit": GraphSuggestionLimit,
+	})
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not run cypher query")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    neoFollowSuggestionItems(rows),
+	}, nil
+}
+
+// GetUserRepositories returns a user with the repositories they starred and
+// own
+func (bolt *Bolt) GetUserRepositories(name string) (*model.User, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Bolt.GetUserRepositories",
+		"user.name": name,
+	})
+
+	logger.Info("get user repositories")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	user, err := neoUserRepositories(name, bolt.query)
+	if err != nil {
+		return nil, err
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return user, nil
+}
+
+// suggestion runs a suggestion query, reasonFormat formats the score of each
+// suggested repository
+func (bolt *Bolt) suggestion(
+	logger *logrus.Entry,
+	user *model.User,
+	statement string,
+	parameters map[string]interface{},
+	reasonFormat string,
+) (*model.Suggestion, error) {
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	// run query
+	rows, err := bolt.read(statement, parameters)
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not run cypher query")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    neoSuggestionItems(rows, reasonFormat),
+	}, nil
+}
+
+// session opens a session on the configured database
+func (bolt *Bolt) session(
+	ctx context.Context,
+	accessMode neo4j.AccessMode,
+) neo4j.SessionWithContext {
+	return bolt.driver.NewSession(ctx, neo4j.SessionConfig{
+		AccessMode:   accessMode,
+		DatabaseName: bolt.database,
+	})
+}
+
+// write runs a query followed by the batches of each given statement in a
+// single transaction
+func (bolt *Bolt) write(
+	logger *logrus.Entry,
+	statement string,
+	parameters map[string]interface{},
+	batches []neoBatch,
+) error {
+	ctx := context.Background()
+	session := bolt.session(ctx, neo4j.AccessModeWrite)
+	defer session.Close(ctx)
+
+	tx, err := session.BeginTransaction(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not begin transaction")
+	}
+	// rolls back unless committed
+	defer tx.Close(ctx)
+
+	run := func(statement string, parameters map[string]interface{}) error {
+		return boltRun(ctx, tx, statement, parameters)
+	}
+
+	if err := run(statement, parameters); err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		if err := neoWriteBatch(logger, bolt.batchSize, run, batch); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// query runs a statement in its own transaction and returns its rows
+func (bolt *Bolt) query(
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error) {
+	return bolt.run(neo4j.AccessModeWrite, statement, parameters)
+}
+
+// read runs a read only statement and returns its rows
+func (bolt *Bolt) read(
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error) {
+	return bolt.run(neo4j.AccessModeRead, statement, parameters)
+}
+
+// run runs a statement in its own transaction and returns its rows
+func (bolt *Bolt) run(
+	accessMode neo4j.AccessMode,
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error) {
+	ctx := context.Background()
+	session := bolt.session(ctx, accessMode)
+	defer session.Close(ctx)
+
+	res, err := session.Run(ctx, statement, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := res.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = record.AsMap()
+	}
+
+	return rows, nil
+}
+
+// boltRun runs a statement and waits for it to finish
+func boltRun(
+	ctx context.Context,
+	tx neo4j.ExplicitTransaction,
+	statement string,
+	parameters map[string]interface{},
+) error {
+	res, err := tx.Run(ctx, statement, parameters)
+	if err != nil {
+		return err
+	}
+
+	_, err = res.Consume(ctx)
+	return err
+}
@@ -0,0 +1,575 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// graphMemoryStar is a HasStarred edge, a user can star a repository more than
+// once at different times
+type graphMemoryStar struct {
+	user       string
+	repository string
+	starredAt  int64
+}
+
+// graphMemoryRemovedEdge is an edge that was removed, kept for history
+type graphMemoryRemovedEdge struct {
+	from      string
+	to        string
+	starredAt int64
+	removedAt int64
+}
+
+// graphMemoryRepository is a repository node, details holds its properties
+// without any of its edges
+type graphMemoryRepository struct {
+	details   model.Repository
+	labels    map[string]bool
+	languages map[string]model.RepositoryLanguage
+}
+
+// GraphMemory is an in-memory GraphStore with the same semantics as Neo,
+// meant for tests and for running everything in a single process
+type GraphMemory struct {
+	mutex sync.RWMutex
+
+	users        map[string]*model.UserProfile
+	repositories map[string]*graphMemoryRepository
+
+	following map[string]map[string]bool
+	stars     map[graphMemoryStar]bool
+	owns      map[string]map[string]model.OwnedRepository
+
+	wasFollowing []graphMemoryRemovedEdge
+	hadStarred   []graphMemoryRemovedEdge
+}
+
+// NewGraphMemory constructs a new in-memory GraphStore
+func NewGraphMemory() (*GraphMemory, error) {
+	mem := &GraphMemory{
+		users:        map[string]*model.UserProfile{},
+		repositories: map[string]*graphMemoryRepository{},
+		following:    map[string]map[string]bool{},
+		stars:        map[graphMemoryStar]bool{},
+		owns:         map[string]map[string]model.OwnedRepository{},
+	}
+
+	return mem, nil
+}
+
+// Close does nothing, the graph is kept until the process exits
+func (mem *GraphMemory) Close() error {
+	return nil
+}
+
+// PutRepository merges a repository's graph
+func (mem *GraphMemory) PutRepository(repository *model.Repository) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":                     "store/GraphMemory.PutRepository",
+		"repository.name":            repository.Name,
+		"repository.stars.count":     len(repository.Stars),
+		"repository.labels.count":    len(repository.Labels),
+		"repository.languages.count": len(repository.Languages),
+	})
+
+	logger.Info("saving repository")
+
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	r := mem.mergeRepository(repository.Name)
+	r.details = *repository
+	r.details.Labels = nil
+	r.details.Stars = nil
+	r.details.Languages = nil
+	for _, label := range repository.Labels {
+		r.labels[label] = true
+	}
+
+	// nil languages were not fetched, otherwise they replace the current ones
+	if repository.Languages != nil {
+		r.languages = map[string]model.RepositoryLanguage{}
+		for _, language := range repository.Languages {
+			r.languages[language.Name] = language
+		}
+	}
+
+	// nil stars were not fetched, so there is nothing to reconcile
+	if repository.Stars == nil {
+		return nil
+	}
+
+	synced := map[graphMemoryStar]bool{}
+	for _, star := range repository.Stars {
+		mem.mergeUser(star.User)
+		synced[graphMemoryStar{
+			user:       star.User,
+			repository: repository.Name,
+			starredAt:  star.StarredAt,
+		}] = true
+	}
+
+	mem.reconcileStars(synced, func(star graphMemoryStar) bool {
+		return star.repository == repository.Name
+	})
+
+	return nil
+}
+
+// PutUser merges a user's graph
+func (mem *GraphMemory) PutUser(user *model.User) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":               "store/GraphMemory.PutUser",
+		"user.name":            user.Name,
+		"user.followees.count": len(user.Followees),
+		"user.stars.count":     len(user.Stars),
+		"user.owns.count":      len(user.Owns),
+	})
+
+	logger.Info("saving user")
+
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	mem.mergeUser(user.Name)
+	removedAt := time.Now().Unix()
+
+	// nil lists were not fetched, so there is nothing to reconcile
+	if user.Followees != nil {
+		synced := map[string]bool{}
+		for _, followee := range user.Followees {
+			mem.mergeUser(followee)
+			synced[followee] = true
+		}
+
+		for followee := range mem.following[user.Name] {
+			if synced[followee] {
+				continue
+			}
+			mem.wasFollowing = append(mem.wasFollowing, graphMemoryRemovedEdge{
+				from:      user.Name,
+				to:        followee,
+				removedAt: removedAt,
+			})
+		}
+
+		mem.following[user.Name] = synced
+	}
+
+	if user.Stars != nil {
+		synced := map[graphMemoryStar]bool{}
+		for _, star := range user.Stars {
+			mem.mergeRepository(star.Repository)
+			synced[graphMemoryStar{
+				user:       user.Name,
+				repository: star.Repository,
+				starredAt:  star.StarredAt,
+			}] = true
+		}
+
+		mem.reconcileStars(synced, func(star graphMemoryStar) bool {
+			return star.user == user.Name
+		})
+	}
+
+	if _, ok := mem.owns[user.Name]; !ok {
+		mem.owns[user.Name] = map[string]model.OwnedRepository{}
+	}
+	for _, owned := range user.Owns {
+		mem.mergeRepository(owned.Repository)
+		mem.owns[user.Name][owned.Repository] = owned
+	}
+
+	return nil
+}
+
+// PutUserProfile sets the profile properties of a user
+func (mem *GraphMemory) PutUserProfile(profile *model.UserProfile) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphMemory.PutUserProfile",
+		"user.name": profile.Name,
+	})
+
+	logger.Info("saving user profile")
+
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	p := *profile
+	mem.users[profile.Name] = &p
+
+	return nil
+}
+
+// GetUserCandidates returns the repositories starred by the user's followees
+// since the given time, the most starred first
+func (mem *GraphMemory) GetUserCandidates(
+	user *model.User,
+	since time.Time,
+) ([]*model.SuggestionCandidate, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphMemory.GetUserCandidates",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user candidates")
+
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	// the affinity of a followee is the number of repositories both they and
+	// the user starred
+	starred := map[string]map[string]bool{}
+	for star := range mem.stars {
+		if _, ok := starred[star.user]; !ok {
+			starred[star.user] = map[string]bool{}
+		}
+		starred[star.user][star.repository] = true
+	}
+	affinity := map[string]int64{}
+	for followee := range mem.following[user.Name] {
+		for repository := range starred[followee] {
+			if starred[user.Name][repository] {
+				affinity[followee]++
+			}
+		}
+	}
+
+	// count stars, not followees, like the cypher query does
+	candidates := map[string]*model.SuggestionCandidate{}
+	followees := mem.following[user.Name]
+	for star := range mem.stars {
+		if !followees[star.user] || star.starredAt <= since.Unix() {
+			continue
+		}
+		candidate, ok := candidates[star.repository]
+		if !ok {
+			details := mem.repositories[star.repository].details
+			details.Name = star.repository
+			candidate = &model.SuggestionCandidate{
+				Repository: &details,
+			}
+			candidates[star.repository] = candidate
+		}
+		candidate.FolloweeStars++
+		candidate.Stars = append(candidate.Stars, model.CandidateStar{
+			Followee:  star.user,
+			StarredAt: star.starredAt,
+			Affinity:  affinity[star.user],
+		})
+		if star.starredAt > candidate.LastStarredAt {
+			candidate.LastStarredAt = star.starredAt
+		}
+	}
+
+	res := make([]*model.SuggestionCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		res = append(res, candidate)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].FolloweeStars != res[j].FolloweeStars {
+			return res[i].FolloweeStars > res[j].FolloweeStars
+		}
+		return res[i].Repository.Name < res[j].Repository.Name
+	})
+
+	if len(res) > GraphCandidateLimit {
+		res = res[:GraphCandidateLimit]
+	}
+
+	// topics and languages of the repositories the user starred
+	topics := mem.starredTopics(user.Name)
+	languages := map[string]bool{}
+	for star := range mem.stars {
+		if star.user != user.Name {
+			continue
+		}
+		for language := range mem.repositories[star.repository].languages {
+			languages[language] = true
+		}
+	}
+
+	for _, candidate := range res {
+		repository := mem.repositories[candidate.Repository.Name]
+		for label := range repository.labels {
+			if topics[label] {
+				candidate.MatchingTopics++
+			}
+		}
+		for language := range repository.languages {
+			if languages[language] {
+				candidate.MatchingLanguages++
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// GetUserCoStarredSuggestion returns the repositories starred most by users
+// who also starred one of the user's stars
+func (mem *GraphMemory) GetUserCoStarredSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphMemory.GetUserCoStarredSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user co-starred suggestion")
+
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	// users may have starred a repository more than once
+	starredBy := map[string]map[string]bool{}
+	starredOf := map[string]map[string]bool{}
+	for star := range mem.stars {
+		if _, ok := starredBy[star.repository]; !ok {
+			starredBy[star.repository] = map[string]bool{}
+		}
+		if _, ok := starredOf[star.user]; !ok {
+			starredOf[star.user] = map[string]bool{}
+		}
+		starredBy[star.repository][star.user] = true
+		starredOf[star.user][star.repository] = true
+	}
+
+	// count each repository once per seed and user that co-starred it
+	counts := map[string]int{}
+	for seed := range starredOf[user.Name] {
+		for other := range starredBy[seed] {
+			if other == user.Name {
+				continue
+			}
+			for repository := range starredOf[other] {
+				if starredOf[user.Name][repository] {
+					continue
+				}
+				if _, ok := mem.owns[user.Name][repository]; ok {
+					continue
+				}
+				counts[repository]++
+			}
+		}
+	}
+
+	return mem.suggestion(user, counts, "%d co-stars with repositories you starred"), nil
+}
+
+// GetUserFollowSuggestion returns the users followed by most of the user's
+// followees, whom the user does not follow yet
+func (mem *GraphMemory) GetUserFollowSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphMemory.GetUserFollowSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user follow suggestion")
+
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	following := mem.following[user.Name]
+	counts := map[string]int64{}
+	for followee := range following {
+		for other := range mem.following[followee] {
+			if other == user.Name || following[other] {
+				continue
+			}
+			counts[other]++
+		}
+	}
+
+	users := make([]string, 0, len(counts))
+	for other := range counts {
+		users = append(users, other)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if counts[users[i]] != counts[users[j]] {
+			return counts[users[i]] > counts[users[j]]
+		}
+		return users[i] < users[j]
+	})
+
+	if len(users) > GraphSuggestionLimit {
+		users = users[:GraphSuggestionLimit]
+	}
+
+	topics := mem.starredTopics(user.Name)
+
+	suggestions := make([]model.SuggestionItem, len(users))
+	for k, other := range users {
+		shared := int64(0)
+		for topic := range mem.starredTopics(other) {
+			if topics[topic] {
+				shared++
+			}
+		}
+
+		profile := model.UserProfile{
+			Name: other,
+		}
+		if p, ok := mem.users[other]; ok {
+			profile = *p
+		}
+
+		suggestions[k] = model.SuggestionItem{
+			Type:   model.SuggestionTypeFollowUser,
+			Value:  other,
+			Reason: followReason(counts[other], shared),
+			User:   &profile,
+		}
+	}
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    suggestions,
+	}, nil
+}
+
+// starredTopics returns the topics of the repositories a user starred
+func (mem *GraphMemory) starredTopics(name string) map[string]bool {
+	topics := map[string]bool{}
+	for star := range mem.stars {
+		if star.user != name {
+			continue
+		}
+		for label := range mem.repositories[star.repository].labels {
+			topics[label] = true
+		}
+	}
+
+	return topics
+}
+
+// suggestion returns the top repositories by count, reasonFormat formats
+// the count of each suggested repository
+func (mem *GraphMemory) suggestion(
+	user *model.User,
+	counts map[string]int,
+	reasonFormat string,
+) *model.Suggestion {
+	repositories := make([]string, 0, len(counts))
+	for repository := range counts {
+		repositories = append(repositories, repository)
+	}
+
+	sort.Slice(repositories, func(i, j int) bool {
+		if counts[repositories[i]] != counts[repositories[j]] {
+			return counts[repositories[i]] > counts[repositories[j]]
+		}
+		return repositories[i] < repositories[j]
+	})
+
+	if len(repositories) > GraphSuggestionLimit {
+		repositories = repositories[:GraphSuggestionLimit]
+	}
+
+	suggestions := make([]model.SuggestionItem, len(repositories))
+	for k, repository := range repositories {
+		details := mem.repositories[repository].details
+		details.Name = repository
+		suggestions[k] = model.SuggestionItem{
+			Type:       "repository",
+			Value:      repository,
+			Reason:     fmt.Sprintf(reasonFormat, counts[repository]),
+			Repository: &details,
+		}
+	}
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    suggestions,
+	}
+}
+
+// GetUserRepositories returns a user with the repositories they starred and
+// own
+func (mem *GraphMemory) GetUserRepositories(name string) (*model.User, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphMemory.GetUserRepositories",
+		"user.name": name,
+	})
+
+	logger.Info("get user repositories")
+
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	user := &model.User{
+		Name:  name,
+		Stars: []model.StarredRepository{},
+		Owns:  []model.OwnedRepository{},
+	}
+	for star := range mem.stars {
+		if star.user == name {
+			user.Stars = append(user.Stars, model.StarredRepository{
+				Repository: star.repository,
+				StarredAt:  star.starredAt,
+			})
+		}
+	}
+	for _, owned := range mem.owns[name] {
+		user.Owns = append(user.Owns, owned)
+	}
+
+	return user, nil
+}
+
+// mergeUser creates a user node if it does not exist
+func (mem *GraphMemory) mergeUser(name string) {
+	if _, ok := mem.users[name]; !ok {
+		mem.users[name] = &model.UserProfile{
+			Name: name,
+		}
+	}
+}
+
+// mergeRepository creates a repository node if it does not exist
+func (mem *GraphMemory) mergeRepository(name string) *graphMemoryRepository {
+	r, ok := mem.repositories[name]
+	if !ok {
+		r = &graphMemoryRepository{
+			labels:    map[string]bool{},
+			languages: map[string]model.RepositoryLanguage{},
+		}
+		mem.repositories[name] = r
+	}
+
+	return r
+}
+
+// reconcileStars replaces the stars matching the filter with the synced ones,
+// keeping the removed ones as history
+func (mem *GraphMemory) reconcileStars(
+	synced map[graphMemoryStar]bool,
+	filter func(graphMemoryStar) bool,
+) {
+	removedAt := time.Now().Unix()
+	for star := range mem.stars {
+		if !filter(star) || synced[star] {
+			continue
+		}
+		delete(mem.stars, star)
+		mem.hadStarred = append(mem.hadStarred, graphMemoryRemovedEdge{
+			from:      star.user,
+			to:        star.repository,
+			starredAt: star.starredAt,
+			removedAt: removedAt,
+		})
+	}
+
+	for star := range synced {
+		mem.stars[star] = true
+	}
+}
@@ -1,11 +1,7 @@
 package store
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"strings"
-	"text/template"
 	"time"
 
 	"github.com/Financial-Times/neoism"
@@ -18,110 +14,696 @@ import (
 // Neo store implementation
 type Neo struct {
 	db *neoism.Database
+	// batchSize is the max number of rows written by a single statement
+	batchSize int
 }
 
 const (
-	neoPutRepositoryQueryTemplate = `
-		MERGE (r:Repository {name: "{{ .Name }}"})
-		WITH r
-		FOREACH (label IN {{ toStrings .Labels }} |
-			MERGE (l:Label {name: label})
-			MERGE (r)-[:ContainsTopic]->(l)
-		)
-		WITH r
-		FOREACH (language IN {{ toStrings .Languages }} |
-			MERGE (l:Label {name: language})
-			MERGE (r)-[:ContainsLanguage]->(l)
-		)
-		WITH r
-		FOREACH (star IN {{ toObject .Stars }} |
-			MERGE (u:User {name: star.user})
-			MERGE (u)-[:HasStarred {starredAt: star.starredAt}]->(r)
-		)
+	neoPutRepositoryQuery = `
+		MERGE (r:Repository {name: $name})
+		SET
+			r.description = $description,
+			r.homepage = $homepage,
+			r.stargazersCount = $stargazersCount,
+			r.forksCount = $forksCount,
+			r.license = $license,
+			r.archived = $archived,
+			r.fork = $fork,
+			r.createdAt = $createdAt,
+			r.pushedAt = $pushedAt
 	`
-	// TODO stars should also be
-	neoPutUserQueryTemplate = `
-		MERGE (u:User {name: "{{ .Name }}"})
-		WITH u
-		FOREACH (followee IN {{ toStrings .Followees}} |
-			MERGE (f:User {name: followee})
-			MERGE (u)-[:IsFollowing]->(f)
-		)
-		WITH u
-		FOREACH (star IN {{ toObject .Stars }} |
-			MERGE (r:Repository {name: star.repository})
-			MERGE (u)-[:HasStarred {starredAt: star.starredAt}]->(r)
-		)
+	neoPutRepositoryLabelsQuery = `
+		MATCH (r:Repository {name: $name})
+		UNWIND $rows AS label
+		MERGE (l:Label {name: label})
+		MERGE (r)-[:ContainsTopic]->(l)
 	`
-	// TODO add dates between starredAt
-	neoGetTopStarredRepositories = `
-		MATCH (user:User)-[:IsFollowing]->(:User)-[starred:HasStarred]->(repository:Repository)
-		WHERE user.name = "{{ .Name }}" AND starred.starredAt > {{ .Timestamp }}
-		RETURN count(starred) as noOfFollowees, repository.name
-		ORDER BY noOfFollowees DESC
-		LIMIT 5
+	neoPutRepositoryLanguagesQuery = `
+		MATCH (r:Repository {name: $name})
+		UNWIND $rows AS language
+		MERGE (l:Language {name: language.name})
+		MERGE (r)-[w:WrittenIn]->(l)
+		SET w.bytes = language.bytes, w.share = language.share, w.syncedAt = $syncedAt
+	`
+	neoRemoveRepositoryLanguagesQuery = `
+		MATCH (r:Repository {name: $name})-[w:WrittenIn]->(:Language)
+		WHERE coalesce(w.syncedAt, 0) <> $syncedAt
+		DELETE w
+	`
+	neoPutRepositoryStarsQuery = `
+		MATCH (r:Repository {name: $name})
+		UNWIND $rows AS star
+		MERGE (u:User {name: star.user})
+		MERGE (u)-[s:HasStarred {starredAt: star.starredAt}]->(r)
+		SET s.syncedAt = $syncedAt
+	`
+	neoRemoveRepositoryStarsQuery = `
+		MATCH (u:User)-[s:HasStarred]->(r:Repository {name: $name})
+		WHERE coalesce(s.syncedAt, 0) <> $syncedAt
+		CREATE (u)-[:HadStarred {starredAt: s.starredAt, removedAt: $removedAt}]->(r)
+		DELETE s
+	`
+	neoPutUserQuery = `
+		MERGE (u:User {name: $name})
+	`
+	neoPutUserFolloweesQuery = `
+		MATCH (u:User {name: $name})
+		UNWIND $rows AS followee
+		MERGE (f:User {name: followee})
+		MERGE (u)-[i:IsFollowing]->(f)
+		SET i.syncedAt = $syncedAt
+	`
+	neoRemoveUserFolloweesQuery = `
+		MATCH (u:User {name: $name})-[i:IsFollowing]->(f:User)
+		WHERE coalesce(i.syncedAt, 0) <> $syncedAt
+		CREATE (u)-[:WasFollowing {removedAt: $removedAt}]->(f)
+		DELETE i
+	`
+	neoPutUserStarsQuery = `
+		MATCH (u:User {name: $name})
+		UNWIND $rows AS star
+		MERGE (r:Repository {name: star.repository})
+		MERGE (u)-[s:HasStarred {starredAt: star.starredAt}]->(r)
+		SET s.syncedAt = $syncedAt
+	`
+	neoRemoveUserStarsQuery = `
+		MATCH (u:User {name: $name})-[s:HasStarred]->(r:Repository)
+		WHERE coalesce(s.syncedAt, 0) <> $syncedAt
+		CREATE (u)-[:HadStarred {starredAt: s.starredAt, removedAt: $removedAt}]->(r)
+		DELETE s
+	`
+	neoPutUserOwnsQuery = `
+		MATCH (u:User {name: $name})
+		UNWIND $rows AS owned
+		MERGE (r:Repository {name: owned.repository})
+		MERGE (u)-[o:Owns]->(r)
+		SET o.createdAt = owned.createdAt, o.fork = owned.fork
+	`
+	neoPutUserProfileQuery = `
+		MERGE (u:User {name: $name})
+		SET
+			u.displayName = $displayName,
+			u.bio = $bio,
+			u.company = $company,
+			u.location = $location,
+			u.avatarURL = $avatarURL,
+			u.followers = $followers,
+			u.createdAt = $createdAt
+	`
+	// neoGetCandidateRepositories returns the repositories most starred by
+	// the user's followees, with the topics and languages they share with
+	// the user's stars
+	// The affinity of a followee is the number of repositories both they and
+	// the user starred
+	neoGetCandidateRepositories = `
+		MATCH (user:User {name: $name})-[:IsFollowing]->(followee:User)
+		OPTIONAL MATCH (user)-[:HasStarred]->(shared:Repository)<-[:HasStarred]-(followee)
+		WITH user, followee, count(DISTINCT shared) AS affinity
+		MATCH (followee)-[starred:HasStarred]->(repository:Repository)
+		WHERE starred.starredAt > $since
+		WITH
+			user,
+			repository,
+			count(starred) AS followeeStars,
+			max(starred.starredAt) AS lastStarredAt,
+			collect({followee: followee.name, starredAt: starred.starredAt, affinity: affinity}) AS stars
+		ORDER BY followeeStars DESC, repository.name
+		LIMIT $limit
+		OPTIONAL MATCH (repository)-[:ContainsTopic]->(topic:Label)<-[:ContainsTopic]-(:Repository)<-[:HasStarred]-(user)
+		WITH user, repository, followeeStars, lastStarredAt, stars, count(DISTINCT topic) AS matchingTopics
+		OPTIONAL MATCH (repository)-[:WrittenIn]->(language:Language)<-[:WrittenIn]-(:Repository)<-[:HasStarred]-(user)
+		WITH repository, followeeStars, lastStarredAt, stars, matchingTopics, count(DISTINCT language) AS matchingLanguages
+		RETURN
+			followeeStars,
+			lastStarredAt,
+			stars,
+			matchingTopics,
+			matchingLanguages,
+			repository.name,
+			repository.description AS description,
+			repository.homepage AS homepage,
+			repository.stargazersCount AS stargazersCount,
+			repository.forksCount AS forksCount,
+			repository.license AS license,
+			repository.archived AS archived,
+			repository.fork AS fork,
+			repository.createdAt AS createdAt,
+			repository.pushedAt AS pushedAt
+		ORDER BY followeeStars DESC, repository.name
+	`
+	// neoGetCoStarredRepositories scores repositories by how many times they
+	// were starred by someone who also starred one of the user's stars
+	neoGetCoStarredRepositories = `
+		MATCH (user:User {name: $name})-[:HasStarred]->(seed:Repository)<-[:HasStarred]-(other:User)-[:HasStarred]->(repository:Repository)
+		WHERE other <> user
+			AND NOT (user)-[:HasStarred]->(repository)
+			AND NOT (user)-[:Owns]->(repository)
+		WITH DISTINCT seed, other, repository
+		RETURN
+			count(*) AS score,
+			repository.name,
+			repository.description AS description,
+			repository.homepage AS homepage,
+			repository.stargazersCount AS stargazersCount,
+			repository.forksCount AS forksCount,
+			repository.license AS license,
+			repository.archived AS archived,
+			repository.fork AS fork,
+			repository.createdAt AS createdAt,
+			repository.pushedAt AS pushedAt
+		ORDER BY score DESC, repository.name
+		LIMIT $limit
+	`
+	// neoGetFollowedByFollowees returns the users followed by most of the
+	// user's followees, with the topics of their stars shared with the user's
+	neoGetFollowedByFollowees = `
+		MATCH (user:User {name: $name})-[:IsFollowing]->(followee:User)-[:IsFollowing]->(other:User)
+		WHERE other <> user
+			AND NOT (user)-[:IsFollowing]->(other)
+		WITH user, other, count(DISTINCT followee) AS score
+		ORDER BY score DESC, other.name
+		LIMIT $limit
+		OPTIONAL MATCH (user)-[:HasStarred]->(:Repository)-[:ContainsTopic]->(topic:Label)<-[:ContainsTopic]-(:Repository)<-[:HasStarred]-(other)
+		WITH other, score, count(DISTINCT topic) AS sharedTopics
+		RETURN
+			score,
+			sharedTopics,
+			other.name AS name,
+			other.displayName AS displayName,
+			other.bio AS bio,
+			other.company AS company,
+			other.location AS location,
+			other.avatarURL AS avatarURL,
+			other.followers AS followers,
+			other.createdAt AS createdAt
+		ORDER BY score DESC, name
+	`
+	neoGetUserStarsQuery = `
+		MATCH (:User {name: $name})-[s:HasStarred]->(r:Repository)
+		RETURN r.name AS repository, s.starredAt AS starredAt
+	`
+	neoGetUserOwnsQuery = `
+		MATCH (:User {name: $name})-[o:Owns]->(r:Repository)
+		RETURN r.name AS repository, o.createdAt AS createdAt, o.fork AS fork
 	`
 )
 
-var (
-	neoIndicesQueries = []string{
-		"CREATE CONSTRAINT ON (n:User) ASSERT n.name IS UNIQUE",
-		"CREATE CONSTRAINT ON (n:Repository) ASSERT n.name IS UNIQUE",
-		"CREATE CONSTRAINT ON (n:Label) ASSERT n.name IS UNIQUE",
-		"CREATE CONSTRAINT ON (n:Language) ASSERT n.name IS UNIQUE",
+// neoBatch is a statement that is run for each batch of its rows, the rows
+// are passed as the `rows` parameter
+// Rows that are not nil are the full set, once all batches have been written
+// the reconcile statement removes whatever was not part of it
+type neoBatch struct {
+	name       string
+	statement  string
+	reconcile  string
+	parameters map[string]interface{}
+	rows       []interface{}
+}
+
+// neoStrings returns strings as batch rows
+func neoStrings(m []string) []interface{} {
+	if m == nil {
+		return nil
 	}
-)
 
-func neoToNeoObject(m interface{}) string {
-	bytes, _ := json.Marshal(m)
-	json := string(bytes)
-	json = strings.Replace(json, `"user"`, "`user`", -1)
-	json = strings.Replace(json, `"repository"`, "`repository`", -1)
-	json = strings.Replace(json, `"starredAt"`, "`starredAt`", -1)
+	res := make([]interface{}, len(m))
+	for i, v := range m {
+		res[i] = v
+	}
+
+	return res
+}
+
+// neoUserStars returns the stars of a repository as batch rows, all
+// properties are set as MERGE does not accept nulls
+func neoUserStars(stars []model.UserStar) []interface{} {
+	if stars == nil {
+		return nil
+	}
+
+	res := make([]interface{}, len(stars))
+	for i, star := range stars {
+		res[i] = map[string]interface{}{
+			"user":      star.User,
+			"starredAt": star.StarredAt,
+		}
+	}
+
+	return res
+}
+
+// neoRepositoryLanguages returns the languages of a repository as batch rows
+func neoRepositoryLanguages(languages []model.RepositoryLanguage) []interface{} {
+	if languages == nil {
+		return nil
+	}
+
+	res := make([]interface{}, len(languages))
+	for i, language := range languages {
+		res[i] = map[string]interface{}{
+			"name":  language.Name,
+			"bytes": language.Bytes,
+			"share": language.Share,
+		}
+	}
+
+	return res
+}
+
+// neoStarredRepositories returns the stars of a user as batch rows
+func neoStarredRepositories(stars []model.StarredRepository) []interface{} {
+	if stars == nil {
+		return nil
+	}
+
+	res := make([]interface{}, len(stars))
+	for i, star := range stars {
+		res[i] = map[string]interface{}{
+			"repository": star.Repository,
+			"starredAt":  star.StarredAt,
+		}
+	}
+
+	return res
+}
+
+// neoOwnedRepositories returns the repositories of a user as batch rows
+func neoOwnedRepositories(owns []model.OwnedRepository) []interface{} {
+	if owns == nil {
+		return nil
+	}
+
+	res := make([]interface{}, len(owns))
+	for i, owned := range owns {
+		res[i] = map[string]interface{}{
+			"repository": owned.Repository,
+			"createdAt":  owned.CreatedAt,
+			"fork":       owned.Fork,
+		}
+	}
+
+	return res
+}
+
+// neoSyncParameters returns the parameters of a write, syncedAt marks the
+// edges written so that the rest can be reconciled
+func neoSyncParameters(name string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      name,
+		"syncedAt":  now.UnixNano(),
+		"removedAt": now.Unix(),
+	}
+}
+
+// neoRepositoryParameters returns the properties of a repository node
+func neoRepositoryParameters(repository *model.Repository) map[string]interface{} {
+	return map[string]interface{}{
+		"name":            repository.Name,
+		"description":     repository.Description,
+		"homepage":        repository.Homepage,
+		"stargazersCount": repository.StargazersCount,
+		"forksCount":      repository.ForksCount,
+		"license":         repository.License,
+		"archived":        repository.Archived,
+		"fork":            repository.Fork,
+		"createdAt":       repository.CreatedAt,
+		"pushedAt":        repository.PushedAt,
+	}
+}
+
+// neoSuggestionItems returns the suggestion items of the rows returned by
+// neoGetCoStarredRepositories
+func neoSuggestionItems(
+	rows []map[string]interface{},
+	reasonFormat string,
+) []model.SuggestionItem {
+	suggestions := make([]model.SuggestionItem, len(rows))
+	for k, row := range rows {
+		repository := neoRepositoryDetails(row)
+		suggestions[k] = model.SuggestionItem{
+			Type:       "repository",
+			Value:      repository.Name,
+			Reason:     fmt.Sprintf(reasonFormat, neoInt(row["score"])),
+			Repository: repository,
+		}
+	}
+
+	return suggestions
+}
+
+// neoFollowSuggestionItems returns the suggestion items of the rows returned
+// by neoGetFollowedByFollowees, properties of users whose profile was never
+// fetched are null
+func neoFollowSuggestionItems(rows []map[string]interface{}) []model.SuggestionItem {
+	suggestions := make([]model.SuggestionItem, len(rows))
+	for k, row := range rows {
+		name, _ := row["name"].(string)
+		displayName, _ := row["displayName"].(string)
+		bio, _ := row["bio"].(string)
+		company, _ := row["company"].(string)
+		location, _ := row["location"].(string)
+		avatarURL, _ := row["avatarURL"].(string)
+
+		suggestions[k] = model.SuggestionItem{
+			Type:  model.SuggestionTypeFollowUser,
+			Value: name,
+			Reason: followReason(
+				neoInt(row["score"]),
+				neoInt(row["sharedTopics"]),
+			),
+			User: &model.UserProfile{
+				Name:        name,
+				DisplayName: displayName,
+				Bio:         bio,
+				Company:     company,
+				Location:    location,
+				AvatarURL:   avatarURL,
+				Followers:   int(neoInt(row["followers"])),
+				CreatedAt:   neoInt(row["createdAt"]),
+			},
+		}
+	}
 
-	if json == "null" {
-		return "[]"
+	return suggestions
+}
+
+// neoSuggestionCandidates returns the candidates of the rows returned by
+// neoGetCandidateRepositories
+func neoSuggestionCandidates(
+	rows []map[string]interface{},
+) []*model.SuggestionCandidate {
+	candidates := make([]*model.SuggestionCandidate, len(rows))
+	for k, row := range rows {
+		candidates[k] = &model.SuggestionCandidate{
+			Repository:        neoRepositoryDetails(row),
+			FolloweeStars:     neoInt(row["followeeStars"]),
+			Stars:             neoCandidateStars(row["stars"]),
+			LastStarredAt:     neoInt(row["lastStarredAt"]),
+			MatchingTopics:    neoInt(row["matchingTopics"]),
+			MatchingLanguages: neoInt(row["matchingLanguages"]),
+		}
+	}
+
+	return candidates
+}
+
+// neoCandidateStars returns the stars collected by
+// neoGetCandidateRepositories, they are maps of followee, starredAt and
+// affinity
+func neoCandidateStars(v interface{}) []model.CandidateStar {
+	rows, _ := v.([]interface{})
+
+	stars := make([]model.CandidateStar, 0, len(rows))
+	for _, row := range rows {
+		star, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		followee, _ := star["followee"].(string)
+		stars = append(stars, model.CandidateStar{
+			Followee:  followee,
+			StarredAt: neoInt(star["starredAt"]),
+			Affinity:  neoInt(star["affinity"]),
+		})
 	}
 
-	return json
+	return stars
 }
 
-func neoToNeoStrings(m []string) string {
-	bytes, _ := json.Marshal(m)
-	return string(bytes)
+// neoRepositoryDetails returns the repository of a suggestion row, properties
+// of repositories that were never fetched are null
+func neoRepositoryDetails(row map[string]interface{}) *model.Repository {
+	name, _ := row["repository.name"].(string)
+	description, _ := row["description"].(string)
+	homepage, _ := row["homepage"].(string)
+	license, _ := row["license"].(string)
+	archived, _ := row["archived"].(bool)
+	fork, _ := row["fork"].(bool)
+
+	return &model.Repository{
+		Name:            name,
+		Description:     description,
+		Homepage:        homepage,
+		StargazersCount: int(neoInt(row["stargazersCount"])),
+		ForksCount:      int(neoInt(row["forksCount"])),
+		License:         license,
+		Archived:        archived,
+		Fork:            fork,
+		CreatedAt:       neoInt(row["createdAt"]),
+		PushedAt:        neoInt(row["pushedAt"]),
+	}
 }
 
-// NewNeo constrcuts a new Neo store given a neoism db
-func NewNeo(db *neoism.Database) (*Neo, error) {
+// neoUserRepositories returns a user with the repositories they starred and
+// own using the given query func
+func neoUserRepositories(name string, query neoQuery) (*model.User, error) {
+	parameters := map[string]interface{}{
+		"name": name,
+	}
+
+	stars, err := query(neoGetUserStarsQuery, parameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get stars")
+	}
+
+	owns, err := query(neoGetUserOwnsQuery, parameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get owned repositories")
+	}
+
+	user := &model.User{
+		Name:  name,
+		Stars: make([]model.StarredRepository, len(stars)),
+		Owns:  make([]model.OwnedRepository, len(owns)),
+	}
+	for i, row := range stars {
+		repository, _ := row["repository"].(string)
+		user.Stars[i] = model.StarredRepository{
+			Repository: repository,
+			StarredAt:  neoInt(row["starredAt"]),
+		}
+	}
+	for i, row := range owns {
+		repository, _ := row["repository"].(string)
+		fork, _ := row["fork"].(bool)
+		user.Owns[i] = model.OwnedRepository{
+			Repository: repository,
+			CreatedAt:  neoInt(row["createdAt"]),
+			Fork:       fork,
+		}
+	}
+
+	return user, nil
+}
+
+// neoRepositoryBatches returns the batches writing a repository's edges
+func neoRepositoryBatches(
+	repository *model.Repository,
+	parameters map[string]interface{},
+) []neoBatch {
+	return []neoBatch{
+		{
+			name:       "labels",
+			statement:  neoPutRepositoryLabelsQuery,
+			parameters: parameters,
+			rows:       neoStrings(repository.Labels),
+		},
+		{
+			name:       "languages",
+			statement:  neoPutRepositoryLanguagesQuery,
+			reconcile:  neoRemoveRepositoryLanguagesQuery,
+			parameters: parameters,
+			rows:       neoRepositoryLanguages(repository.Languages),
+		},
+		{
+			name:       "stars",
+			statement:  neoPutRepositoryStarsQuery,
+			reconcile:  neoRemoveRepositoryStarsQuery,
+			parameters: parameters,
+			rows:       neoUserStars(repository.Stars),
+		},
+	}
+}
+
+// neoUserBatches returns the batches writing a user's edges
+func neoUserBatches(
+	user *model.User,
+	parameters map[string]interface{},
+) []neoBatch {
+	return []neoBatch{
+		{
+			name:       "followees",
+			statement:  neoPutUserFolloweesQuery,
+			reconcile:  neoRemoveUserFolloweesQuery,
+			parameters: parameters,
+			rows:       neoStrings(user.Followees),
+		},
+		{
+			name:       "stars",
+			statement:  neoPutUserStarsQuery,
+			reconcile:  neoRemoveUserStarsQuery,
+			parameters: parameters,
+			rows:       neoStarredRepositories(user.Stars),
+		},
+		{
+			name:       "owns",
+			statement:  neoPutUserOwnsQuery,
+			parameters: parameters,
+			rows:       neoOwnedRepositories(user.Owns),
+		},
+	}
+}
+
+// NewNeo constrcuts a new Neo store given a neoism db and the max number of
+// rows written by a single statement
+func NewNeo(db *neoism.Database, batchSize int) (*Neo, error) {
+	if batchSize < 1 {
+		return nil, errors.New("batch size must be positive")
+	}
+
 	neo := &Neo{
-		db: db,
+		db:        db,
+		batchSize: batchSize,
 	}
 
 	return neo, nil
 }
 
-// SetupIndices creates indices for neo
-func (neo *Neo) SetupIndices() error {
-	logger := logrus.WithFields(logrus.Fields{
-		"logger": "store/Neo.SetupIndices",
-	})
+// Close does nothing as the REST client does not hold on to connections
+func (neo *Neo) Close() error {
+	return nil
+}
 
-	logger.Info("setting up indices")
+// write runs a query followed by the batches of each given statement in a
+// single transaction
+func (neo *Neo) write(
+	logger *logrus.Entry,
+	query *neoism.CypherQuery,
+	batches []neoBatch,
+) error {
+	tx, err := neo.db.Begin([]*neoism.CypherQuery{query})
+	if err != nil {
+		if tx != nil {
+			neoRollback(logger, tx)
+		}
+		return errors.Wrap(err, "could not begin transaction")
+	}
 
-	// run queries
-	for _, neoIndicesQuery := range neoIndicesQueries {
-		cypherQuery := &neoism.CypherQuery{
-			Statement:  neoIndicesQuery,
-			Parameters: map[string]interface{}{},
+	run := func(statement string, parameters map[string]interface{}) error {
+		return tx.Query([]*neoism.CypherQuery{
+			{
+				Statement:  statement,
+				Parameters: parameters,
+			},
+		})
+	}
+
+	for _, batch := range batches {
+		if err := neoWriteBatch(logger, neo.batchSize, run, batch); err != nil {
+			neoRollback(logger, tx)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	return nil
+}
+
+// neoWriteBatch runs a statement for each batch of its rows and then
+// reconciles them, run executes a statement in the transaction of the write
+func neoWriteBatch(
+	logger *logrus.Entry,
+	batchSize int,
+	run func(statement string, parameters map[string]interface{}) error,
+	batch neoBatch,
+) error {
+	for start := 0; start < len(batch.rows); start += batchSize {
+		end := start + batchSize
+		if end > len(batch.rows) {
+			end = len(batch.rows)
+		}
+
+		parameters := map[string]interface{}{
+			"rows": batch.rows[start:end],
+		}
+		for k, v := range batch.parameters {
+			parameters[k] = v
 		}
-		if err := neo.db.Cypher(cypherQuery); err != nil {
-			return errors.Wrap(err, "could not setup indices")
+
+		// keep start time for batch metrics
+		startTime := time.Now()
+
+		if err := run(batch.statement, parameters); err != nil {
+			return errors.Wrapf(err, "could not write %s batch", batch.name)
 		}
+
+		// log batch time
+		logger.
+			WithField("batch", batch.name).
+			WithField("batch.offset", start).
+			WithField("batch.rows", end-start).
+			WithField("execution_time", time.Now().Sub(startTime)).
+			Debug("batch execution finished")
+	}
+
+	// nil rows were not fetched, so there is nothing to reconcile
+	if batch.reconcile == "" || batch.rows == nil {
+		return nil
+	}
+
+	if err := run(batch.reconcile, batch.parameters); err != nil {
+		return errors.Wrapf(err, "could not reconcile %s", batch.name)
 	}
 
 	return nil
 }
 
+// neoRollback rolls back a failed transaction, errors are only logged as the
+// transaction will expire anyway
+func neoRollback(logger *logrus.Entry, tx *neoism.Tx) {
+	if err := tx.Rollback(); err != nil {
+		logger.WithError(err).Warn("could not rollback transaction")
+	}
+}
+
+// Migrate applies the pending graph migrations
+func (neo *Neo) Migrate() error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger": "store/Neo.Migrate",
+	})
+
+	logger.Info("migrating graph")
+
+	return neoMigrate(logger, neo.batchSize, false, neo.query)
+}
+
+// Migrations returns all graph migrations and whether they have been applied
+func (neo *Neo) Migrations() ([]GraphMigration, error) {
+	return neoMigrationsStatus(neo.query)
+}
+
+// query runs a statement in its own transaction and returns its rows
+func (neo *Neo) query(
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error) {
+	res := []map[string]interface{}{}
+
+	cypherQuery := &neoism.CypherQuery{
+		Statement:  statement,
+		Parameters: parameters,
+		Result:     &res,
+	}
+	if err := neo.db.Cypher(cypherQuery); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // PutRepository merges a repository's graph in neo
 func (neo *Neo) PutRepository(repository *model.Repository) error {
 	logger := logrus.WithFields(logrus.Fields{
@@ -137,32 +719,19 @@ func (neo *Neo) PutRepository(repository *model.Repository) error {
 	// keep start time for query metrics
 	startTime := time.Now()
 
-	// create template for query
-	neoPutRepositoryQuery, err := template.
-		New("neoPutRepositoryQuery").
-		Funcs(template.FuncMap{
-			"toObject":  neoToNeoObject,
-			"toStrings": neoToNeoStrings,
-		}).
-		Parse(neoPutRepositoryQueryTemplate)
-	if err != nil {
-		return errors.Wrap(err, "could not parse template")
-	}
-
-	// render query
-	query := &bytes.Buffer{}
-	if err := neoPutRepositoryQuery.Execute(query, repository); err != nil {
-		return errors.Wrap(err, "could not merge repository")
-	}
-
-	logger.WithField("query", query).Debug("running query")
+	// edges not synced by this write are removed
+	parameters := neoSyncParameters(repository.Name, startTime)
 
-	// run query
-	cypherQuery := &neoism.CypherQuery{
-		Statement:  query.String(),
-		Parameters: map[string]interface{}{},
-	}
-	if err := neo.db.Cypher(cypherQuery); err != nil {
+	// run queries
+	err := neo.write(
+		logger,
+		&neoism.CypherQuery{
+			Statement:  neoPutRepositoryQuery,
+			Parameters: neoRepositoryParameters(repository),
+		},
+		neoRepositoryBatches(repository, parameters),
+	)
+	if err != nil {
 		return errors.Wrap(err, "could not merge repo")
 	}
 
@@ -181,6 +750,7 @@ func (neo *Neo) PutUser(user *model.User) error {
 		"user.name":            user.Name,
 		"user.followees.count": len(user.Followees),
 		"user.stars.count":     len(user.Stars),
+		"user.owns.count":      len(user.Owns),
 	})
 
 	logger.Info("saving user")
@@ -188,33 +758,60 @@ func (neo *Neo) PutUser(user *model.User) error {
 	// keep start time for query metrics
 	startTime := time.Now()
 
-	// create template for query
-	neoPutUserQuery, err := template.
-		New("neoPutUserQuery").
-		Funcs(template.FuncMap{
-			"toObject":  neoToNeoObject,
-			"toStrings": neoToNeoStrings,
-		}).
-		Parse(neoPutUserQueryTemplate)
-	if err != nil {
-		return errors.Wrap(err, "could not parse template")
-	}
+	// edges not synced by this write are removed
+	parameters := neoSyncParameters(user.Name, startTime)
 
-	// render query
-	query := &bytes.Buffer{}
-	if err := neoPutUserQuery.Execute(query, user); err != nil {
+	// run queries
+	err := neo.write(
+		logger,
+		&neoism.CypherQuery{
+			Statement: neoPutUserQuery,
+			Parameters: map[string]interface{}{
+				"name": user.Name,
+			},
+		},
+		neoUserBatches(user, parameters),
+	)
+	if err != nil {
 		return errors.Wrap(err, "could not merge user")
 	}
 
-	logger.WithField("query", query).Debug("running query")
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// PutUserProfile sets the profile properties of a user in neo
+func (neo *Neo) PutUserProfile(profile *model.UserProfile) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Neo.PutUserProfile",
+		"user.name": profile.Name,
+	})
+
+	logger.Info("saving user profile")
+
+	// keep start time for query metrics
+	startTime := time.Now()
 
 	// run query
 	cypherQuery := &neoism.CypherQuery{
-		Statement:  query.String(),
-		Parameters: map[string]interface{}{},
+		Statement: neoPutUserProfileQuery,
+		Parameters: map[string]interface{}{
+			"name":        profile.Name,
+			"displayName": profile.DisplayName,
+			"bio":         profile.Bio,
+			"company":     profile.Company,
+			"location":    profile.Location,
+			"avatarURL":   profile.AvatarURL,
+			"followers":   profile.Followers,
+			"createdAt":   profile.CreatedAt,
+		},
 	}
 	if err := neo.db.Cypher(cypherQuery); err != nil {
-		return errors.Wrap(err, "could not merge user")
+		return errors.Wrap(err, "could not set user profile")
 	}
 
 	// log query time
@@ -225,50 +822,138 @@ func (neo *Neo) PutUser(user *model.User) error {
 	return nil
 }
 
-// GetUserSuggestion get user suggestions
-func (neo *Neo) GetUserSuggestion(user *model.User) (*model.Suggestion, error) {
+// GetUserCandidates returns the repositories starred by the user's followees
+// since the given time
+func (neo *Neo) GetUserCandidates(
+	user *model.User,
+	since time.Time,
+) ([]*model.SuggestionCandidate, error) {
 	logger := logrus.WithFields(logrus.Fields{
-		"logger":    "store/Neo.GetUserSuggestion",
+		"logger":    "store/Neo.GetUserCandidates",
 		"user.name": user.Name,
 	})
 
-	logger.Info("get user suggestion")
+	logger.Info("get user candidates")
 
 	// keep start time for query metrics
 	startTime := time.Now()
 
-	// create template for query
-	neoGetUserSuggestionQuery, err := template.
-		New("neoGetUserSuggestionQuery").
-		Parse(neoGetTopStarredRepositories)
+	rows, err := neo.query(neoGetCandidateRepositories, map[string]interface{}{
+		"name":  user.Name,
+		"since": since.Unix(),
+		"limit": GraphCandidateLimit,
+	})
 	if err != nil {
-		return &model.Suggestion{}, errors.Wrap(err, "could not parse template")
+		return nil, errors.Wrap(err, "could not run cypher query")
 	}
 
-	// render query
-	query := &bytes.Buffer{}
-	type InputQuery struct {
-		Name      string
-		Timestamp int64
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return neoSuggestionCandidates(rows), nil
+}
+
+// GetUserCoStarredSuggestion suggests repositories that are starred together
+// with the ones the user starred
+func (neo *Neo) GetUserCoStarredSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Neo.GetUserCoStarredSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user co-starred suggestion")
+
+	return neo.suggestion(
+		logger,
+		user,
+		neoGetCoStarredRepositories,
+		map[string]interface{}{
+			"name":  user.Name,
+			"limit": GraphSuggestionLimit,
+		},
+		"%d co-stars with repositories you starred",
+	)
+}
+
+// GetUserFollowSuggestion suggests users followed by many of the user's
+// followees
+func (neo *Neo) GetUserFollowSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Neo.GetUserFollowSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user follow suggestion")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	rows, err := neo.query(neoGetFollowedByFollowees, map[string]interface{}{
+		"name":  user.Name,
+		"limit": GraphSuggestionLimit,
+	})
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not run cypher query")
 	}
-	if err := neoGetUserSuggestionQuery.Execute(query, InputQuery{
-		Name:      user.Name,
-		Timestamp: startTime.Add(time.Hour * 24 * -7).Unix(),
-	}); err != nil {
-		return &model.Suggestion{}, errors.Wrap(err, "could not execute query")
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    neoFollowSuggestionItems(rows),
+	}, nil
+}
+
+// GetUserRepositories returns a user with the repositories they starred and
+// own
+func (neo *Neo) GetUserRepositories(name string) (*model.User, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/Neo.GetUserRepositories",
+		"user.name": name,
+	})
+
+	logger.Info("get user repositories")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	user, err := neoUserRepositories(name, neo.query)
+	if err != nil {
+		return nil, err
 	}
 
-	logger.WithField("query", query).Debug("running query")
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return user, nil
+}
+
+// suggestion runs a suggestion query, reasonFormat formats the score of each
+// suggested repository
+func (neo *Neo) suggestion(
+	logger *logrus.Entry,
+	user *model.User,
+	statement string,
+	parameters map[string]interface{},
+	reasonFormat string,
+) (*model.Suggestion, error) {
+	// keep start time for query metrics
+	startTime := time.Now()
 
-	res := []struct {
-		NoOfFollowees int    `json:"noOfFollowees"`
-		Repository    string `json:"repository.name"`
-	}{}
+	res := []map[string]interface{}{}
 
 	// run query
 	cypherQuery := &neoism.CypherQuery{
-		Statement:  query.String(),
-		Parameters: map[string]interface{}{},
+		Statement:  statement,
+		Parameters: parameters,
 		Result:     &res,
 	}
 	if err := neo.db.Cypher(cypherQuery); err != nil {
@@ -280,19 +965,9 @@ func (neo *Neo) GetUserSuggestion(user *model.User) (*model.Suggestion, error) {
 		WithField("execution_time", time.Now().Sub(startTime)).
 		Debug("query execution finished")
 
-	suggestions := make([]model.SuggestionItem, len(res))
-
-	for k, _ := range res {
-		suggestions[k] = model.SuggestionItem{
-			Type:   "repository",
-			Value:  res[k].Repository,
-			Reason: fmt.Sprintf("%d followers starred it", res[k].NoOfFollowees),
-		}
-	}
-
 	return &model.Suggestion{
 		UserID:   user.Name,
 		DateTime: time.Now(),
-		Items:    suggestions,
+		Items:    neoSuggestionItems(res, reasonFormat),
 	}, nil
 }
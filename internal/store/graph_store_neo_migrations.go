@@ -0,0 +1,291 @@
+package store
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	neoGetMigrationsQuery = `
+		MATCH (m:SchemaMigration)
+		RETURN m.version AS version
+	`
+	neoPutMigrationQuery = `
+		MERGE (m:SchemaMigration {version: $version})
+		SET m.description = $description, m.appliedAt = $appliedAt
+	`
+)
+
+// neoMigrationStep is a statement of a migration
+type neoMigrationStep struct {
+	// statement is the cypher for neo 3.5, used over REST
+	statement string
+	// boltStatement replaces statement over bolt, for neo 4.4 and later,
+	// when the syntax differs
+	boltStatement string
+	// batched statements take a `batchSize` parameter and return a `count`
+	// column, they are repeated until the count is 0
+	batched bool
+}
+
+// neoMigration is a versioned change to the graph's schema or data
+// Every step runs in its own transaction, as neo does not allow schema and
+// data changes in the same one, so steps must be safe to run again in case
+// a migration was interrupted
+type neoMigration struct {
+	version     int
+	description string
+	steps       []neoMigrationStep
+}
+
+// neoMigrations are applied in order, new migrations are appended and
+// existing ones must never change
+var neoMigrations = []neoMigration{
+	{
+		version:     1,
+		description: "unique user, repository and label names",
+		steps: []neoMigrationStep{
+			{
+				statement:     "CREATE CONSTRAINT ON (n:User) ASSERT n.name IS UNIQUE",
+				boltStatement: "CREATE CONSTRAINT user_name IF NOT EXISTS FOR (n:User) REQUIRE n.name IS UNIQUE",
+			},
+			{
+				statement:     "CREATE CONSTRAINT ON (n:Repository) ASSERT n.name IS UNIQUE",
+				boltStatement: "CREATE CONSTRAINT repository_name IF NOT EXISTS FOR (n:Repository) REQUIRE n.name IS UNIQUE",
+			},
+			{
+				statement:     "CREATE CONSTRAINT ON (n:Label) ASSERT n.name IS UNIQUE",
+				boltStatement: "CREATE CONSTRAINT label_name IF NOT EXISTS FOR (n:Label) REQUIRE n.name IS UNIQUE",
+			},
+		},
+	},
+	{
+		version:     2,
+		description: "split languages into :Language nodes",
+		steps: []neoMigrationStep{
+			{
+				statement:     "CREATE CONSTRAINT ON (n:Language) ASSERT n.name IS UNIQUE",
+				boltStatement: "CREATE CONSTRAINT language_name IF NOT EXISTS FOR (n:Language) REQUIRE n.name IS UNIQUE",
+			},
+			{
+				// move ContainsLanguage edges from labels to languages
+				statement: `
+					MATCH (r:Repository)-[c:ContainsLanguage]->(l:Label)
+					WITH r, c, l LIMIT $batchSize
+					MERGE (language:Language {name: l.name})
+					MERGE (r)-[:ContainsLanguage]->(language)
+					DELETE c
+					RETURN count(*) AS count
+				`,
+				batched: true,
+			},
+			{
+				// labels that were only used as languages are left behind
+				statement: `
+					MATCH (l:Label)
+					WHERE NOT (l)--()
+					WITH l LIMIT $batchSize
+					DELETE l
+					RETURN count(*) AS count
+				`,
+				batched: true,
+			},
+		},
+	},
+	{
+		version:     3,
+		description: "replace ContainsLanguage with WrittenIn edges",
+		steps: []neoMigrationStep{
+			{
+				// the share is unknown until the repository is crawled again
+				statement: `
+					MATCH (r:Repository)-[c:ContainsLanguage]->(l:Language)
+					WITH r, c, l LIMIT $batchSize
+					MERGE (r)-[:WrittenIn]->(l)
+					DELETE c
+					RETURN count(*) AS count
+				`,
+				batched: true,
+			},
+		},
+	},
+}
+
+// GraphMigration is a graph schema migration and whether it has been applied
+type GraphMigration struct {
+	Version     int
+	Description string
+	Applied     bool
+}
+
+// GraphMigrator is implemented by graph stores with versioned migrations
+type GraphMigrator interface {
+	Migrate() error
+	Migrations() ([]GraphMigration, error)
+	// Close releases the store's connections
+	Close() error
+}
+
+// MigratableGraphStore is a GraphStore with versioned migrations
+type MigratableGraphStore interface {
+	GraphStore
+	GraphMigrator
+}
+
+// PendingGraphMigrations returns the migrations that have not been applied
+func PendingGraphMigrations(migrator GraphMigrator) ([]GraphMigration, error) {
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []GraphMigration{}
+	for _, migration := range migrations {
+		if !migration.Applied {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// neoQuery runs a statement in its own transaction and returns its rows
+type neoQuery func(
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error)
+
+// neoMigrationsStatus returns all migrations and whether they have been
+// applied
+func neoMigrationsStatus(query neoQuery) ([]GraphMigration, error) {
+	rows, err := query(neoGetMigrationsQuery, map[string]interface{}{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get applied migrations")
+	}
+
+	applied := map[int]bool{}
+	for _, row := range rows {
+		applied[int(neoInt(row["version"]))] = true
+	}
+
+	migrations := make([]GraphMigration, len(neoMigrations))
+	for i, migration := range neoMigrations {
+		migrations[i] = GraphMigration{
+			Version:     migration.version,
+			Description: migration.description,
+			Applied:     applied[migration.version],
+		}
+	}
+
+	return migrations, nil
+}
+
+// neoMigrate applies the pending migrations in order, recording each one
+// once all of its steps have run
+func neoMigrate(
+	logger *logrus.Entry,
+	batchSize int,
+	bolt bool,
+	query neoQuery,
+) error {
+	migrations, err := neoMigrationsStatus(query)
+	if err != nil {
+		return err
+	}
+
+	for i, migration := range neoMigrations {
+		if migrations[i].Applied {
+			continue
+		}
+
+		logger := logger.WithFields(logrus.Fields{
+			"migration.version":     migration.version,
+			"migration.description": migration.description,
+		})
+
+		logger.Info("applying migration")
+
+		// keep start time for query metrics
+		startTime := time.Now()
+
+		for _, step := range migration.steps {
+			if err := neoMigrateStep(logger, batchSize, bolt, query, step); err != nil {
+				return errors.Wrapf(err, "could not apply migration %d", migration.version)
+			}
+		}
+
+		_, err := query(neoPutMigrationQuery, map[string]interface{}{
+			"version":     migration.version,
+			"description": migration.description,
+			"appliedAt":   time.Now().Unix(),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "could not record migration %d", migration.version)
+		}
+
+		// log query time
+		logger.
+			WithField("execution_time", time.Now().Sub(startTime)).
+			Debug("migration applied")
+	}
+
+	return nil
+}
+
+// neoMigrateStep runs a migration step, batched steps are repeated until they
+// no longer change anything
+func neoMigrateStep(
+	logger *logrus.Entry,
+	batchSize int,
+	bolt bool,
+	query neoQuery,
+	step neoMigrationStep,
+) error {
+	statement := step.statement
+	if bolt && step.boltStatement != "" {
+		statement = step.boltStatement
+	}
+
+	for {
+		rows, err := query(statement, map[string]interface{}{
+			"batchSize": batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !step.batched {
+			return nil
+		}
+
+		count := int64(0)
+		if len(rows) > 0 {
+			count = neoInt(rows[0]["count"])
+		}
+
+		logger.
+			WithField("batch.rows", count).
+			Debug("migration batch finished")
+
+		if count == 0 {
+			return nil
+		}
+	}
+}
+
+// neoInt returns a number returned by neo, which is a float64 over REST and
+// an int64 over bolt
+func neoInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	default:
+		return 0
+	}
+}
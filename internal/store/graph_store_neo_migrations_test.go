@@ -0,0 +1,161 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMigrationGraph records the statements of a migration and returns the
+// given counts for batched ones
+type fakeMigrationGraph struct {
+	applied    []interface{}
+	counts     map[string][]int64
+	failOn     string
+	statements []string
+	recorded   []interface{}
+}
+
+func (f *fakeMigrationGraph) query(
+	statement string,
+	parameters map[string]interface{},
+) ([]map[string]interface{}, error) {
+	f.statements = append(f.statements, statement)
+
+	switch statement {
+	case f.failOn:
+		return nil, errors.New("failed")
+	case neoGetMigrationsQuery:
+		rows := []map[string]interface{}{}
+		for _, version := range f.applied {
+			rows = append(rows, map[string]interface{}{"version": version})
+		}
+		return rows, nil
+	case neoPutMigrationQuery:
+		f.recorded = append(f.recorded, parameters["version"])
+		return nil, nil
+	}
+
+	counts := f.counts[statement]
+	if len(counts) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	f.counts[statement] = counts[1:]
+
+	return []map[string]interface{}{{"count": counts[0]}}, nil
+}
+
+func TestNeoMigrate(t *testing.T) {
+	logger := logrus.WithField("logger", "test")
+	moveLanguages := neoMigrations[1].steps[1].statement
+
+	t.Run("applies pending migrations in order", func(t *testing.T) {
+		f := &fakeMigrationGraph{
+			counts: map[string][]int64{
+				moveLanguages: {2, 2, 1, 0},
+			},
+		}
+		require.NoError(t, neoMigrate(logger, 2, false, f.query))
+
+		require.Equal(t, []interface{}{1, 2, 3}, f.recorded)
+		require.Equal(t, []string{
+			neoGetMigrationsQuery,
+			neoMigrations[0].steps[0].statement,
+			neoMigrations[0].steps[1].statement,
+			neoMigrations[0].steps[2].statement,
+			neoPutMigrationQuery,
+			neoMigrations[1].steps[0].statement,
+			// repeated until nothing is left to move
+			moveLanguages,
+			moveLanguages,
+			moveLanguages,
+			moveLanguages,
+			neoMigrations[1].steps[2].statement,
+			neoPutMigrationQuery,
+			neoMigrations[2].steps[0].statement,
+			neoPutMigrationQuery,
+		}, f.statements)
+	})
+
+	t.Run("skips applied migrations", func(t *testing.T) {
+		// versions are float64 over REST
+		f := &fakeMigrationGraph{
+			applied: []interface{}{float64(1)},
+		}
+		require.NoError(t, neoMigrate(logger, 2, false, f.query))
+
+		require.Equal(t, []interface{}{2, 3}, f.recorded)
+		require.NotContains(t, f.statements, neoMigrations[0].steps[0].statement)
+	})
+
+	t.Run("uses bolt statements over bolt", func(t *testing.T) {
+		f := &fakeMigrationGraph{}
+		require.NoError(t, neoMigrate(logger, 2, true, f.query))
+
+		require.Contains(t, f.statements, neoMigrations[0].steps[0].boltStatement)
+		require.NotContains(t, f.statements, neoMigrations[0].steps[0].statement)
+	})
+
+	t.Run("does not record failed migrations", func(t *testing.T) {
+		f := &fakeMigrationGraph{
+			failOn: moveLanguages,
+		}
+		require.Error(t, neoMigrate(logger, 2, false, f.query))
+
+		require.Equal(t, []interface{}{1}, f.recorded)
+	})
+}
+
+func TestNeoMigrationsStatus(t *testing.T) {
+	// versions are int64 over bolt
+	f := &fakeMigrationGraph{
+		applied: []interface{}{int64(1)},
+	}
+	migrator := &fakeMigrator{query: f.query}
+
+	migrations, err := migrator.Migrations()
+	require.NoError(t, err)
+	require.Len(t, migrations, len(neoMigrations))
+	require.True(t, migrations[0].Applied)
+	require.False(t, migrations[1].Applied)
+
+	pending, err := PendingGraphMigrations(migrator)
+	require.NoError(t, err)
+	require.Len(t, pending, len(neoMigrations)-1)
+	require.Equal(t, 2, pending[0].Version)
+}
+
+// fakeMigrator is a GraphMigrator over a query func
+type fakeMigrator struct {
+	query neoQuery
+}
+
+func (m *fakeMigrator) Migrate() error {
+	return neoMigrate(logrus.WithField("logger", "test"), 1, false, m.query)
+}
+
+func (m *fakeMigrator) Migrations() ([]GraphMigration, error) {
+	return neoMigrationsStatus(m.query)
+}
+
+func (m *fakeMigrator) Close() error {
+	return nil
+}
+
+func TestNeo_Migrate(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	require.NoError(t, neo.Migrate())
+
+	// nothing was applied on the fake, so every migration is recorded
+	statement := fake.lastStatement(t)
+	require.Equal(t, neoPutMigrationQuery, statement.Statement)
+	params := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+	require.Equal(t, float64(len(neoMigrations)), params["version"])
+}
@@ -0,0 +1,567 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/neoism"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// neoOddNames are names that would break queries if they were rendered into
+// cypher instead of being passed as parameters
+var neoOddNames = []string{
+	`quo"te`,
+	`single'quote`,
+	`back\slash`,
+	"uni·códe-名前-🚀",
+	`"}) DETACH DELETE n //`,
+	"new\nline",
+}
+
+// neoStatement is a cypher statement received by the fake neo server
+type neoStatement struct {
+	Statement  string          `json:"statement"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// fakeNeo is a minimal neo4j REST server recording cypher statements
+type fakeNeo struct {
+	server *httptest.Server
+
+	mutex      sync.Mutex
+	statements []neoStatement
+	commits    int
+	rollbacks  int
+	// response is returned for every cypher request
+	response string
+	// failStatement makes transactions running it fail
+	failStatement string
+}
+
+func newFakeNeo(t *testing.T) *fakeNeo {
+	f := &fakeNeo{
+		response: `{"columns": [], "data": []}`,
+	}
+
+	f.server = httptest.NewServer(http.HandlerFunc(func(
+		w http.ResponseWriter,
+		r *http.Request,
+	) {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/db/data/":
+			json.NewEncoder(w).Encode(map[string]string{
+				"cypher":        f.server.URL + "/db/data/cypher",
+				"transaction":   f.server.URL + "/db/data/transaction",
+				"neo4j_version": "3.5.0",
+			})
+
+		case r.URL.Path == "/db/data/cypher":
+			req := struct {
+				Query  string          `json:"query"`
+				Params json.RawMessage `json:"params"`
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			f.statements = append(f.statements, neoStatement{
+				Statement:  req.Query,
+				Parameters: req.Params,
+			})
+			w.Write([]byte(f.response))
+
+		case strings.HasSuffix(r.URL.Path, "/commit"):
+			f.commits++
+			w.Write([]byte(`{"results": [], "errors": []}`))
+
+		case r.Method == http.MethodDelete:
+			f.rollbacks++
+			w.Write([]byte(`{"results": [], "errors": []}`))
+
+		case strings.HasPrefix(r.URL.Path, "/db/data/transaction"):
+			req := struct {
+				Statements []neoStatement `json:"statements"`
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			f.statements = append(f.statements, req.Statements...)
+
+			results := []string{}
+			errs := "[]"
+			for _, statement := range req.Statements {
+				if statement.Statement == f.failStatement {
+					errs = `[{"code": "Neo.ClientError", "message": "failed"}]`
+					break
+				}
+				results = append(results, `{"columns": [], "data": []}`)
+			}
+			location := f.server.URL + "/db/data/transaction/1"
+			if r.URL.Path == "/db/data/transaction" {
+				w.Header().Set("Location", location)
+				w.WriteHeader(http.StatusCreated)
+			}
+			fmt.Fprintf(
+				w,
+				`{"commit": %q, "results": [%s], "errors": %s}`,
+				location+"/commit",
+				strings.Join(results, ","),
+				errs,
+			)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return f
+}
+
+// rows returns the rows of all the statements matching the given one
+func (f *fakeNeo) rows(t *testing.T, statement string) []json.RawMessage {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	rows := []json.RawMessage{}
+	for _, s := range f.statements {
+		if s.Statement != statement {
+			continue
+		}
+		params := struct {
+			Rows []json.RawMessage `json:"rows"`
+		}{}
+		require.NoError(t, json.Unmarshal(s.Parameters, &params))
+		rows = append(rows, params.Rows...)
+	}
+
+	return rows
+}
+
+// lastStatement returns the last statement received
+func (f *fakeNeo) lastStatement(t *testing.T) neoStatement {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	require.NotEmpty(t, f.statements)
+	return f.statements[len(f.statements)-1]
+}
+
+// requireNoneRendered checks that none of the names was rendered into any of
+// the statements received
+func (f *fakeNeo) requireNoneRendered(t *testing.T, names []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, s := range f.statements {
+		for _, name := range names {
+			require.False(
+				t,
+				strings.Contains(s.Statement, name),
+				"%q rendered into statement", name,
+			)
+		}
+	}
+}
+
+// requireRows checks that the rows of a statement decode to the given ones
+func requireRows(t *testing.T, expected interface{}, rows []json.RawMessage) {
+	gotRows, err := json.Marshal(rows)
+	require.NoError(t, err)
+	expectedRows, err := json.Marshal(expected)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedRows), string(gotRows))
+}
+
+func getNeo(t *testing.T, host string, batchSize int) *Neo {
+	db, err := neoism.Connect(host)
+	require.NoError(t, err)
+
+	neo, err := NewNeo(db, batchSize)
+	require.NoError(t, err)
+
+	return neo
+}
+
+func TestNeo_PutRepositoryParameters(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	repository := &model.Repository{
+		Name:   neoOddNames[0],
+		Labels: neoOddNames,
+		Languages: []model.RepositoryLanguage{
+			{
+				Name:  neoOddNames[2],
+				Bytes: 300,
+				Share: 0.75,
+			},
+			{
+				Name:  neoOddNames[3],
+				Bytes: 100,
+				Share: 0.25,
+			},
+		},
+		Stars: []model.UserStar{
+			{
+				User:      neoOddNames[1],
+				StarredAt: 0,
+			},
+		},
+	}
+	require.NoError(t, neo.PutRepository(repository))
+	fake.requireNoneRendered(t, neoOddNames)
+
+	requireRows(t, neoOddNames, fake.rows(t, neoPutRepositoryLabelsQuery))
+	requireRows(t, []map[string]interface{}{
+		{
+			"name":  neoOddNames[2],
+			"bytes": 300,
+			"share": 0.75,
+		},
+		{
+			"name":  neoOddNames[3],
+			"bytes": 100,
+			"share": 0.25,
+		},
+	}, fake.rows(t, neoPutRepositoryLanguagesQuery))
+	requireRows(t, []map[string]interface{}{
+		{
+			"user":      neoOddNames[1],
+			"starredAt": 0,
+		},
+	}, fake.rows(t, neoPutRepositoryStarsQuery))
+
+	for _, statement := range fake.statements {
+		params := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+		require.Equal(t, repository.Name, params["name"])
+	}
+	require.Equal(t, 1, fake.commits)
+}
+
+func TestNeo_PutUserParameters(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	user := &model.User{
+		Name:      neoOddNames[2],
+		Followees: neoOddNames,
+		Stars: []model.StarredRepository{
+			{
+				Repository: neoOddNames[3],
+				StarredAt:  1,
+			},
+		},
+		Owns: []model.OwnedRepository{
+			{
+				Repository: neoOddNames[4],
+				CreatedAt:  2,
+			},
+		},
+	}
+	require.NoError(t, neo.PutUser(user))
+	fake.requireNoneRendered(t, neoOddNames)
+
+	requireRows(t, neoOddNames, fake.rows(t, neoPutUserFolloweesQuery))
+	requireRows(t, []map[string]interface{}{
+		{
+			"repository": neoOddNames[3],
+			"starredAt":  1,
+		},
+	}, fake.rows(t, neoPutUserStarsQuery))
+	requireRows(t, []map[string]interface{}{
+		{
+			"repository": neoOddNames[4],
+			"createdAt":  2,
+			"fork":       false,
+		},
+	}, fake.rows(t, neoPutUserOwnsQuery))
+
+	for _, statement := range fake.statements {
+		params := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+		require.Equal(t, user.Name, params["name"])
+	}
+	require.Equal(t, 1, fake.commits)
+}
+
+func TestNeo_PutRepositoryBatches(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 2)
+
+	repository := &model.Repository{
+		Name: "foo/bar",
+	}
+	for i := 0; i < 5; i++ {
+		repository.Stars = append(repository.Stars, model.UserStar{
+			User:      fmt.Sprintf("user-%d", i),
+			StarredAt: int64(i),
+		})
+	}
+	require.NoError(t, neo.PutRepository(repository))
+
+	// stars are written in batches of up to 2 rows
+	batches := 0
+	for _, statement := range fake.statements {
+		if statement.Statement != neoPutRepositoryStarsQuery {
+			continue
+		}
+		params := struct {
+			Rows []json.RawMessage `json:"rows"`
+		}{}
+		require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+		require.True(t, len(params.Rows) <= 2)
+		batches++
+	}
+	require.Equal(t, 3, batches)
+	require.Len(t, fake.rows(t, neoPutRepositoryStarsQuery), 5)
+
+	// empty lists are not written at all
+	require.Empty(t, fake.rows(t, neoPutRepositoryLabelsQuery))
+	require.Empty(t, fake.rows(t, neoPutRepositoryLanguagesQuery))
+
+	// all batches are committed together
+	require.Equal(t, 1, fake.commits)
+	require.Equal(t, 0, fake.rollbacks)
+}
+
+func TestNeo_PutUserReconcile(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	// followees are the full set, stars were not fetched
+	require.NoError(t, neo.PutUser(&model.User{
+		Name:      "foo",
+		Followees: []string{"bar"},
+	}))
+
+	statements := map[string]map[string]interface{}{}
+	for _, statement := range fake.statements {
+		params := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+		statements[statement.Statement] = params
+	}
+
+	require.Contains(t, statements, neoRemoveUserFolloweesQuery)
+	require.NotContains(t, statements, neoRemoveUserStarsQuery)
+
+	// only edges not written in this transaction are removed
+	require.Equal(
+		t,
+		statements[neoPutUserFolloweesQuery]["syncedAt"],
+		statements[neoRemoveUserFolloweesQuery]["syncedAt"],
+	)
+	require.NotNil(t, statements[neoRemoveUserFolloweesQuery]["removedAt"])
+
+	// empty lists remove all edges
+	fake.statements = nil
+	require.NoError(t, neo.PutRepository(&model.Repository{
+		Name:  "foo/bar",
+		Stars: []model.UserStar{},
+	}))
+	require.Empty(t, fake.rows(t, neoPutRepositoryStarsQuery))
+	require.Equal(t, neoRemoveRepositoryStarsQuery, fake.lastStatement(t).Statement)
+}
+
+func TestNeo_PutUserRollback(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	fake.failStatement = neoPutUserStarsQuery
+
+	require.Error(t, neo.PutUser(&model.User{
+		Name:      "foo",
+		Followees: []string{"bar"},
+		Stars: []model.StarredRepository{
+			{
+				Repository: "foo/bar",
+			},
+		},
+	}))
+	require.Equal(t, 0, fake.commits)
+	require.Equal(t, 1, fake.rollbacks)
+}
+
+func TestNeo_NewNeoBatchSize(t *testing.T) {
+	_, err := NewNeo(nil, 0)
+	require.Error(t, err)
+}
+
+func TestNeo_GetUserCandidatesParameters(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	fake.response = `{
+		"columns": ["followeeStars", "lastStarredAt", "stars", "matchingTopics", "matchingLanguages", "repository.name", "description", "stargazersCount", "archived", "pushedAt"],
+		"data": [[2, 5, [{"followee": "a", "starredAt": 5, "affinity": 3}, {"followee": "b", "starredAt": 1, "affinity": 0}], 1, 0, "back\\slash/\"quo'te\"-名前", "foo", 3, true, 4]]
+	}`
+
+	since := time.Unix(1000, 0)
+	gotCandidates, err := neo.GetUserCandidates(&model.User{
+		Name: neoOddNames[5],
+	}, since)
+	require.NoError(t, err)
+	require.Equal(t, []*model.SuggestionCandidate{
+		{
+			Repository: &model.Repository{
+				Name:            `back\slash/"quo'te"-名前`,
+				Description:     "foo",
+				StargazersCount: 3,
+				Archived:        true,
+				PushedAt:        4,
+			},
+			FolloweeStars: 2,
+			Stars: []model.CandidateStar{
+				{Followee: "a", StarredAt: 5, Affinity: 3},
+				{Followee: "b", StarredAt: 1},
+			},
+			LastStarredAt:  5,
+			MatchingTopics: 1,
+		},
+	}, gotCandidates)
+	fake.requireNoneRendered(t, neoOddNames)
+
+	statement := fake.lastStatement(t)
+	require.Equal(t, neoGetCandidateRepositories, statement.Statement)
+	params := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+	require.Equal(t, neoOddNames[5], params["name"])
+	require.Equal(t, float64(1000), params["since"])
+	require.Equal(t, float64(GraphCandidateLimit), params["limit"])
+}
+
+func TestNeo_GetUserCoStarredSuggestionParameters(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	fake.response = `{
+		"columns": ["score", "repository.name"],
+		"data": [[3, "foo/bar"]]
+	}`
+
+	gotSuggestion, err := neo.GetUserCoStarredSuggestion(&model.User{
+		Name: neoOddNames[4],
+	})
+	require.NoError(t, err)
+	require.Len(t, gotSuggestion.Items, 1)
+	require.Equal(t, "foo/bar", gotSuggestion.Items[0].Value)
+	require.Equal(t, "3 co-stars with repositories you starred", gotSuggestion.Items[0].Reason)
+	fake.requireNoneRendered(t, neoOddNames)
+
+	statement := fake.lastStatement(t)
+	require.Equal(t, neoGetCoStarredRepositories, statement.Statement)
+	params := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+	require.Equal(t, neoOddNames[4], params["name"])
+}
+
+func TestNeo_GetUserFollowSuggestionParameters(t *testing.T) {
+	fake := newFakeNeo(t)
+	defer fake.server.Close()
+	neo := getNeo(t, fake.server.URL+"/db/data/", 1000)
+
+	fake.response = `{
+		"columns": ["score", "sharedTopics", "name", "displayName", "followers"],
+		"data": [[4, 2, "octocat", "The Octocat", 10], [1, 0, "other", null, null]]
+	}`
+
+	gotSuggestion, err := neo.GetUserFollowSuggestion(&model.User{
+		Name: neoOddNames[3],
+	})
+	require.NoError(t, err)
+	require.Equal(t, []model.SuggestionItem{
+		{
+			Type:   model.SuggestionTypeFollowUser,
+			Value:  "octocat",
+			Reason: "followed by 4 people you follow, 2 topics in common",
+			User: &model.UserProfile{
+				Name:        "octocat",
+				DisplayName: "The Octocat",
+				Followers:   10,
+			},
+		},
+		{
+			Type:   model.SuggestionTypeFollowUser,
+			Value:  "other",
+			Reason: "followed by 1 people you follow",
+			User: &model.UserProfile{
+				Name: "other",
+			},
+		},
+	}, gotSuggestion.Items)
+	fake.requireNoneRendered(t, neoOddNames)
+
+	statement := fake.lastStatement(t)
+	require.Equal(t, neoGetFollowedByFollowees, statement.Statement)
+	params := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(statement.Parameters, &params))
+	require.Equal(t, neoOddNames[3], params["name"])
+}
+
+// TestNeo_OddNames round-trips odd names through a real neo4j, it only runs
+// when NEO4J_TEST_HOST is set, eg http://localhost:7474/db/data
+func TestNeo_OddNames(t *testing.T) {
+	host := os.Getenv("NEO4J_TEST_HOST")
+	if host == "" {
+		t.Skip("NEO4J_TEST_HOST not set")
+	}
+
+	neo := getNeo(t, host, 2)
+	require.NoError(t, neo.Migrate())
+
+	// a user following someone who starred repositories with odd names
+	for _, name := range neoOddNames {
+		require.NoError(t, neo.PutUser(&model.User{
+			Name:      "go-discover-test-" + name,
+			Followees: []string{"go-discover-test-followee-" + name},
+		}))
+		require.NoError(t, neo.PutUser(&model.User{
+			Name: "go-discover-test-followee-" + name,
+			Stars: []model.StarredRepository{
+				{
+					Repository: "go-discover-test-" + name,
+					StarredAt:  time.Now().Unix(),
+				},
+			},
+		}))
+	}
+
+	since := time.Now().Add(time.Hour * 24 * -7)
+	for _, name := range neoOddNames {
+		gotCandidates, err := neo.GetUserCandidates(&model.User{
+			Name: "go-discover-test-" + name,
+		}, since)
+		require.NoError(t, err)
+		require.Len(t, gotCandidates, 1)
+		require.Equal(t, "go-discover-test-"+name, gotCandidates[0].Repository.Name)
+	}
+
+	// unfollowing removes the suggestions
+	for _, name := range neoOddNames {
+		require.NoError(t, neo.PutUser(&model.User{
+			Name:      "go-discover-test-" + name,
+			Followees: []string{},
+		}))
+		gotCandidates, err := neo.GetUserCandidates(&model.User{
+			Name: "go-discover-test-" + name,
+		}, since)
+		require.NoError(t, err)
+		require.Empty(t, gotCandidates)
+	}
+}
@@ -0,0 +1,1031 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+const (
+	graphSQLTopicKindLabel = "label"
+	// graphSQLTopicKindLanguage topics are replaced by graph_languages
+	graphSQLTopicKindLanguage = "language"
+
+	// graphSQLCandidateRepositories has the same semantics as
+	// neoGetCandidateRepositories, without the matching topics and languages
+	graphSQLCandidateRepositories = `
+		SELECT
+			s.repository_name AS repository,
+			count(*) AS followee_stars,
+			max(s.starred_at) AS last_starred_at
+		FROM graph_follows f
+		JOIN graph_stars s ON s.user_name = f.followee_name
+		WHERE f.user_name = ?
+			AND f.removed_at IS NULL
+			AND s.removed_at IS NULL
+			AND s.starred_at > ?
+		GROUP BY s.repository_name
+		ORDER BY followee_stars DESC, repository
+		LIMIT ?
+	`
+
+	// graphSQLCandidateStars returns the stars of the given repositories by
+	// the user's followees, along with the affinity of each followee, which
+	// is the number of repositories both they and the user starred
+	graphSQLCandidateStars = `
+		SELECT
+			s.repository_name AS repository,
+			s.user_name AS followee,
+			s.starred_at AS starred_at,
+			(
+				SELECT count(DISTINCT mine.repository_name)
+				FROM graph_stars mine
+				JOIN graph_stars theirs ON theirs.repository_name = mine.repository_name
+				WHERE mine.user_name = f.user_name
+					AND theirs.user_name = f.followee_name
+					AND mine.removed_at IS NULL
+					AND theirs.removed_at IS NULL
+			) AS affinity
+		FROM graph_follows f
+		JOIN graph_stars s ON s.user_name = f.followee_name
+		WHERE f.user_name = ?
+			AND f.removed_at IS NULL
+			AND s.removed_at IS NULL
+			AND s.starred_at > ?
+			AND s.repository_name IN (?)
+	`
+
+	// graphSQLFollowedByFollowees has the same semantics as
+	// neoGetFollowedByFollowees, without the shared topics
+	graphSQLFollowedByFollowees = `
+		SELECT other.followee_name AS name, count(DISTINCT f.followee_name) AS followees
+		FROM graph_follows f
+		JOIN graph_follows other ON other.user_name = f.followee_name
+		WHERE f.user_name = ?
+			AND f.removed_at IS NULL
+			AND other.removed_at IS NULL
+			AND other.followee_name <> f.user_name
+			AND other.followee_name NOT IN (
+				SELECT followee_name FROM graph_follows
+				WHERE user_name = ? AND removed_at IS NULL
+			)
+		GROUP BY other.followee_name
+		ORDER BY followees DESC, name
+		LIMIT ?
+	`
+
+	// graphSQLSharedTopics counts the topics of the stars of the given users
+	// that are also topics of the user's stars
+	graphSQLSharedTopics = `
+		SELECT s.user_name AS name, count(DISTINCT t.name) AS matches
+		FROM graph_stars s
+		JOIN graph_topics t ON t.repository_name = s.repository_name
+		WHERE s.user_name IN (?)
+			AND s.removed_at IS NULL
+			AND t.kind = ?
+			AND t.name IN (
+				SELECT starred.name FROM graph_topics starred
+				JOIN graph_stars mine ON mine.repository_name = starred.repository_name
+				WHERE mine.user_name = ? AND mine.removed_at IS NULL AND starred.kind = ?
+			)
+		GROUP BY s.user_name
+	`
+
+	// graphSQLMatchingTopics counts the topics of the given repositories that
+	// are also topics of the user's stars
+	graphSQLMatchingTopics = `
+		SELECT t.repository_name AS name, count(DISTINCT t.name) AS matches
+		FROM graph_topics t
+		WHERE t.repository_name IN (?)
+			AND t.kind = ?
+			AND t.name IN (
+				SELECT starred.name FROM graph_topics starred
+				JOIN graph_stars s ON s.repository_name = starred.repository_name
+				WHERE s.user_name = ? AND s.removed_at IS NULL AND starred.kind = ?
+			)
+		GROUP BY t.repository_name
+	`
+
+	// graphSQLMatchingLanguages counts the languages of the given
+	// repositories that are also languages of the user's stars
+	graphSQLMatchingLanguages = `
+		SELECT l.repository_name AS name, count(DISTINCT l.name) AS matches
+		FROM graph_languages l
+		WHERE l.repository_name IN (?)
+			AND l.name IN (
+				SELECT starred.name FROM graph_languages starred
+				JOIN graph_stars s ON s.repository_name = starred.repository_name
+				WHERE s.user_name = ? AND s.removed_at IS NULL
+			)
+		GROUP BY l.repository_name
+	`
+
+	// graphSQLCoStarredRepositories has the same semantics as
+	// neoGetCoStarredRepositories
+	graphSQLCoStarredRepositories = `
+		SELECT c.repository AS repository, count(*) AS stars
+		FROM (
+			SELECT DISTINCT
+				seed.repository_name AS seed,
+				other.user_name AS other,
+				candidate.repository_name AS repository
+			FROM graph_stars seed
+			JOIN graph_stars other ON other.repository_name = seed.repository_name
+			JOIN graph_stars candidate ON candidate.user_name = other.user_name
+			WHERE seed.user_name = ?
+				AND other.user_name <> seed.user_name
+				AND seed.removed_at IS NULL
+				AND other.removed_at IS NULL
+				AND candidate.removed_at IS NULL
+				AND candidate.repository_name NOT IN (
+					SELECT repository_name FROM graph_stars
+					WHERE user_name = ? AND removed_at IS NULL
+				)
+				AND candidate.repository_name NOT IN (
+					SELECT repository_name FROM graph_owns WHERE user_name = ?
+				)
+		) c
+		GROUP BY c.repository
+		ORDER BY stars DESC, repository
+		LIMIT ?
+	`
+)
+
+// graphSQLUser is a user node
+type graphSQLUser struct {
+	Name        string `gorm:"primary_key"`
+	DisplayName string
+	Bio         string
+	Company     string
+	Location    string
+	AvatarURL   string
+	Followers   int
+	CreatedAt   int64
+}
+
+func (graphSQLUser) TableName() string {
+	return "graph_users"
+}
+
+// graphSQLRepository is a repository node
+type graphSQLRepository struct {
+	Name            string `gorm:"primary_key"`
+	Description     string
+	Homepage        string
+	StargazersCount int
+	ForksCount      int
+	License         string
+	Archived        bool
+	Fork            bool
+	CreatedAt       int64
+	PushedAt        int64
+}
+
+func (graphSQLRepository) TableName() string {
+	return "graph_repositories"
+}
+
+// graphSQLFollow is an IsFollowing edge, removed edges are kept for history
+type graphSQLFollow struct {
+	ID           uint   `gorm:"primary_key"`
+	UserName     string `gorm:"index:graph_follows_user_name"`
+	FolloweeName string `gorm:"index:graph_follows_followee_name"`
+	SyncedAt     int64
+	RemovedAt    *int64
+}
+
+func (graphSQLFollow) TableName() string {
+	return "graph_follows"
+}
+
+// graphSQLStar is a HasStarred edge, removed edges are kept for history
+type graphSQLStar struct {
+	ID             uint   `gorm:"primary_key"`
+	UserName       string `gorm:"index:graph_stars_user_name"`
+	RepositoryName string `gorm:"index:graph_stars_repository_name"`
+	StarredAt      int64
+	SyncedAt       int64
+	RemovedAt      *int64
+}
+
+func (graphSQLStar) TableName() string {
+	return "graph_stars"
+}
+
+// graphSQLTopic is a label of a repository
+type graphSQLTopic struct {
+	RepositoryName string `gorm:"primary_key"`
+	Kind           string `gorm:"primary_key"`
+	Name           string `gorm:"primary_key"`
+}
+
+func (graphSQLTopic) TableName() string {
+	return "graph_topics"
+}
+
+// graphSQLLanguage is a WrittenIn edge
+type graphSQLLanguage struct {
+	RepositoryName string `gorm:"primary_key"`
+	Name           string `gorm:"primary_key"`
+	Bytes          int64
+	Share          float64
+}
+
+func (graphSQLLanguage) TableName() string {
+	return "graph_languages"
+}
+
+// graphSQLOwn is an Owns edge
+type graphSQLOwn struct {
+	UserName       string `gorm:"primary_key"`
+	RepositoryName string `gorm:"primary_key"`
+	CreatedAt      int64
+	Fork           bool
+}
+
+func (graphSQLOwn) TableName() string {
+	return "graph_owns"
+}
+
+var (
+	graphSQLModels = []interface{}{
+		&graphSQLUser{},
+		&graphSQLRepository{},
+		&graphSQLFollow{},
+		&graphSQLStar{},
+		&graphSQLTopic{},
+		&graphSQLLanguage{},
+		&graphSQLOwn{},
+	}
+)
+
+// GraphSQL is a GraphStore on top of a gorm db, meant for small deployments
+// that don't want to run neo
+// Its tables are prefixed with `graph_` so it can share the db of the
+// suggestion store
+type GraphSQL struct {
+	db *gorm.DB
+}
+
+// NewGraphSQL constructs a new GraphSQL store given a gorm db
+func NewGraphSQL(db *gorm.DB) (*GraphSQL, error) {
+	s := &GraphSQL{
+		db: db,
+	}
+
+	return s, nil
+}
+
+// Setup creates the graph tables
+func (s *GraphSQL) Setup() error {
+	res := s.db.AutoMigrate(graphSQLModels...)
+	return errors.Wrap(res.Error, "could not migrate tables")
+}
+
+// Close does nothing as the db is owned by the caller
+func (s *GraphSQL) Close() error {
+	return nil
+}
+
+// Cleanup drops the graph tables
+func (s *GraphSQL) Cleanup() error {
+	res := s.db.DropTableIfExists(graphSQLModels...)
+	return errors.Wrap(res.Error, "could not drop tables")
+}
+
+// PutRepository merges a repository's graph
+func (s *GraphSQL) PutRepository(repository *model.Repository) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":                     "store/GraphSQL.PutRepository",
+		"repository.name":            repository.Name,
+		"repository.stars.count":     len(repository.Stars),
+		"repository.labels.count":    len(repository.Labels),
+		"repository.languages.count": len(repository.Languages),
+	})
+
+	logger.Info("saving repository")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	err := s.transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Where(&graphSQLRepository{
+				Name: repository.Name,
+			}).
+			Assign(map[string]interface{}{
+				"description":      repository.Description,
+				"homepage":         repository.Homepage,
+				"stargazers_count": repository.StargazersCount,
+				"forks_count":      repository.ForksCount,
+				"license":          repository.License,
+				"archived":         repository.Archived,
+				"fork":             repository.Fork,
+				"created_at":       repository.CreatedAt,
+				"pushed_at":        repository.PushedAt,
+			}).
+			FirstOrCreate(&graphSQLRepository{})
+		if res.Error != nil {
+			return errors.Wrap(res.Error, "could not merge repository")
+		}
+
+		for _, name := range repository.Labels {
+			res := tx.FirstOrCreate(&graphSQLTopic{}, &graphSQLTopic{
+				RepositoryName: repository.Name,
+				Kind:           graphSQLTopicKindLabel,
+				Name:           name,
+			})
+			if res.Error != nil {
+				return errors.Wrap(res.Error, "could not merge topic")
+			}
+		}
+
+		// nil languages were not fetched, otherwise they replace the current
+		// ones
+		if repository.Languages != nil {
+			if err := graphSQLReplaceLanguages(tx, repository); err != nil {
+				return err
+			}
+		}
+
+		// nil stars were not fetched, so there is nothing to reconcile
+		if repository.Stars == nil {
+			return nil
+		}
+
+		syncedAt := startTime.UnixNano()
+		for _, star := range repository.Stars {
+			if err := graphSQLMergeUser(tx, star.User); err != nil {
+				return err
+			}
+			if err := graphSQLMergeStar(tx, star.User, repository.Name, star.StarredAt, syncedAt); err != nil {
+				return err
+			}
+		}
+
+		res = tx.
+			Model(&graphSQLStar{}).
+			Where("repository_name = ? AND removed_at IS NULL AND synced_at <> ?", repository.Name, syncedAt).
+			Update("removed_at", startTime.Unix())
+		return errors.Wrap(res.Error, "could not remove stars")
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not merge repo")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// PutUser merges a user's graph
+func (s *GraphSQL) PutUser(user *model.User) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":               "store/GraphSQL.PutUser",
+		"user.name":            user.Name,
+		"user.followees.count": len(user.Followees),
+		"user.stars.count":     len(user.Stars),
+		"user.owns.count":      len(user.Owns),
+	})
+
+	logger.Info("saving user")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	syncedAt := startTime.UnixNano()
+	removedAt := startTime.Unix()
+
+	err := s.transaction(func(tx *gorm.DB) error {
+		if err := graphSQLMergeUser(tx, user.Name); err != nil {
+			return err
+		}
+
+		// nil lists were not fetched, so there is nothing to reconcile
+		if user.Followees != nil {
+			for _, followee := range user.Followees {
+				if err := graphSQLMergeUser(tx, followee); err != nil {
+					return err
+				}
+				if err := graphSQLMergeFollow(tx, user.Name, followee, syncedAt); err != nil {
+					return err
+				}
+			}
+
+			res := tx.
+				Model(&graphSQLFollow{}).
+				Where("user_name = ? AND removed_at IS NULL AND synced_at <> ?", user.Name, syncedAt).
+				Update("removed_at", removedAt)
+			if res.Error != nil {
+				return errors.Wrap(res.Error, "could not remove follows")
+			}
+		}
+
+		if user.Stars != nil {
+			for _, star := range user.Stars {
+				if err := graphSQLMergeRepository(tx, star.Repository); err != nil {
+					return err
+				}
+				if err := graphSQLMergeStar(tx, user.Name, star.Repository, star.StarredAt, syncedAt); err != nil {
+					return err
+				}
+			}
+
+			res := tx.
+				Model(&graphSQLStar{}).
+				Where("user_name = ? AND removed_at IS NULL AND synced_at <> ?", user.Name, syncedAt).
+				Update("removed_at", removedAt)
+			if res.Error != nil {
+				return errors.Wrap(res.Error, "could not remove stars")
+			}
+		}
+
+		for _, owned := range user.Owns {
+			if err := graphSQLMergeRepository(tx, owned.Repository); err != nil {
+				return err
+			}
+			res := tx.
+				Where(&graphSQLOwn{
+					UserName:       user.Name,
+					RepositoryName: owned.Repository,
+				}).
+				Assign(map[string]interface{}{
+					"created_at": owned.CreatedAt,
+					"fork":       owned.Fork,
+				}).
+				FirstOrCreate(&graphSQLOwn{})
+			if res.Error != nil {
+				return errors.Wrap(res.Error, "could not merge owned repository")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not merge user")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// PutUserProfile sets the profile properties of a user
+func (s *GraphSQL) PutUserProfile(profile *model.UserProfile) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphSQL.PutUserProfile",
+		"user.name": profile.Name,
+	})
+
+	logger.Info("saving user profile")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	res := s.db.
+		Where(&graphSQLUser{
+			Name: profile.Name,
+		}).
+		Assign(map[string]interface{}{
+			"display_name": profile.DisplayName,
+			"bio":          profile.Bio,
+			"company":      profile.Company,
+			"location":     profile.Location,
+			"avatar_url":   profile.AvatarURL,
+			"followers":    profile.Followers,
+			"created_at":   profile.CreatedAt,
+		}).
+		FirstOrCreate(&graphSQLUser{})
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "could not set user profile")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	return nil
+}
+
+// GetUserCandidates returns the repositories starred by the user's followees
+// since the given time, the most starred first
+func (s *GraphSQL) GetUserCandidates(
+	user *model.User,
+	since time.Time,
+) ([]*model.SuggestionCandidate, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphSQL.GetUserCandidates",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user candidates")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	res := []struct {
+		Repository    string
+		FolloweeStars int64
+		LastStarredAt int64
+	}{}
+
+	err := s.db.
+		Raw(
+			graphSQLCandidateRepositories,
+			user.Name,
+			since.Unix(),
+			GraphCandidateLimit,
+		).
+		Scan(&res).
+		Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not run query")
+	}
+
+	names := make([]string, len(res))
+	for k := range res {
+		names[k] = res[k].Repository
+	}
+
+	details, err := s.repositoryDetails(names)
+	if err != nil {
+		return nil, err
+	}
+
+	stars := []struct {
+		Repository string
+		Followee   string
+		StarredAt  int64
+		Affinity   int64
+	}{}
+
+	err = s.db.
+		Raw(graphSQLCandidateStars, user.Name, since.Unix(), names).
+		Scan(&stars).
+		Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get candidate stars")
+	}
+
+	candidateStars := map[string][]model.CandidateStar{}
+	for _, star := range stars {
+		candidateStars[star.Repository] = append(
+			candidateStars[star.Repository],
+			model.CandidateStar{
+				Followee:  star.Followee,
+				StarredAt: star.StarredAt,
+				Affinity:  star.Affinity,
+			},
+		)
+	}
+
+	topics, err := s.matches(
+		graphSQLMatchingTopics,
+		names,
+		graphSQLTopicKindLabel,
+		user.Name,
+		graphSQLTopicKindLabel,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get matching topics")
+	}
+
+	languages, err := s.matches(graphSQLMatchingLanguages, names, user.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get matching languages")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	candidates := make([]*model.SuggestionCandidate, len(res))
+	for k := range res {
+		candidates[k] = &model.SuggestionCandidate{
+			Repository:        details[res[k].Repository],
+			FolloweeStars:     res[k].FolloweeStars,
+			Stars:             candidateStars[res[k].Repository],
+			LastStarredAt:     res[k].LastStarredAt,
+			MatchingTopics:    topics[res[k].Repository],
+			MatchingLanguages: languages[res[k].Repository],
+		}
+	}
+
+	return candidates, nil
+}
+
+// GetUserCoStarredSuggestion returns the repositories starred most by users
+// who also starred one of the user's stars
+func (s *GraphSQL) GetUserCoStarredSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphSQL.GetUserCoStarredSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user co-starred suggestion")
+
+	return s.suggestion(
+		logger,
+		user,
+		"%d co-stars with repositories you starred",
+		graphSQLCoStarredRepositories,
+		user.Name,
+		user.Name,
+		user.Name,
+		GraphSuggestionLimit,
+	)
+}
+
+// GetUserFollowSuggestion returns the users followed by most of the user's
+// followees, whom the user does not follow yet
+func (s *GraphSQL) GetUserFollowSuggestion(user *model.User) (*model.Suggestion, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphSQL.GetUserFollowSuggestion",
+		"user.name": user.Name,
+	})
+
+	logger.Info("get user follow suggestion")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	res := []struct {
+		Name      string
+		Followees int64
+	}{}
+
+	err := s.db.
+		Raw(
+			graphSQLFollowedByFollowees,
+			user.Name,
+			user.Name,
+			GraphSuggestionLimit,
+		).
+		Scan(&res).
+		Error
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not run query")
+	}
+
+	names := make([]string, len(res))
+	for k := range res {
+		names[k] = res[k].Name
+	}
+
+	profiles := []graphSQLUser{}
+	err = s.db.
+		Where("name IN (?)", names).
+		Find(&profiles).
+		Error
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not get users")
+	}
+
+	details := map[string]graphSQLUser{}
+	for _, profile := range profiles {
+		details[profile.Name] = profile
+	}
+
+	topics, err := s.matches(
+		graphSQLSharedTopics,
+		names,
+		graphSQLTopicKindLabel,
+		user.Name,
+		graphSQLTopicKindLabel,
+	)
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not get shared topics")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	suggestions := make([]model.SuggestionItem, len(res))
+	for k := range res {
+		profile := details[res[k].Name]
+		suggestions[k] = model.SuggestionItem{
+			Type:   model.SuggestionTypeFollowUser,
+			Value:  res[k].Name,
+			Reason: followReason(res[k].Followees, topics[res[k].Name]),
+			User: &model.UserProfile{
+				Name:        res[k].Name,
+				DisplayName: profile.DisplayName,
+				Bio:         profile.Bio,
+				Company:     profile.Company,
+				Location:    profile.Location,
+				AvatarURL:   profile.AvatarURL,
+				Followers:   profile.Followers,
+				CreatedAt:   profile.CreatedAt,
+			},
+		}
+	}
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    suggestions,
+	}, nil
+}
+
+// GetUserRepositories returns a user with the repositories they starred and
+// own
+func (s *GraphSQL) GetUserRepositories(name string) (*model.User, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"logger":    "store/GraphSQL.GetUserRepositories",
+		"user.name": name,
+	})
+
+	logger.Info("get user repositories")
+
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	stars := []graphSQLStar{}
+	res := s.db.
+		Where("user_name = ? AND removed_at IS NULL", name).
+		Find(&stars)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, "could not get stars")
+	}
+
+	owns := []graphSQLOwn{}
+	res = s.db.
+		Where("user_name = ?", name).
+		Find(&owns)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, "could not get owned repositories")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	user := &model.User{
+		Name:  name,
+		Stars: make([]model.StarredRepository, len(stars)),
+		Owns:  make([]model.OwnedRepository, len(owns)),
+	}
+	for i, star := range stars {
+		user.Stars[i] = model.StarredRepository{
+			Repository: star.RepositoryName,
+			StarredAt:  star.StarredAt,
+		}
+	}
+	for i, owned := range owns {
+		user.Owns[i] = model.OwnedRepository{
+			Repository: owned.RepositoryName,
+			CreatedAt:  owned.CreatedAt,
+			Fork:       owned.Fork,
+		}
+	}
+
+	return user, nil
+}
+
+// suggestion runs a query returning repositories and their stars, reasonFormat
+// formats the stars of each suggested repository
+func (s *GraphSQL) suggestion(
+	logger *logrus.Entry,
+	user *model.User,
+	reasonFormat string,
+	query string,
+	args ...interface{},
+) (*model.Suggestion, error) {
+	// keep start time for query metrics
+	startTime := time.Now()
+
+	res := []struct {
+		Repository string
+		Stars      int
+	}{}
+
+	err := s.db.
+		Raw(query, args...).
+		Scan(&res).
+		Error
+	if err != nil {
+		return &model.Suggestion{}, errors.Wrap(err, "could not run query")
+	}
+
+	// log query time
+	logger.
+		WithField("execution_time", time.Now().Sub(startTime)).
+		Debug("query execution finished")
+
+	names := make([]string, len(res))
+	for k := range res {
+		names[k] = res[k].Repository
+	}
+
+	details, err := s.repositoryDetails(names)
+	if err != nil {
+		return &model.Suggestion{}, err
+	}
+
+	suggestions := make([]model.SuggestionItem, len(res))
+
+	for k := range res {
+		suggestions[k] = model.SuggestionItem{
+			Type:       "repository",
+			Value:      res[k].Repository,
+			Reason:     fmt.Sprintf(reasonFormat, res[k].Stars),
+			Repository: details[res[k].Repository],
+		}
+	}
+
+	return &model.Suggestion{
+		UserID:   user.Name,
+		DateTime: time.Now(),
+		Items:    suggestions,
+	}, nil
+}
+
+// repositoryDetails returns the repositories with the given names, the ones
+// that were never fetched have only their name set
+func (s *GraphSQL) repositoryDetails(names []string) (map[string]*model.Repository, error) {
+	repositories := []graphSQLRepository{}
+	err := s.db.
+		Where("name IN (?)", names).
+		Find(&repositories).
+		Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get repositories")
+	}
+
+	details := map[string]*model.Repository{}
+	for _, name := range names {
+		details[name] = &model.Repository{
+			Name: name,
+		}
+	}
+	for _, repository := range repositories {
+		details[repository.Name] = &model.Repository{
+			Name:            repository.Name,
+			Description:     repository.Description,
+			Homepage:        repository.Homepage,
+			StargazersCount: repository.StargazersCount,
+			ForksCount:      repository.ForksCount,
+			License:         repository.License,
+			Archived:        repository.Archived,
+			Fork:            repository.Fork,
+			CreatedAt:       repository.CreatedAt,
+			PushedAt:        repository.PushedAt,
+		}
+	}
+
+	return details, nil
+}
+
+// matches runs a query returning names and their number of matches
+func (s *GraphSQL) matches(
+	query string,
+	args ...interface{},
+) (map[string]int64, error) {
+	res := []struct {
+		Name    string
+		Matches int64
+	}{}
+
+	err := s.db.
+		Raw(query, args...).
+		Scan(&res).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	matches := map[string]int64{}
+	for _, row := range res {
+		matches[row.Name] = row.Matches
+	}
+
+	return matches, nil
+}
+
+// transaction runs the given function in a transaction, committing it if the
+// function succeeds
+func (s *GraphSQL) transaction(fn func(tx *gorm.DB) error) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "could not begin transaction")
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return errors.Wrap(tx.Commit().Error, "could not commit transaction")
+}
+
+// graphSQLMergeUser creates a user node if it does not exist
+func graphSQLMergeUser(tx *gorm.DB, name string) error {
+	res := tx.FirstOrCreate(&graphSQLUser{}, &graphSQLUser{
+		Name: name,
+	})
+	return errors.Wrap(res.Error, "could not merge user")
+}
+
+// graphSQLMergeRepository creates a repository node if it does not exist
+func graphSQLMergeRepository(tx *gorm.DB, name string) error {
+	res := tx.FirstOrCreate(&graphSQLRepository{}, &graphSQLRepository{
+		Name: name,
+	})
+	return errors.Wrap(res.Error, "could not merge repository")
+}
+
+// graphSQLMergeFollow marks a follow as synced, creating it if needed
+func graphSQLMergeFollow(tx *gorm.DB, user, followee string, syncedAt int64) error {
+	res := tx.
+		Model(&graphSQLFollow{}).
+		Where("user_name = ? AND followee_name = ? AND removed_at IS NULL", user, followee).
+		Update("synced_at", syncedAt)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "could not sync follow")
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	res = tx.Create(&graphSQLFollow{
+		UserName:     user,
+		FolloweeName: followee,
+		SyncedAt:     syncedAt,
+	})
+	return errors.Wrap(res.Error, "could not create follow")
+}
+
+// graphSQLMergeStar marks a star as synced, creating it if needed
+func graphSQLMergeStar(tx *gorm.DB, user, repository string, starredAt, syncedAt int64) error {
+	res := tx.
+		Model(&graphSQLStar{}).
+		Where(
+			"user_name = ? AND repository_name = ? AND starred_at = ? AND removed_at IS NULL",
+			user,
+			repository,
+			starredAt,
+		).
+		Update("synced_at", syncedAt)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "could not sync star")
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	res = tx.Create(&graphSQLStar{
+		UserName:       user,
+		RepositoryName: repository,
+		StarredAt:      starredAt,
+		SyncedAt:       syncedAt,
+	})
+	return errors.Wrap(res.Error, "could not create star")
+}
+
+// graphSQLReplaceLanguages replaces the languages of a repository, along with
+// the language topics written before graph_languages existed
+func graphSQLReplaceLanguages(tx *gorm.DB, repository *model.Repository) error {
+	res := tx.
+		Where("repository_name = ? AND kind = ?", repository.Name, graphSQLTopicKindLanguage).
+		Delete(&graphSQLTopic{})
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "could not remove language topics")
+	}
+
+	res = tx.
+		Where("repository_name = ?", repository.Name).
+		Delete(&graphSQLLanguage{})
+	if res.Error != nil {
+		return errors.Wrap(res.Error, "could not remove languages")
+	}
+
+	for _, language := range repository.Languages {
+		res := tx.Create(&graphSQLLanguage{
+			RepositoryName: repository.Name,
+			Name:           language.Name,
+			Bytes:          language.Bytes,
+			Share:          language.Share,
+		})
+		if res.Error != nil {
+			return errors.Wrap(res.Error, "could not create language")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+func TestGraphSQL_PutUserProfile(t *testing.T) {
+	s, err := NewGraphSQL(getDB(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Setup())
+
+	// profiles of existing users are updated
+	require.NoError(t, s.PutUser(&model.User{
+		Name: "foo",
+	}))
+	require.NoError(t, s.PutUserProfile(&model.UserProfile{
+		Name:      "foo",
+		Bio:       "bar",
+		AvatarURL: "https://avatars/foo",
+		Followers: 2,
+		CreatedAt: 3,
+	}))
+
+	gotUser := &graphSQLUser{}
+	require.NoError(t, s.db.First(gotUser, "name = ?", "foo").Error)
+	require.Equal(t, &graphSQLUser{
+		Name:      "foo",
+		Bio:       "bar",
+		AvatarURL: "https://avatars/foo",
+		Followers: 2,
+		CreatedAt: 3,
+	}, gotUser)
+
+	// profiles of new users create them
+	require.NoError(t, s.PutUserProfile(&model.UserProfile{
+		Name: "bar",
+	}))
+	gotUser = &graphSQLUser{}
+	require.NoError(t, s.db.First(gotUser, "name = ?", "bar").Error)
+	require.Equal(t, int64(0), gotUser.CreatedAt)
+}
+
+func TestGraphSQL_PutUser(t *testing.T) {
+	s, err := NewGraphSQL(getDB(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Setup())
+
+	require.NoError(t, s.PutUser(&model.User{
+		Name: "foo",
+		Owns: []model.OwnedRepository{
+			{
+				Repository: "foo/bar",
+				CreatedAt:  1,
+			},
+		},
+	}))
+	require.NoError(t, s.PutUser(&model.User{
+		Name: "foo",
+		Owns: []model.OwnedRepository{
+			{
+				Repository: "foo/bar",
+				CreatedAt:  1,
+				Fork:       true,
+			},
+		},
+	}))
+
+	gotOwns := []graphSQLOwn{}
+	require.NoError(t, s.db.Find(&gotOwns).Error)
+	require.Equal(t, []graphSQLOwn{
+		{
+			UserName:       "foo",
+			RepositoryName: "foo/bar",
+			CreatedAt:      1,
+			Fork:           true,
+		},
+	}, gotOwns)
+
+	// removed edges are kept with the time they were removed
+	require.NoError(t, s.PutUser(&model.User{
+		Name:      "foo",
+		Followees: []string{"bar"},
+	}))
+	require.NoError(t, s.PutUser(&model.User{
+		Name:      "foo",
+		Followees: []string{},
+	}))
+
+	gotFollows := []graphSQLFollow{}
+	require.NoError(t, s.db.Find(&gotFollows).Error)
+	require.Len(t, gotFollows, 1)
+	require.NotNil(t, gotFollows[0].RemovedAt)
+}
+
+func TestGraphSQL_PutRepositoryLanguages(t *testing.T) {
+	s, err := NewGraphSQL(getDB(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Setup())
+
+	getLanguages := func() []graphSQLLanguage {
+		languages := []graphSQLLanguage{}
+		require.NoError(t, s.db.Order("name").Find(&languages).Error)
+		return languages
+	}
+
+	require.NoError(t, s.PutRepository(&model.Repository{
+		Name: "foo/bar",
+		Languages: []model.RepositoryLanguage{
+			{Name: "Go", Bytes: 300, Share: 0.75},
+			{Name: "Shell", Bytes: 100, Share: 0.25},
+		},
+	}))
+	require.Equal(t, []graphSQLLanguage{
+		{RepositoryName: "foo/bar", Name: "Go", Bytes: 300, Share: 0.75},
+		{RepositoryName: "foo/bar", Name: "Shell", Bytes: 100, Share: 0.25},
+	}, getLanguages())
+
+	// languages that were not fetched are left as they are
+	require.NoError(t, s.PutRepository(&model.Repository{
+		Name: "foo/bar",
+	}))
+	require.Len(t, getLanguages(), 2)
+
+	// fetched languages replace the current ones
+	require.NoError(t, s.PutRepository(&model.Repository{
+		Name: "foo/bar",
+		Languages: []model.RepositoryLanguage{
+			{Name: "Go", Bytes: 400, Share: 1},
+		},
+	}))
+	require.Equal(t, []graphSQLLanguage{
+		{RepositoryName: "foo/bar", Name: "Go", Bytes: 400, Share: 1},
+	}, getLanguages())
+}
@@ -0,0 +1,677 @@
+package store
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/jinzhu/gorm/dialects/postgres" // required for postgres
+
+	"github.com/kbariotis/go-discover/internal/model"
+)
+
+// testGraphStore is the contract every GraphStore implementation must pass
+// Names are prefixed with a random id so that it can run against a shared
+// database
+func testGraphStore(t *testing.T, newStore func(t *testing.T) GraphStore) {
+	now := time.Now().Unix()
+	old := time.Now().Add(time.Hour * 24 * -8).Unix()
+	since := time.Now().Add(time.Hour * 24 * -7)
+
+	getPrefix := func(t *testing.T) string {
+		id, err := uuid.NewV4()
+		require.NoError(t, err)
+		return "go-discover-test-" + id.String() + "-"
+	}
+
+	getNames := func(candidates []*model.SuggestionCandidate) []string {
+		names := []string{}
+		for _, candidate := range candidates {
+			names = append(names, candidate.Repository.Name)
+		}
+		return names
+	}
+
+	getValues := func(suggestion *model.Suggestion) []string {
+		values := []string{}
+		for _, item := range suggestion.Items {
+			values = append(values, item.Value)
+		}
+		return values
+	}
+
+	t.Run("suggestions", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a", p + "b", p + "c"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "popular", StarredAt: now},
+				{Repository: p + "other", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "b",
+			Stars: []model.StarredRepository{
+				{Repository: p + "popular", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name: p + "popular",
+			Stars: []model.UserStar{
+				{User: p + "a", StarredAt: now},
+				{User: p + "b", StarredAt: now},
+				{User: p + "c", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "c",
+			Stars: []model.StarredRepository{
+				{Repository: p + "popular", StarredAt: now},
+				{Repository: p + "old", StarredAt: old},
+			},
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Equal(t, []string{p + "popular", p + "other"}, getNames(gotCandidates))
+		require.Equal(t, int64(3), gotCandidates[0].FolloweeStars)
+		require.Equal(t, now, gotCandidates[0].LastStarredAt)
+		require.Equal(t, int64(1), gotCandidates[1].FolloweeStars)
+	})
+
+	t.Run("candidates order", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		// repository i is starred by i followees
+		n := 7
+		followees := []string{}
+		for i := 1; i <= n; i++ {
+			followee := p + "followee-" + strconv.Itoa(i)
+			followees = append(followees, followee)
+			stars := []model.StarredRepository{}
+			for j := i; j <= n; j++ {
+				stars = append(stars, model.StarredRepository{
+					Repository: p + "repository-" + strconv.Itoa(j),
+					StarredAt:  now,
+				})
+			}
+			require.NoError(t, s.PutUser(&model.User{
+				Name:  followee,
+				Stars: stars,
+			}))
+		}
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: followees,
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			p + "repository-7",
+			p + "repository-6",
+			p + "repository-5",
+			p + "repository-4",
+			p + "repository-3",
+			p + "repository-2",
+			p + "repository-1",
+		}, getNames(gotCandidates))
+	})
+
+	t.Run("candidates limit", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		stars := []model.StarredRepository{}
+		for i := 0; i <= GraphCandidateLimit; i++ {
+			stars = append(stars, model.StarredRepository{
+				Repository: p + "repository-" + strconv.Itoa(1000+i),
+				StarredAt:  now,
+			})
+		}
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name:  p + "a",
+			Stars: stars,
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		names := getNames(gotCandidates)
+		require.Len(t, names, GraphCandidateLimit)
+		// ties are broken by name
+		require.Equal(t, stars[0].Repository, names[0])
+		require.Equal(t, stars[GraphCandidateLimit-1].Repository, names[GraphCandidateLimit-1])
+	})
+
+	t.Run("candidate features", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a"},
+			Stars: []model.StarredRepository{
+				{Repository: p + "mine", StarredAt: old},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "candidate", StarredAt: old},
+				{Repository: p + "candidate", StarredAt: now},
+				// shared with the user before the window
+				{Repository: p + "mine", StarredAt: old - 100},
+			},
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:   p + "mine",
+			Labels: []string{p + "go", p + "graphs"},
+			Languages: []model.RepositoryLanguage{
+				{Name: p + "Go", Bytes: 10, Share: 1},
+			},
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:            p + "candidate",
+			StargazersCount: 42,
+			Labels:          []string{p + "go", p + "graphs", p + "cli"},
+			Languages: []model.RepositoryLanguage{
+				{Name: p + "Go", Bytes: 8, Share: 0.8},
+				{Name: p + "Shell", Bytes: 2, Share: 0.2},
+			},
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, time.Unix(old-1, 0))
+		require.NoError(t, err)
+		require.Len(t, gotCandidates, 1)
+		require.Equal(t, p+"candidate", gotCandidates[0].Repository.Name)
+		require.Equal(t, 42, gotCandidates[0].Repository.StargazersCount)
+		require.Equal(t, int64(2), gotCandidates[0].FolloweeStars)
+		require.Equal(t, now, gotCandidates[0].LastStarredAt)
+		require.Equal(t, int64(2), gotCandidates[0].MatchingTopics)
+		require.Equal(t, int64(1), gotCandidates[0].MatchingLanguages)
+		require.ElementsMatch(t, []model.CandidateStar{
+			{Followee: p + "a", StarredAt: old, Affinity: 1},
+			{Followee: p + "a", StarredAt: now, Affinity: 1},
+		}, gotCandidates[0].Stars)
+	})
+
+	t.Run("user repositories", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "user",
+			Stars: []model.StarredRepository{{
+				Repository: p + "starred",
+				StarredAt:  now,
+			}},
+			Owns: []model.OwnedRepository{{
+				Repository: p + "owned",
+				CreatedAt:  now,
+			}},
+		}))
+
+		gotUser, err := s.GetUserRepositories(p + "user")
+		require.NoError(t, err)
+		require.Equal(t, p+"user", gotUser.Name)
+		require.Nil(t, gotUser.Followees)
+		require.Equal(t, []model.StarredRepository{{
+			Repository: p + "starred",
+			StarredAt:  now,
+		}}, gotUser.Stars)
+		require.Len(t, gotUser.Owns, 1)
+		require.Equal(t, p+"owned", gotUser.Owns[0].Repository)
+
+		gotUser, err = s.GetUserRepositories(p + "unknown")
+		require.NoError(t, err)
+		require.Empty(t, gotUser.Stars)
+		require.Empty(t, gotUser.Owns)
+	})
+
+	t.Run("unfollow", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a", p + "b"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "from-a", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "b",
+			Stars: []model.StarredRepository{
+				{Repository: p + "from-b", StarredAt: now},
+			},
+		}))
+
+		// followees that were not fetched are left as they are
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "user",
+		}))
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{p + "from-a", p + "from-b"}, getNames(gotCandidates))
+
+		// unfollowed users no longer contribute
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "b"},
+		}))
+		gotCandidates, err = s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Equal(t, []string{p + "from-b"}, getNames(gotCandidates))
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{},
+		}))
+		gotCandidates, err = s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Empty(t, gotCandidates)
+	})
+
+	t.Run("unstar", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "first", StarredAt: now},
+				{Repository: p + "second", StarredAt: now},
+				{Repository: p + "third", StarredAt: now},
+			},
+		}))
+
+		// unstarred by the user
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "first", StarredAt: now},
+				{Repository: p + "second", StarredAt: now},
+			},
+		}))
+
+		// unstarred according to the repository
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:  p + "second",
+			Stars: []model.UserStar{},
+		}))
+
+		// stars that were not fetched are left as they are
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name: p + "first",
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Equal(t, []string{p + "first"}, getNames(gotCandidates))
+	})
+
+	t.Run("odd names", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		stars := []model.StarredRepository{}
+		for _, name := range neoOddNames {
+			stars = append(stars, model.StarredRepository{
+				Repository: p + name,
+				StarredAt:  now,
+			})
+		}
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + neoOddNames[0],
+			Followees: []string{p + neoOddNames[1]},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name:  p + neoOddNames[1],
+			Stars: stars[:5],
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + neoOddNames[0],
+		}, since)
+		require.NoError(t, err)
+		expected := []string{}
+		for _, star := range stars[:5] {
+			expected = append(expected, star.Repository)
+		}
+		require.ElementsMatch(t, expected, getNames(gotCandidates))
+	})
+
+	t.Run("repository details", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "fetched", StarredAt: now},
+				{Repository: p + "not-fetched", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:            p + "fetched",
+			Description:     "a repository",
+			Homepage:        "https://example.com",
+			StargazersCount: 10,
+			ForksCount:      2,
+			License:         "MIT",
+			Archived:        true,
+			Fork:            true,
+			CreatedAt:       old,
+			PushedAt:        now,
+		}))
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []*model.Repository{
+			{
+				Name:            p + "fetched",
+				Description:     "a repository",
+				Homepage:        "https://example.com",
+				StargazersCount: 10,
+				ForksCount:      2,
+				License:         "MIT",
+				Archived:        true,
+				Fork:            true,
+				CreatedAt:       old,
+				PushedAt:        now,
+			},
+			{
+				Name: p + "not-fetched",
+			},
+		}, []*model.Repository{
+			gotCandidates[0].Repository,
+			gotCandidates[1].Repository,
+		})
+	})
+
+	t.Run("co-starred", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "user",
+			Stars: []model.StarredRepository{
+				{Repository: p + "seed-1", StarredAt: old},
+				{Repository: p + "seed-2", StarredAt: old},
+			},
+			Owns: []model.OwnedRepository{
+				{Repository: p + "owned"},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "a",
+			Stars: []model.StarredRepository{
+				{Repository: p + "seed-1", StarredAt: old},
+				// starred twice, counted once
+				{Repository: p + "both", StarredAt: old},
+				{Repository: p + "both", StarredAt: now},
+				{Repository: p + "one", StarredAt: now},
+				{Repository: p + "owned", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "b",
+			Stars: []model.StarredRepository{
+				{Repository: p + "seed-1", StarredAt: old},
+				{Repository: p + "seed-2", StarredAt: old},
+				{Repository: p + "both", StarredAt: now},
+			},
+		}))
+		// stars of users who starred nothing in common are ignored
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "c",
+			Stars: []model.StarredRepository{
+				{Repository: p + "unrelated", StarredAt: now},
+			},
+		}))
+
+		gotSuggestion, err := s.GetUserCoStarredSuggestion(&model.User{
+			Name: p + "user",
+		})
+		require.NoError(t, err)
+		require.Equal(t, p+"user", gotSuggestion.UserID)
+		require.Equal(t, []string{p + "both", p + "one"}, getValues(gotSuggestion))
+		require.Equal(t, "3 co-stars with repositories you starred", gotSuggestion.Items[0].Reason)
+		require.Equal(t, "1 co-stars with repositories you starred", gotSuggestion.Items[1].Reason)
+
+		// unknown users have no stars to start from
+		gotSuggestion, err = s.GetUserCoStarredSuggestion(&model.User{
+			Name: p + "unknown",
+		})
+		require.NoError(t, err)
+		require.Empty(t, gotSuggestion.Items)
+	})
+
+	t.Run("follow suggestions", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "user",
+			Followees: []string{p + "a", p + "b", p + "c"},
+			Stars: []model.StarredRepository{
+				{Repository: p + "mine", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "a",
+			Followees: []string{p + "x", p + "y", p + "user"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "b",
+			Followees: []string{p + "x", p + "c", p + "z"},
+		}))
+		// unfollowed users are not suggested
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "b",
+			Followees: []string{p + "x", p + "c"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name:      p + "c",
+			Followees: []string{p + "x"},
+		}))
+		require.NoError(t, s.PutUser(&model.User{
+			Name: p + "x",
+			Stars: []model.StarredRepository{
+				{Repository: p + "theirs", StarredAt: now},
+			},
+		}))
+		require.NoError(t, s.PutUserProfile(&model.UserProfile{
+			Name:        p + "x",
+			DisplayName: "Ex",
+			Bio:         "builds things",
+			Followers:   12,
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:   p + "mine",
+			Labels: []string{p + "go", p + "graphs"},
+		}))
+		require.NoError(t, s.PutRepository(&model.Repository{
+			Name:   p + "theirs",
+			Labels: []string{p + "go", p + "graphs", p + "cli"},
+		}))
+
+		gotSuggestion, err := s.GetUserFollowSuggestion(&model.User{
+			Name: p + "user",
+		})
+		require.NoError(t, err)
+		require.Equal(t, p+"user", gotSuggestion.UserID)
+		require.Equal(t, []model.SuggestionItem{
+			{
+				Type:   model.SuggestionTypeFollowUser,
+				Value:  p + "x",
+				Reason: "followed by 3 people you follow, 2 topics in common",
+				User: &model.UserProfile{
+					Name:        p + "x",
+					DisplayName: "Ex",
+					Bio:         "builds things",
+					Followers:   12,
+				},
+			},
+			{
+				Type:   model.SuggestionTypeFollowUser,
+				Value:  p + "y",
+				Reason: "followed by 1 people you follow",
+				User: &model.UserProfile{
+					Name: p + "y",
+				},
+			},
+		}, gotSuggestion.Items)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		s := newStore(t)
+		p := getPrefix(t)
+
+		gotCandidates, err := s.GetUserCandidates(&model.User{
+			Name: p + "user",
+		}, since)
+		require.NoError(t, err)
+		require.Empty(t, gotCandidates)
+
+		gotSuggestion, err := s.GetUserCoStarredSuggestion(&model.User{
+			Name: p + "user",
+		})
+		require.NoError(t, err)
+		require.Empty(t, gotSuggestion.Items)
+
+		gotSuggestion, err = s.GetUserFollowSuggestion(&model.User{
+			Name: p + "user",
+		})
+		require.NoError(t, err)
+		require.Empty(t, gotSuggestion.Items)
+	})
+}
+
+func TestGraphMemory_Contract(t *testing.T) {
+	testGraphStore(t, func(t *testing.T) GraphStore {
+		s, err := NewGraphMemory()
+		require.NoError(t, err)
+		return s
+	})
+}
+
+// TestNeo_Contract runs against a real neo4j, it only runs when
+// NEO4J_TEST_HOST is set
+func TestNeo_Contract(t *testing.T) {
+	host := os.Getenv("NEO4J_TEST_HOST")
+	if host == "" {
+		t.Skip("NEO4J_TEST_HOST not set")
+	}
+
+	neo := getNeo(t, host, 2)
+	require.NoError(t, neo.Migrate())
+
+	testGraphStore(t, func(t *testing.T) GraphStore {
+		return neo
+	})
+}
+
+func TestGraphSQL_Contract(t *testing.T) {
+	testGraphStore(t, func(t *testing.T) GraphStore {
+		s, err := NewGraphSQL(getDB(t))
+		require.NoError(t, err)
+		require.NoError(t, s.Setup())
+		return s
+	})
+}
+
+// TestGraphSQL_ContractPostgres runs against a real postgres, it only runs
+// when POSTGRES_TEST_DSN is set
+func TestGraphSQL_ContractPostgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+
+	db, err := gorm.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err := NewGraphSQL(db)
+	require.NoError(t, err)
+	require.NoError(t, s.Setup())
+
+	testGraphStore(t, func(t *testing.T) GraphStore {
+		return s
+	})
+}
+
+// TestBolt_Contract runs against a real neo4j over bolt, it only runs when
+// NEO4J_TEST_BOLT_URI is set
+func TestBolt_Contract(t *testing.T) {
+	uri := os.Getenv("NEO4J_TEST_BOLT_URI")
+	if uri == "" {
+		t.Skip("NEO4J_TEST_BOLT_URI not set")
+	}
+
+	driver, err := neo4j.NewDriverWithContext(
+		uri,
+		neo4j.BasicAuth(
+			os.Getenv("NEO4J_TEST_USERNAME"),
+			os.Getenv("NEO4J_TEST_PASSWORD"),
+			"",
+		),
+	)
+	require.NoError(t, err)
+
+	bolt, err := NewBolt(driver, "", 2)
+	require.NoError(t, err)
+	defer bolt.Close()
+	require.NoError(t, bolt.Migrate())
+
+	testGraphStore(t, func(t *testing.T) GraphStore {
+		return bolt
+	})
+}
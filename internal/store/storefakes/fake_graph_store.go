@@ -3,12 +3,76 @@ package storefakes
 
 import (
 	"sync"
+	"time"
 
 	"github.com/kbariotis/go-discover/internal/model"
 	"github.com/kbariotis/go-discover/internal/store"
 )
 
 type FakeGraphStore struct {
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct {
+	}
+	closeReturns struct {
+		result1 error
+	}
+	closeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GetUserCandidatesStub        func(*model.User, time.Time) ([]*model.SuggestionCandidate, error)
+	getUserCandidatesMutex       sync.RWMutex
+	getUserCandidatesArgsForCall []struct {
+		arg1 *model.User
+		arg2 time.Time
+	}
+	getUserCandidatesReturns struct {
+		result1 []*model.SuggestionCandidate
+		result2 error
+	}
+	getUserCandidatesReturnsOnCall map[int]struct {
+		result1 []*model.SuggestionCandidate
+		result2 error
+	}
+	GetUserCoStarredSuggestionStub        func(*model.User) (*model.Suggestion, error)
+	getUserCoStarredSuggestionMutex       sync.RWMutex
+	getUserCoStarredSuggestionArgsForCall []struct {
+		arg1 *model.User
+	}
+	getUserCoStarredSuggestionReturns struct {
+		result1 *model.Suggestion
+		result2 error
+	}
+	getUserCoStarredSuggestionReturnsOnCall map[int]struct {
+		result1 *model.Suggestion
+		result2 error
+	}
+	GetUserFollowSuggestionStub        func(*model.User) (*model.Suggestion, error)
+	getUserFollowSuggestionMutex       sync.RWMutex
+	getUserFollowSuggestionArgsForCall []struct {
+		arg1 *model.User
+	}
+	getUserFollowSuggestionReturns struct {
+		result1 *model.Suggestion
+		result2 error
+	}
+	getUserFollowSuggestionReturnsOnCall map[int]struct {
+		result1 *model.Suggestion
+		result2 error
+	}
+	GetUserRepositoriesStub        func(string) (*model.User, error)
+	getUserRepositoriesMutex       sync.RWMutex
+	getUserRepositoriesArgsForCall []struct {
+		arg1 string
+	}
+	getUserRepositoriesReturns struct {
+		result1 *model.User
+		result2 error
+	}
+	getUserRepositoriesReturnsOnCall map[int]struct {
+		result1 *model.User
+		result2 error
+	}
 	PutRepositoryStub        func(*model.Repository) error
 	putRepositoryMutex       sync.RWMutex
 	putRepositoryArgsForCall []struct {
@@ -31,25 +95,347 @@ type FakeGraphStore struct {
 	putUserReturnsOnCall map[int]struct {
 		result1 error
 	}
+	PutUserProfileStub        func(*model.UserProfile) error
+	putUserProfileMutex       sync.RWMutex
+	putUserProfileArgsForCall []struct {
+		arg1 *model.UserProfile
+	}
+	putUserProfileReturns struct {
+		result1 error
+	}
+	putUserProfileReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeGraphStore) Close() error {
+	fake.closeMutex.Lock()
+	ret, specificReturn := fake.closeReturnsOnCall[len(fake.closeArgsForCall)]
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct {
+	}{})
+	stub := fake.CloseStub
+	fakeReturns := fake.closeReturns
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeGraphStore) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeGraphStore) CloseCalls(stub func() error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = stub
+}
+
+func (fake *FakeGraphStore) CloseReturns(result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGraphStore) CloseReturnsOnCall(i int, result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	if fake.closeReturnsOnCall == nil {
+		fake.closeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGraphStore) GetUserCandidates(arg1 *model.User, arg2 time.Time) ([]*model.SuggestionCandidate, error) {
+	fake.getUserCandidatesMutex.Lock()
+	ret, specificReturn := fake.getUserCandidatesReturnsOnCall[len(fake.getUserCandidatesArgsForCall)]
+	fake.getUserCandidatesArgsForCall = append(fake.getUserCandidatesArgsForCall, struct {
+		arg1 *model.User
+		arg2 time.Time
+	}{arg1, arg2})
+	stub := fake.GetUserCandidatesStub
+	fakeReturns := fake.getUserCandidatesReturns
+	fake.recordInvocation("GetUserCandidates", []interface{}{arg1, arg2})
+	fake.getUserCandidatesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGraphStore) GetUserCandidatesCallCount() int {
+	fake.getUserCandidatesMutex.RLock()
+	defer fake.getUserCandidatesMutex.RUnlock()
+	return len(fake.getUserCandidatesArgsForCall)
+}
+
+func (fake *FakeGraphStore) GetUserCandidatesCalls(stub func(*model.User, time.Time) ([]*model.SuggestionCandidate, error)) {
+	fake.getUserCandidatesMutex.Lock()
+	defer fake.getUserCandidatesMutex.Unlock()
+	fake.GetUserCandidatesStub = stub
+}
+
+func (fake *FakeGraphStore) GetUserCandidatesArgsForCall(i int) (*model.User, time.Time) {
+	fake.getUserCandidatesMutex.RLock()
+	defer fake.getUserCandidatesMutex.RUnlock()
+	argsForCall := fake.getUserCandidatesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeGraphStore) GetUserCandidatesReturns(result1 []*model.SuggestionCandidate, result2 error) {
+	fake.getUserCandidatesMutex.Lock()
+	defer fake.getUserCandidatesMutex.Unlock()
+	fake.GetUserCandidatesStub = nil
+	fake.getUserCandidatesReturns = struct {
+		result1 []*model.SuggestionCandidate
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserCandidatesReturnsOnCall(i int, result1 []*model.SuggestionCandidate, result2 error) {
+	fake.getUserCandidatesMutex.Lock()
+	defer fake.getUserCandidatesMutex.Unlock()
+	fake.GetUserCandidatesStub = nil
+	if fake.getUserCandidatesReturnsOnCall == nil {
+		fake.getUserCandidatesReturnsOnCall = make(map[int]struct {
+			result1 []*model.SuggestionCandidate
+			result2 error
+		})
+	}
+	fake.getUserCandidatesReturnsOnCall[i] = struct {
+		result1 []*model.SuggestionCandidate
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestion(arg1 *model.User) (*model.Suggestion, error) {
+	fake.getUserCoStarredSuggestionMutex.Lock()
+	ret, specificReturn := fake.getUserCoStarredSuggestionReturnsOnCall[len(fake.getUserCoStarredSuggestionArgsForCall)]
+	fake.getUserCoStarredSuggestionArgsForCall = append(fake.getUserCoStarredSuggestionArgsForCall, struct {
+		arg1 *model.User
+	}{arg1})
+	stub := fake.GetUserCoStarredSuggestionStub
+	fakeReturns := fake.getUserCoStarredSuggestionReturns
+	fake.recordInvocation("GetUserCoStarredSuggestion", []interface{}{arg1})
+	fake.getUserCoStarredSuggestionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestionCallCount() int {
+	fake.getUserCoStarredSuggestionMutex.RLock()
+	defer fake.getUserCoStarredSuggestionMutex.RUnlock()
+	return len(fake.getUserCoStarredSuggestionArgsForCall)
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestionCalls(stub func(*model.User) (*model.Suggestion, error)) {
+	fake.getUserCoStarredSuggestionMutex.Lock()
+	defer fake.getUserCoStarredSuggestionMutex.Unlock()
+	fake.GetUserCoStarredSuggestionStub = stub
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestionArgsForCall(i int) *model.User {
+	fake.getUserCoStarredSuggestionMutex.RLock()
+	defer fake.getUserCoStarredSuggestionMutex.RUnlock()
+	argsForCall := fake.getUserCoStarredSuggestionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestionReturns(result1 *model.Suggestion, result2 error) {
+	fake.getUserCoStarredSuggestionMutex.Lock()
+	defer fake.getUserCoStarredSuggestionMutex.Unlock()
+	fake.GetUserCoStarredSuggestionStub = nil
+	fake.getUserCoStarredSuggestionReturns = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserCoStarredSuggestionReturnsOnCall(i int, result1 *model.Suggestion, result2 error) {
+	fake.getUserCoStarredSuggestionMutex.Lock()
+	defer fake.getUserCoStarredSuggestionMutex.Unlock()
+	fake.GetUserCoStarredSuggestionStub = nil
+	if fake.getUserCoStarredSuggestionReturnsOnCall == nil {
+		fake.getUserCoStarredSuggestionReturnsOnCall = make(map[int]struct {
+			result1 *model.Suggestion
+			result2 error
+		})
+	}
+	fake.getUserCoStarredSuggestionReturnsOnCall[i] = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestion(arg1 *model.User) (*model.Suggestion, error) {
+	fake.getUserFollowSuggestionMutex.Lock()
+	ret, specificReturn := fake.getUserFollowSuggestionReturnsOnCall[len(fake.getUserFollowSuggestionArgsForCall)]
+	fake.getUserFollowSuggestionArgsForCall = append(fake.getUserFollowSuggestionArgsForCall, struct {
+		arg1 *model.User
+	}{arg1})
+	stub := fake.GetUserFollowSuggestionStub
+	fakeReturns := fake.getUserFollowSuggestionReturns
+	fake.recordInvocation("GetUserFollowSuggestion", []interface{}{arg1})
+	fake.getUserFollowSuggestionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestionCallCount() int {
+	fake.getUserFollowSuggestionMutex.RLock()
+	defer fake.getUserFollowSuggestionMutex.RUnlock()
+	return len(fake.getUserFollowSuggestionArgsForCall)
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestionCalls(stub func(*model.User) (*model.Suggestion, error)) {
+	fake.getUserFollowSuggestionMutex.Lock()
+	defer fake.getUserFollowSuggestionMutex.Unlock()
+	fake.GetUserFollowSuggestionStub = stub
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestionArgsForCall(i int) *model.User {
+	fake.getUserFollowSuggestionMutex.RLock()
+	defer fake.getUserFollowSuggestionMutex.RUnlock()
+	argsForCall := fake.getUserFollowSuggestionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestionReturns(result1 *model.Suggestion, result2 error) {
+	fake.getUserFollowSuggestionMutex.Lock()
+	defer fake.getUserFollowSuggestionMutex.Unlock()
+	fake.GetUserFollowSuggestionStub = nil
+	fake.getUserFollowSuggestionReturns = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserFollowSuggestionReturnsOnCall(i int, result1 *model.Suggestion, result2 error) {
+	fake.getUserFollowSuggestionMutex.Lock()
+	defer fake.getUserFollowSuggestionMutex.Unlock()
+	fake.GetUserFollowSuggestionStub = nil
+	if fake.getUserFollowSuggestionReturnsOnCall == nil {
+		fake.getUserFollowSuggestionReturnsOnCall = make(map[int]struct {
+			result1 *model.Suggestion
+			result2 error
+		})
+	}
+	fake.getUserFollowSuggestionReturnsOnCall[i] = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserRepositories(arg1 string) (*model.User, error) {
+	fake.getUserRepositoriesMutex.Lock()
+	ret, specificReturn := fake.getUserRepositoriesReturnsOnCall[len(fake.getUserRepositoriesArgsForCall)]
+	fake.getUserRepositoriesArgsForCall = append(fake.getUserRepositoriesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetUserRepositoriesStub
+	fakeReturns := fake.getUserRepositoriesReturns
+	fake.recordInvocation("GetUserRepositories", []interface{}{arg1})
+	fake.getUserRepositoriesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGraphStore) GetUserRepositoriesCallCount() int {
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
+	return len(fake.getUserRepositoriesArgsForCall)
+}
+
+func (fake *FakeGraphStore) GetUserRepositoriesCalls(stub func(string) (*model.User, error)) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = stub
+}
+
+func (fake *FakeGraphStore) GetUserRepositoriesArgsForCall(i int) string {
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
+	argsForCall := fake.getUserRepositoriesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGraphStore) GetUserRepositoriesReturns(result1 *model.User, result2 error) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = nil
+	fake.getUserRepositoriesReturns = struct {
+		result1 *model.User
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGraphStore) GetUserRepositoriesReturnsOnCall(i int, result1 *model.User, result2 error) {
+	fake.getUserRepositoriesMutex.Lock()
+	defer fake.getUserRepositoriesMutex.Unlock()
+	fake.GetUserRepositoriesStub = nil
+	if fake.getUserRepositoriesReturnsOnCall == nil {
+		fake.getUserRepositoriesReturnsOnCall = make(map[int]struct {
+			result1 *model.User
+			result2 error
+		})
+	}
+	fake.getUserRepositoriesReturnsOnCall[i] = struct {
+		result1 *model.User
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGraphStore) PutRepository(arg1 *model.Repository) error {
 	fake.putRepositoryMutex.Lock()
 	ret, specificReturn := fake.putRepositoryReturnsOnCall[len(fake.putRepositoryArgsForCall)]
 	fake.putRepositoryArgsForCall = append(fake.putRepositoryArgsForCall, struct {
 		arg1 *model.Repository
 	}{arg1})
+	stub := fake.PutRepositoryStub
+	fakeReturns := fake.putRepositoryReturns
 	fake.recordInvocation("PutRepository", []interface{}{arg1})
 	fake.putRepositoryMutex.Unlock()
-	if fake.PutRepositoryStub != nil {
-		return fake.PutRepositoryStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putRepositoryReturns
 	return fakeReturns.result1
 }
 
@@ -101,15 +487,16 @@ func (fake *FakeGraphStore) PutUser(arg1 *model.User) error {
 	fake.putUserArgsForCall = append(fake.putUserArgsForCall, struct {
 		arg1 *model.User
 	}{arg1})
+	stub := fake.PutUserStub
+	fakeReturns := fake.putUserReturns
 	fake.recordInvocation("PutUser", []interface{}{arg1})
 	fake.putUserMutex.Unlock()
-	if fake.PutUserStub != nil {
-		return fake.PutUserStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putUserReturns
 	return fakeReturns.result1
 }
 
@@ -155,13 +542,86 @@ func (fake *FakeGraphStore) PutUserReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeGraphStore) PutUserProfile(arg1 *model.UserProfile) error {
+	fake.putUserProfileMutex.Lock()
+	ret, specificReturn := fake.putUserProfileReturnsOnCall[len(fake.putUserProfileArgsForCall)]
+	fake.putUserProfileArgsForCall = append(fake.putUserProfileArgsForCall, struct {
+		arg1 *model.UserProfile
+	}{arg1})
+	stub := fake.PutUserProfileStub
+	fakeReturns := fake.putUserProfileReturns
+	fake.recordInvocation("PutUserProfile", []interface{}{arg1})
+	fake.putUserProfileMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeGraphStore) PutUserProfileCallCount() int {
+	fake.putUserProfileMutex.RLock()
+	defer fake.putUserProfileMutex.RUnlock()
+	return len(fake.putUserProfileArgsForCall)
+}
+
+func (fake *FakeGraphStore) PutUserProfileCalls(stub func(*model.UserProfile) error) {
+	fake.putUserProfileMutex.Lock()
+	defer fake.putUserProfileMutex.Unlock()
+	fake.PutUserProfileStub = stub
+}
+
+func (fake *FakeGraphStore) PutUserProfileArgsForCall(i int) *model.UserProfile {
+	fake.putUserProfileMutex.RLock()
+	defer fake.putUserProfileMutex.RUnlock()
+	argsForCall := fake.putUserProfileArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGraphStore) PutUserProfileReturns(result1 error) {
+	fake.putUserProfileMutex.Lock()
+	defer fake.putUserProfileMutex.Unlock()
+	fake.PutUserProfileStub = nil
+	fake.putUserProfileReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGraphStore) PutUserProfileReturnsOnCall(i int, result1 error) {
+	fake.putUserProfileMutex.Lock()
+	defer fake.putUserProfileMutex.Unlock()
+	fake.PutUserProfileStub = nil
+	if fake.putUserProfileReturnsOnCall == nil {
+		fake.putUserProfileReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.putUserProfileReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeGraphStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	fake.getUserCandidatesMutex.RLock()
+	defer fake.getUserCandidatesMutex.RUnlock()
+	fake.getUserCoStarredSuggestionMutex.RLock()
+	defer fake.getUserCoStarredSuggestionMutex.RUnlock()
+	fake.getUserFollowSuggestionMutex.RLock()
+	defer fake.getUserFollowSuggestionMutex.RUnlock()
+	fake.getUserRepositoriesMutex.RLock()
+	defer fake.getUserRepositoriesMutex.RUnlock()
 	fake.putRepositoryMutex.RLock()
 	defer fake.putRepositoryMutex.RUnlock()
 	fake.putUserMutex.RLock()
 	defer fake.putUserMutex.RUnlock()
+	fake.putUserProfileMutex.RLock()
+	defer fake.putUserProfileMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
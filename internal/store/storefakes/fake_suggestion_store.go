@@ -3,6 +3,7 @@ package storefakes
 
 import (
 	"sync"
+	"time"
 
 	"github.com/kbariotis/go-discover/internal/model"
 	"github.com/kbariotis/go-discover/internal/store"
@@ -21,6 +22,19 @@ type FakeSuggestionStore struct {
 		result1 []*model.User
 		result2 error
 	}
+	GetLatestSuggestionForUserStub        func(string) (*model.Suggestion, error)
+	getLatestSuggestionForUserMutex       sync.RWMutex
+	getLatestSuggestionForUserArgsForCall []struct {
+		arg1 string
+	}
+	getLatestSuggestionForUserReturns struct {
+		result1 *model.Suggestion
+		result2 error
+	}
+	getLatestSuggestionForUserReturnsOnCall map[int]struct {
+		result1 *model.Suggestion
+		result2 error
+	}
 	GetSuggestionStub        func(uint) (*model.Suggestion, error)
 	getSuggestionMutex       sync.RWMutex
 	getSuggestionArgsForCall []struct {
@@ -34,6 +48,20 @@ type FakeSuggestionStore struct {
 		result1 *model.Suggestion
 		result2 error
 	}
+	GetSuggestionsForUserSinceStub        func(string, time.Time) ([]*model.Suggestion, error)
+	getSuggestionsForUserSinceMutex       sync.RWMutex
+	getSuggestionsForUserSinceArgsForCall []struct {
+		arg1 string
+		arg2 time.Time
+	}
+	getSuggestionsForUserSinceReturns struct {
+		result1 []*model.Suggestion
+		result2 error
+	}
+	getSuggestionsForUserSinceReturnsOnCall map[int]struct {
+		result1 []*model.Suggestion
+		result2 error
+	}
 	GetUserStub        func(string) (*model.User, error)
 	getUserMutex       sync.RWMutex
 	getUserArgsForCall []struct {
@@ -78,15 +106,16 @@ func (fake *FakeSuggestionStore) GetAllUsers() ([]*model.User, error) {
 	ret, specificReturn := fake.getAllUsersReturnsOnCall[len(fake.getAllUsersArgsForCall)]
 	fake.getAllUsersArgsForCall = append(fake.getAllUsersArgsForCall, struct {
 	}{})
+	stub := fake.GetAllUsersStub
+	fakeReturns := fake.getAllUsersReturns
 	fake.recordInvocation("GetAllUsers", []interface{}{})
 	fake.getAllUsersMutex.Unlock()
-	if fake.GetAllUsersStub != nil {
-		return fake.GetAllUsersStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getAllUsersReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -128,21 +157,86 @@ func (fake *FakeSuggestionStore) GetAllUsersReturnsOnCall(i int, result1 []*mode
 	}{result1, result2}
 }
 
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUser(arg1 string) (*model.Suggestion, error) {
+	fake.getLatestSuggestionForUserMutex.Lock()
+	ret, specificReturn := fake.getLatestSuggestionForUserReturnsOnCall[len(fake.getLatestSuggestionForUserArgsForCall)]
+	fake.getLatestSuggestionForUserArgsForCall = append(fake.getLatestSuggestionForUserArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetLatestSuggestionForUserStub
+	fakeReturns := fake.getLatestSuggestionForUserReturns
+	fake.recordInvocation("GetLatestSuggestionForUser", []interface{}{arg1})
+	fake.getLatestSuggestionForUserMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUserCallCount() int {
+	fake.getLatestSuggestionForUserMutex.RLock()
+	defer fake.getLatestSuggestionForUserMutex.RUnlock()
+	return len(fake.getLatestSuggestionForUserArgsForCall)
+}
+
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUserCalls(stub func(string) (*model.Suggestion, error)) {
+	fake.getLatestSuggestionForUserMutex.Lock()
+	defer fake.getLatestSuggestionForUserMutex.Unlock()
+	fake.GetLatestSuggestionForUserStub = stub
+}
+
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUserArgsForCall(i int) string {
+	fake.getLatestSuggestionForUserMutex.RLock()
+	defer fake.getLatestSuggestionForUserMutex.RUnlock()
+	argsForCall := fake.getLatestSuggestionForUserArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUserReturns(result1 *model.Suggestion, result2 error) {
+	fake.getLatestSuggestionForUserMutex.Lock()
+	defer fake.getLatestSuggestionForUserMutex.Unlock()
+	fake.GetLatestSuggestionForUserStub = nil
+	fake.getLatestSuggestionForUserReturns = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSuggestionStore) GetLatestSuggestionForUserReturnsOnCall(i int, result1 *model.Suggestion, result2 error) {
+	fake.getLatestSuggestionForUserMutex.Lock()
+	defer fake.getLatestSuggestionForUserMutex.Unlock()
+	fake.GetLatestSuggestionForUserStub = nil
+	if fake.getLatestSuggestionForUserReturnsOnCall == nil {
+		fake.getLatestSuggestionForUserReturnsOnCall = make(map[int]struct {
+			result1 *model.Suggestion
+			result2 error
+		})
+	}
+	fake.getLatestSuggestionForUserReturnsOnCall[i] = struct {
+		result1 *model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSuggestionStore) GetSuggestion(arg1 uint) (*model.Suggestion, error) {
 	fake.getSuggestionMutex.Lock()
 	ret, specificReturn := fake.getSuggestionReturnsOnCall[len(fake.getSuggestionArgsForCall)]
 	fake.getSuggestionArgsForCall = append(fake.getSuggestionArgsForCall, struct {
 		arg1 uint
 	}{arg1})
+	stub := fake.GetSuggestionStub
+	fakeReturns := fake.getSuggestionReturns
 	fake.recordInvocation("GetSuggestion", []interface{}{arg1})
 	fake.getSuggestionMutex.Unlock()
-	if fake.GetSuggestionStub != nil {
-		return fake.GetSuggestionStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getSuggestionReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -191,21 +285,87 @@ func (fake *FakeSuggestionStore) GetSuggestionReturnsOnCall(i int, result1 *mode
 	}{result1, result2}
 }
 
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSince(arg1 string, arg2 time.Time) ([]*model.Suggestion, error) {
+	fake.getSuggestionsForUserSinceMutex.Lock()
+	ret, specificReturn := fake.getSuggestionsForUserSinceReturnsOnCall[len(fake.getSuggestionsForUserSinceArgsForCall)]
+	fake.getSuggestionsForUserSinceArgsForCall = append(fake.getSuggestionsForUserSinceArgsForCall, struct {
+		arg1 string
+		arg2 time.Time
+	}{arg1, arg2})
+	stub := fake.GetSuggestionsForUserSinceStub
+	fakeReturns := fake.getSuggestionsForUserSinceReturns
+	fake.recordInvocation("GetSuggestionsForUserSince", []interface{}{arg1, arg2})
+	fake.getSuggestionsForUserSinceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSinceCallCount() int {
+	fake.getSuggestionsForUserSinceMutex.RLock()
+	defer fake.getSuggestionsForUserSinceMutex.RUnlock()
+	return len(fake.getSuggestionsForUserSinceArgsForCall)
+}
+
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSinceCalls(stub func(string, time.Time) ([]*model.Suggestion, error)) {
+	fake.getSuggestionsForUserSinceMutex.Lock()
+	defer fake.getSuggestionsForUserSinceMutex.Unlock()
+	fake.GetSuggestionsForUserSinceStub = stub
+}
+
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSinceArgsForCall(i int) (string, time.Time) {
+	fake.getSuggestionsForUserSinceMutex.RLock()
+	defer fake.getSuggestionsForUserSinceMutex.RUnlock()
+	argsForCall := fake.getSuggestionsForUserSinceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSinceReturns(result1 []*model.Suggestion, result2 error) {
+	fake.getSuggestionsForUserSinceMutex.Lock()
+	defer fake.getSuggestionsForUserSinceMutex.Unlock()
+	fake.GetSuggestionsForUserSinceStub = nil
+	fake.getSuggestionsForUserSinceReturns = struct {
+		result1 []*model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSuggestionStore) GetSuggestionsForUserSinceReturnsOnCall(i int, result1 []*model.Suggestion, result2 error) {
+	fake.getSuggestionsForUserSinceMutex.Lock()
+	defer fake.getSuggestionsForUserSinceMutex.Unlock()
+	fake.GetSuggestionsForUserSinceStub = nil
+	if fake.getSuggestionsForUserSinceReturnsOnCall == nil {
+		fake.getSuggestionsForUserSinceReturnsOnCall = make(map[int]struct {
+			result1 []*model.Suggestion
+			result2 error
+		})
+	}
+	fake.getSuggestionsForUserSinceReturnsOnCall[i] = struct {
+		result1 []*model.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSuggestionStore) GetUser(arg1 string) (*model.User, error) {
 	fake.getUserMutex.Lock()
 	ret, specificReturn := fake.getUserReturnsOnCall[len(fake.getUserArgsForCall)]
 	fake.getUserArgsForCall = append(fake.getUserArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.GetUserStub
+	fakeReturns := fake.getUserReturns
 	fake.recordInvocation("GetUser", []interface{}{arg1})
 	fake.getUserMutex.Unlock()
-	if fake.GetUserStub != nil {
-		return fake.GetUserStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getUserReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -260,15 +420,16 @@ func (fake *FakeSuggestionStore) PutSuggestion(arg1 *model.Suggestion) error {
 	fake.putSuggestionArgsForCall = append(fake.putSuggestionArgsForCall, struct {
 		arg1 *model.Suggestion
 	}{arg1})
+	stub := fake.PutSuggestionStub
+	fakeReturns := fake.putSuggestionReturns
 	fake.recordInvocation("PutSuggestion", []interface{}{arg1})
 	fake.putSuggestionMutex.Unlock()
-	if fake.PutSuggestionStub != nil {
-		return fake.PutSuggestionStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putSuggestionReturns
 	return fakeReturns.result1
 }
 
@@ -320,15 +481,16 @@ func (fake *FakeSuggestionStore) PutUser(arg1 *model.User) error {
 	fake.putUserArgsForCall = append(fake.putUserArgsForCall, struct {
 		arg1 *model.User
 	}{arg1})
+	stub := fake.PutUserStub
+	fakeReturns := fake.putUserReturns
 	fake.recordInvocation("PutUser", []interface{}{arg1})
 	fake.putUserMutex.Unlock()
-	if fake.PutUserStub != nil {
-		return fake.PutUserStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putUserReturns
 	return fakeReturns.result1
 }
 
@@ -379,8 +541,12 @@ func (fake *FakeSuggestionStore) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.getAllUsersMutex.RLock()
 	defer fake.getAllUsersMutex.RUnlock()
+	fake.getLatestSuggestionForUserMutex.RLock()
+	defer fake.getLatestSuggestionForUserMutex.RUnlock()
 	fake.getSuggestionMutex.RLock()
 	defer fake.getSuggestionMutex.RUnlock()
+	fake.getSuggestionsForUserSinceMutex.RLock()
+	defer fake.getSuggestionsForUserSinceMutex.RUnlock()
 	fake.getUserMutex.RLock()
 	defer fake.getUserMutex.RUnlock()
 	fake.putSuggestionMutex.RLock()
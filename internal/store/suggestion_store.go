@@ -1,6 +1,8 @@
 package store
 
 import (
+	"time"
+
 	"github.com/kbariotis/go-discover/internal/model"
 )
 
@@ -13,5 +15,6 @@ type SuggestionStore interface {
 	PutUser(*model.User) error
 	GetSuggestion(uint) (*model.Suggestion, error)
 	GetLatestSuggestionForUser(Name string) (*model.Suggestion, error)
+	GetSuggestionsForUserSince(name string, since time.Time) ([]*model.Suggestion, error)
 	PutSuggestion(*model.Suggestion) error
 }
@@ -1,15 +1,30 @@
 package store
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 
 	"github.com/kbariotis/go-discover/internal/model"
 )
 
+// encryptedTokenPrefix marks encrypted tokens, tokens stored before they were
+// encrypted are read as they are
+const encryptedTokenPrefix = "enc:"
+
 // SuggestionSQL store implementation
+// Users' GitHub tokens are encrypted with tokenCipher, they are not stored
+// when there is none
 type SuggestionSQL struct {
-	db *gorm.DB
+	db          *gorm.DB
+	tokenCipher cipher.AEAD
 }
 
 var (
@@ -20,13 +35,27 @@ var (
 	}
 )
 
-// NewSuggestionSQL constrcuts a new SuggestionSQL store given a neoism db
-func NewSuggestionSQL(db *gorm.DB) (*SuggestionSQL, error) {
-	neo := &SuggestionSQL{
+// NewSuggestionSQL constrcuts a new SuggestionSQL store given a gorm db and
+// the AES key users' tokens are encrypted with, tokens are not stored without
+// a key
+func NewSuggestionSQL(db *gorm.DB, tokenKey []byte) (*SuggestionSQL, error) {
+	s := &SuggestionSQL{
 		db: db,
 	}
 
-	return neo, nil
+	if len(tokenKey) > 0 {
+		block, err := aes.NewCipher(tokenKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid token encryption key")
+		}
+
+		s.tokenCipher, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not construct token cipher")
+		}
+	}
+
+	return s, nil
 }
 
 // Setup -
@@ -45,31 +74,106 @@ func (s *SuggestionSQL) Cleanup() error {
 func (s *SuggestionSQL) GetAllUsers() ([]*model.User, error) {
 	users := []*model.User{}
 	res := s.db.Find(&users)
-	return users, errors.Wrap(res.Error, "could not get all users")
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, "could not get all users")
+	}
+
+	for _, user := range users {
+		if err := s.decryptToken(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
 }
 
 // GetUser -
 func (s *SuggestionSQL) GetUser(name string) (*model.User, error) {
 	user := &model.User{}
 	res := s.db.First(user, model.User{Name: name})
-	return user, errors.Wrap(res.Error, "could not get user")
+	if res.Error != nil {
+		return user, errors.Wrap(res.Error, "could not get user")
+	}
+
+	if err := s.decryptToken(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 // PutUser -
 func (s *SuggestionSQL) PutUser(user *model.User) error {
+	token, err := s.encryptToken(user.Token)
+	if err != nil {
+		return err
+	}
+
 	selectedUser := model.User{
 		Name: user.Name,
 	}
 	updatedUser := model.User{
 		Email: user.Email,
+		Token: token,
 	}
+	// the stored user is not read back into user so that its token stays
+	// decrypted
 	res := s.db.
 		Where(selectedUser).
 		Assign(updatedUser).
-		FirstOrCreate(user)
+		FirstOrCreate(&model.User{})
 	return errors.Wrap(res.Error, "could not put user")
 }
 
+// encryptToken returns the token encrypted as it is stored, or nothing if
+// tokens are not stored
+func (s *SuggestionSQL) encryptToken(token string) (string, error) {
+	if token == "" || s.tokenCipher == nil {
+		return "", nil
+	}
+
+	nonce := make([]byte, s.tokenCipher.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "could not create token nonce")
+	}
+
+	sealed := s.tokenCipher.Seal(nonce, nonce, []byte(token), nil)
+
+	return encryptedTokenPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken decrypts the stored token of a user in place
+func (s *SuggestionSQL) decryptToken(user *model.User) error {
+	if !strings.HasPrefix(user.Token, encryptedTokenPrefix) {
+		return nil
+	}
+
+	if s.tokenCipher == nil {
+		return errors.New("no key to decrypt the token of user " + user.Name)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(
+		strings.TrimPrefix(user.Token, encryptedTokenPrefix),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not decode token of user "+user.Name)
+	}
+
+	nonceSize := s.tokenCipher.NonceSize()
+	if len(sealed) < nonceSize {
+		return errors.New("invalid token of user " + user.Name)
+	}
+
+	token, err := s.tokenCipher.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return errors.Wrap(err, "could not decrypt token of user "+user.Name)
+	}
+
+	user.Token = string(token)
+
+	return nil
+}
+
 // GetSuggestion -
 func (s *SuggestionSQL) GetSuggestion(id uint) (*model.Suggestion, error) {
 	suggestion := &model.Suggestion{}
@@ -88,8 +192,29 @@ func (s *SuggestionSQL) GetLatestSuggestionForUser(Name string) (*model.Suggesti
 	return suggestion, errors.Wrap(res.Error, "could not get suggestion for user")
 }
 
+// GetSuggestionsForUserSince returns the suggestions made to a user after the
+// given time
+func (s *SuggestionSQL) GetSuggestionsForUserSince(
+	name string,
+	since time.Time,
+) ([]*model.Suggestion, error) {
+	suggestions := []*model.Suggestion{}
+	res := s.db.
+		Preload("Items").
+		Where("user_id = ? AND date_time > ?", name, since).
+		Order("date_time").
+		Find(&suggestions)
+	return suggestions, errors.Wrap(res.Error, "could not get suggestions for user")
+}
+
 // PutSuggestion -
 func (s *SuggestionSQL) PutSuggestion(suggestion *model.Suggestion) error {
+	// a blank id would select any suggestion, so new ones are always created
+	if suggestion.ID == 0 {
+		res := s.db.Create(suggestion)
+		return errors.Wrap(res.Error, "could not put suggestions")
+	}
+
 	selectedSuggestion := model.Suggestion{
 		ID: suggestion.ID,
 	}
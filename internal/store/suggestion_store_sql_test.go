@@ -3,6 +3,7 @@ package store
 import (
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -62,6 +63,61 @@ func TestSuggestionSQL_Users(t *testing.T) {
 	require.NoError(t, db.Close())
 }
 
+func TestSuggestionSQL_UserTokens(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	s, err := NewSuggestionSQL(db, key)
+	require.NoError(t, err)
+	require.NoError(t, s.Setup())
+
+	user := &model.User{
+		Name:  "foo",
+		Token: "secret",
+	}
+	require.NoError(t, s.PutUser(user))
+	require.Equal(t, "secret", user.Token)
+
+	// tokens are encrypted at rest
+	stored := &model.User{}
+	require.NoError(t, db.First(stored, model.User{Name: "foo"}).Error)
+	require.True(t, strings.HasPrefix(stored.Token, encryptedTokenPrefix))
+	require.NotContains(t, stored.Token, "secret")
+
+	gotUser, err := s.GetUser("foo")
+	require.NoError(t, err)
+	require.Equal(t, "secret", gotUser.Token)
+
+	gotUsers, err := s.GetAllUsers()
+	require.NoError(t, err)
+	require.Equal(t, "secret", gotUsers[0].Token)
+
+	// and can't be read without the key
+	withoutKey, err := NewSuggestionSQL(db, nil)
+	require.NoError(t, err)
+	_, err = withoutKey.GetUser("foo")
+	require.Error(t, err)
+
+	otherKey, err := NewSuggestionSQL(db, []byte("fedcba9876543210fedcba9876543210"))
+	require.NoError(t, err)
+	_, err = otherKey.GetAllUsers()
+	require.Error(t, err)
+
+	// tokens are not stored without a key
+	require.NoError(t, withoutKey.PutUser(&model.User{
+		Name:  "bar",
+		Token: "secret",
+	}))
+	gotUser, err = withoutKey.GetUser("bar")
+	require.NoError(t, err)
+	require.Empty(t, gotUser.Token)
+
+	// invalid keys are rejected
+	_, err = NewSuggestionSQL(db, []byte("short"))
+	require.Error(t, err)
+}
+
 func TestSuggestionSQL_Suggestions(t *testing.T) {
 	// connect to db
 	db := getDB(t)
@@ -124,6 +180,61 @@ func TestSuggestionSQL_Suggestions(t *testing.T) {
 	require.NoError(t, db.Close())
 }
 
+func TestSuggestionSQL_SuggestionsSince(t *testing.T) {
+	db := getDB(t)
+
+	s := &SuggestionSQL{
+		db: db,
+	}
+	require.NoError(t, s.Setup())
+
+	now := time.Now().Round(time.Second).UTC()
+	suggestions := []*model.Suggestion{
+		{
+			UserID:   "foo",
+			DateTime: now.Add(time.Hour * 24 * -14),
+			Items: []model.SuggestionItem{
+				{Type: model.SuggestionTypeStarRepository, Value: "old"},
+			},
+		},
+		{
+			UserID:   "foo",
+			DateTime: now.Add(time.Hour * 24 * -7),
+			Items: []model.SuggestionItem{
+				{Type: model.SuggestionTypeStarRepository, Value: "recent"},
+			},
+		},
+		{
+			UserID:   "bar",
+			DateTime: now.Add(time.Hour * 24 * -7),
+			Items: []model.SuggestionItem{
+				{Type: model.SuggestionTypeStarRepository, Value: "other"},
+			},
+		},
+	}
+	for _, suggestion := range suggestions {
+		require.NoError(t, s.PutSuggestion(suggestion))
+	}
+
+	gotSuggestions, err := s.GetSuggestionsForUserSince(
+		"foo",
+		now.Add(time.Hour*24*-10),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []*model.Suggestion{suggestions[1]}, gotSuggestions)
+
+	// unknown users have no suggestions
+	gotSuggestions, err = s.GetSuggestionsForUserSince(
+		"baz",
+		now.Add(time.Hour*24*-10),
+	)
+	require.NoError(t, err)
+	require.Empty(t, gotSuggestions)
+
+	require.NoError(t, s.Cleanup())
+	require.NoError(t, db.Close())
+}
+
 func getDB(t *testing.T) *gorm.DB {
 	dir, err := ioutil.TempDir("", "go-discover-store")
 	require.NoError(t, err)